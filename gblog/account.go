@@ -0,0 +1,257 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type UserTokenPurpose string
+
+const (
+	TokenVerifyEmail   UserTokenPurpose = "verify_email"
+	TokenResetPassword UserTokenPurpose = "reset_password"
+)
+
+// 通过邮件发送的一次性token, 只保存hash
+type UserToken struct {
+	ID        uint             `gorm:"primarykey"`
+	UserID    uint             `gorm:"index"`
+	Purpose   UserTokenPurpose `gorm:"size:16"`
+	TokenHash string           `gorm:"size:64;uniqueIndex"`
+	Email     string           `gorm:"size:255"` // 发送时的邮箱, 之后修改了邮箱则验证token失效
+	ExpiresAt time.Time        `gorm:"index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type VerifyEmailReq struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type ForgotPasswordReq struct {
+	Email string `form:"email" binding:"required,email"`
+}
+
+type ResetPasswordReq struct {
+	Token    string `form:"token" binding:"required"`
+	Password string `form:"password" binding:"required"`
+}
+
+type ChangePasswordReq struct {
+	OldPassword string `form:"old_password" binding:"required"`
+	NewPassword string `form:"new_password" binding:"required"`
+}
+
+// 修改资料, 未提交的字段不修改, 提交空字符串表示清空
+type UpdateMeReq struct {
+	DisplayName *string `form:"display_name" binding:"omitempty,max=64"`
+	AvatarURL   *string `form:"avatar_url" binding:"omitempty,max=512,eq=|http_url"`
+	Bio         *string `form:"bio" binding:"omitempty,max=500"`
+	Email       *string `form:"email" binding:"omitempty,max=255,eq=|email"`
+}
+
+type DeleteMeReq struct {
+	Password string `form:"password"`
+	// 没有设置密码的钱包用户需要用钱包重新签名一条SIWE消息
+	Message   string `form:"message"`
+	Signature string `form:"signature"`
+}
+
+// 公开的用户资料
+func profileJSON(u *User) gin.H {
+	return gin.H{
+		"id":             u.ID,
+		"username":       u.Username,
+		"display_name":   u.DisplayName,
+		"avatar_url":     u.AvatarURL,
+		"bio":            u.Bio,
+		"wallet_address": u.WalletAddress,
+		"created_at":     u.CreatedAt,
+	}
+}
+
+// 用户本人和管理员可见的完整资料
+func accountJSON(u *User) gin.H {
+	data := profileJSON(u)
+	data["email"] = u.Email
+	data["email_verified_at"] = u.EmailVerifiedAt
+	data["role"] = u.Role
+	data["suspended_at"] = u.SuspendedAt
+	return data
+}
+
+// 按表单中的邮箱限流, 防止向同一邮箱频繁发信
+func formEmailKey(c *gin.Context) string {
+	return normalizeEmail(c.PostForm("email"))
+}
+
+// 提交邮件中的token验证邮箱
+func (h *Handler) VerifyEmailHandler(c *gin.Context) {
+	var req VerifyEmailReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "VerifyEmail", bindError(err))
+		return
+	}
+
+	user, err := h.account.VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		respondError(c, "VerifyEmail", err)
+		return
+	}
+
+	requestLogger(c).Info("VerifyEmail successfully", zap.Uint("user_id", user.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+}
+
+// 重新发送验证邮件, 之前的验证链接作废
+func (h *Handler) ResendVerificationHandler(c *gin.Context) {
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+	if err := h.account.ResendVerification(c.Request.Context(), uid); err != nil {
+		respondError(c, "ResendVerification", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// 忘记密码, 无论邮箱是否存在都返回成功
+func (h *Handler) ForgotPasswordHandler(c *gin.Context) {
+	var req ForgotPasswordReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "ForgotPassword", bindError(err))
+		return
+	}
+
+	if err := h.account.ForgotPassword(c.Request.Context(), req.Email); err != nil {
+		respondError(c, "ForgotPassword", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "if the email is registered and verified, a password reset link has been sent",
+	})
+}
+
+// 使用邮件中的token重置密码, 成功后需要重新登录
+func (h *Handler) ResetPasswordHandler(c *gin.Context) {
+	var req ResetPasswordReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "ResetPassword", bindError(err))
+		return
+	}
+
+	if err := h.account.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		respondError(c, "ResetPassword", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// 修改密码, 其他登录失效, 返回新的token对
+func (h *Handler) ChangePasswordHandler(c *gin.Context) {
+	var req ChangePasswordReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "ChangePassword", bindError(err))
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	pair, err := h.account.ChangePassword(c.Request.Context(), uid, req.OldPassword, req.NewPassword)
+	if err != nil {
+		respondError(c, "ChangePassword", err)
+		return
+	}
+
+	requestLogger(c).Info("ChangePassword successfully")
+	c.JSON(http.StatusOK, tokenPairResponse(pair))
+}
+
+// 当前用户的资料
+func (h *Handler) GetMeHandler(c *gin.Context) {
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+	user, err := h.account.Profile(c.Request.Context(), uid)
+	if err != nil {
+		respondError(c, "GetMe", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "user": accountJSON(user)})
+}
+
+// 修改昵称、头像、简介和邮箱, 修改邮箱后需重新验证
+func (h *Handler) UpdateMeHandler(c *gin.Context) {
+	var req UpdateMeReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "UpdateMe", bindError(err))
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.account.UpdateProfile(c.Request.Context(), uid, ProfileInput{
+		DisplayName: req.DisplayName,
+		AvatarURL:   req.AvatarURL,
+		Bio:         req.Bio,
+		Email:       req.Email,
+	})
+	if err != nil {
+		respondError(c, "UpdateMe", err)
+		return
+	}
+
+	requestLogger(c).Info("UpdateMe successfully")
+	c.JSON(http.StatusOK, gin.H{"success": true, "user": accountJSON(user)})
+}
+
+// 注销账号, 文章和评论一并删除, 用户名保留不可再注册
+//
+// 需要在请求体中提交密码或SIWE签名, net/http不解析DELETE请求的表单, 因此使用POST
+func (h *Handler) DeleteMeHandler(c *gin.Context) {
+	var req DeleteMeReq
+	if err := c.ShouldBind(&req); err != nil {
+		writeProblem(c, bindError(err))
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.account.DeleteAccount(c.Request.Context(), uid, req.Password, req.Message, req.Signature); err != nil {
+		respondError(c, "DeleteMe", err)
+		return
+	}
+
+	requestLogger(c).Info("DeleteMe successfully")
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// 用户的公开资料
+func (h *Handler) GetProfileHandler(c *gin.Context) {
+	id, ok := validateUserID(c)
+	if !ok {
+		return
+	}
+	user, err := h.account.Profile(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, "GetProfile", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "user": profileJSON(user)})
+}
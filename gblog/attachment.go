@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 文章附件, 文件保存在BlobStore中
+type Attachment struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	PostID      uint      `gorm:"index" json:"post_id"`
+	UserID      uint      `json:"user_id"` // 上传者
+	Filename    string    `gorm:"size:255" json:"filename"`
+	ContentType string    `gorm:"size:100" json:"content_type"`
+	Size        int64     `json:"size"`
+	FileKey     string    `gorm:"size:128;uniqueIndex" json:"-"`
+	ThumbKey    string    `gorm:"size:128;index" json:"-"` // 图片宽度不超过缩略图宽度时为空
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// 文件访问路径
+const filesPath = "/files/"
+
+func attachmentJSON(a *Attachment) gin.H {
+	url := filesPath + a.FileKey
+	thumb := ""
+	if a.ThumbKey != "" {
+		thumb = filesPath + a.ThumbKey
+	} else if a.Width > 0 {
+		thumb = url
+	}
+	return gin.H{
+		"id":            a.ID,
+		"post_id":       a.PostID,
+		"user_id":       a.UserID,
+		"filename":      a.Filename,
+		"content_type":  a.ContentType,
+		"size":          a.Size,
+		"width":         a.Width,
+		"height":        a.Height,
+		"url":           url,
+		"thumbnail_url": thumb,
+		"created_at":    a.CreatedAt,
+	}
+}
+
+// 上传附件, 表单字段file
+func (h *Handler) UploadAttachmentHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	actor, ok := getCurrentActor(c)
+	if !ok {
+		return
+	}
+
+	// 限制请求体大小, 为multipart的其他部分留出余量
+	maxBytes := h.attachments.MaxBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = ErrFileTooLarge
+		} else {
+			err = ErrNoUploadFile
+		}
+		respondError(c, "UploadAttachment", err)
+		return
+	}
+	if fh.Size > maxBytes {
+		respondError(c, "UploadAttachment", ErrFileTooLarge)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		respondError(c, "UploadAttachment", err)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxBytes+1))
+	if err != nil {
+		respondError(c, "UploadAttachment", err)
+		return
+	}
+
+	a, err := h.attachments.Upload(c.Request.Context(), actor, pid, fh.Filename, data)
+	if err != nil {
+		respondError(c, "UploadAttachment", err)
+		return
+	}
+
+	requestLogger(c).Info("UploadAttachment successfully", zap.Uint("attachment_id", a.ID), zap.Uint("post_id", pid), zap.Int64("size", a.Size))
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"attachment": attachmentJSON(a),
+	})
+}
+
+func (h *Handler) ListAttachmentsHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachments.List(c.Request.Context(), uid, pid)
+	if err != nil {
+		respondError(c, "ListAttachments", err)
+		return
+	}
+	items := make([]gin.H, 0, len(attachments))
+	for i := range attachments {
+		items = append(items, attachmentJSON(&attachments[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"attachments": items,
+	})
+}
+
+func (h *Handler) DeleteAttachmentHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("aid"), 10, 64)
+	if err != nil {
+		writeProblem(c, invalidParam("aid", "attachment_id format is not correct"))
+		return
+	}
+	actor, ok := getCurrentActor(c)
+	if !ok {
+		return
+	}
+
+	if err := h.attachments.Delete(c.Request.Context(), actor, pid, uint(id)); err != nil {
+		respondError(c, "DeleteAttachment", err)
+		return
+	}
+
+	requestLogger(c).Info("DeleteAttachment successfully", zap.Uint64("attachment_id", id))
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"attachment_id": id,
+	})
+}
+
+// 下载附件或缩略图, 已发布文章的文件不需要登录以便在文章中引用图片; key随机生成且不会复用
+func (h *Handler) ServeFileHandler(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	// 未登录时为0, 只能下载已发布文章的文件
+	uid := c.GetUint("userID")
+	f, err := h.attachments.Open(c.Request.Context(), uid, key)
+	if err != nil {
+		respondError(c, "ServeFile", err)
+		return
+	}
+	defer f.Close()
+
+	contentType, size := f.ContentType, f.Size
+	if key == f.ThumbKey {
+		contentType, size = mime.TypeByExtension(filepath.Ext(key)), -1
+	}
+	headers := map[string]string{
+		"Cache-Control":          "public, max-age=31536000, immutable",
+		"X-Content-Type-Options": "nosniff",
+	}
+	if !f.Public {
+		// 文章发布前只有作者能看到, 不能被共享缓存
+		headers["Cache-Control"] = "private, no-store"
+	}
+	if !isImageType(f.ContentType) {
+		headers["Content-Disposition"] = mime.FormatMediaType("attachment", map[string]string{"filename": f.Filename})
+	}
+	c.DataFromReader(http.StatusOK, size, contentType, f, headers)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 服务端保存的refresh token, 只存哈希值
+type RefreshToken struct {
+	ID              uint   `gorm:"primarykey"`
+	UserID          uint   `gorm:"index"`
+	TokenHash       string `gorm:"size:64;uniqueIndex"`
+	FamilyID        string `gorm:"size:64;index"` // 同一次登录轮换出的token属于同一家族
+	AccessJTI       string `gorm:"size:64"`       // 与该refresh token一同签发的access token
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+// 已吊销的access token(jti黑名单), 过期后可清理
+type RevokedToken struct {
+	JTI       string    `gorm:"primarykey;size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+type RefreshReq struct {
+	RefreshToken string `form:"refresh_token" binding:"required"`
+}
+
+type LogoutReq struct {
+	RefreshToken string `form:"refresh_token"`
+}
+
+// 登录/注册/刷新的响应体
+func tokenPairResponse(pair *TokenPair) gin.H {
+	return gin.H{
+		"success":       true,
+		"token":         pair.AccessToken,
+		"refresh_token": pair.RefreshToken,
+		"expires_in":    int(time.Until(pair.AccessExpiresAt).Seconds()),
+	}
+}
+
+// 使用refresh token换取新的token对, 旧的refresh token作废
+func (h *Handler) RefreshHandler(c *gin.Context) {
+	var req RefreshReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "refresh", bindError(err))
+		return
+	}
+
+	pair, err := h.auth.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		respondError(c, "refresh", err)
+		return
+	}
+
+	requestLogger(c).Info("refresh successfully", zap.Uint("userID", pair.UserID))
+	c.JSON(http.StatusOK, tokenPairResponse(pair))
+}
+
+// 退出登录: 吊销当前access token, 若带上refresh token则一并作废
+func (h *Handler) LogoutHandler(c *gin.Context) {
+	var req LogoutReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "logout", bindError(err))
+		return
+	}
+
+	claims, ok := getCurrentClaims(c)
+	if !ok {
+		return
+	}
+	if err := h.auth.Logout(c.Request.Context(), claims, req.RefreshToken); err != nil {
+		respondError(c, "logout", err)
+		return
+	}
+
+	requestLogger(c).Info("logout successfully")
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func getCurrentClaims(c *gin.Context) (*Claims, bool) {
+	v, exists := c.Get("claims")
+	if !exists {
+		writeProblem(c, ErrUnauthenticated)
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	if !ok {
+		respondError(c, "get claims", fmt.Errorf("unexpected claims type %T", v))
+		return nil, false
+	}
+	return claims, true
+}
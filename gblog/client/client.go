@@ -0,0 +1,139 @@
+// Package client 是gblog接口的Go客户端
+//
+// 接口方法和请求、响应类型由 gblog openapi 根据OpenAPI文档生成在client_gen.go中,
+// 修改接口后在本目录执行 go generate 重新生成
+package client
+
+//go:generate go run .. openapi -client client_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// access token, 不为空时放在Authorization头中发送
+	Token string
+}
+
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: http.DefaultClient}
+}
+
+// 上传的文件
+type File struct {
+	Name    string
+	Content io.Reader
+}
+
+// 接口返回的错误响应, 可以用errors.As取出后按Code判断错误类型
+func (p *Problem) Error() string {
+	switch {
+	case p.Code == "":
+		return fmt.Sprintf("gblog: %d %s", p.Status, p.Title)
+	case p.Detail == "":
+		return fmt.Sprintf("gblog: %d %s", p.Status, p.Code)
+	default:
+		return fmt.Sprintf("gblog: %d %s: %s", p.Status, p.Code, p.Detail)
+	}
+}
+
+// 查询和表单参数的字符串形式
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// 发送请求并把json响应解析到out; 有文件时以multipart提交, 否则表单以x-www-form-urlencoded提交
+func (c *Client) do(ctx context.Context, method, path string, query, form url.Values, files map[string]*File, out any) error {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	var contentType string
+	switch {
+	case files != nil:
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for k, vs := range form {
+			for _, v := range vs {
+				if err := w.WriteField(k, v); err != nil {
+					return err
+				}
+			}
+		}
+		for field, f := range files {
+			part, err := w.CreateFormFile(field, f.Name)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(part, f.Content); err != nil {
+				return err
+			}
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		body, contentType = &buf, w.FormDataContentType()
+	case form != nil:
+		body, contentType = strings.NewReader(form.Encode()), "application/x-www-form-urlencoded"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		// 非problem+json的错误(如代理返回的错误页)只保留状态码
+		problem := &Problem{}
+		if err := json.NewDecoder(resp.Body).Decode(problem); err != nil || problem.Code == "" {
+			problem = &Problem{Title: http.StatusText(resp.StatusCode)}
+		}
+		problem.Status = int64(resp.StatusCode)
+		return problem
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,2035 @@
+// Code generated by "gblog openapi"; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+type Account struct {
+	AvatarURL       string     `json:"avatar_url"`
+	Bio             string     `json:"bio"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DisplayName     string     `json:"display_name"`
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	ID              int64      `json:"id"`
+	Role            string     `json:"role"`
+	SuspendedAt     *time.Time `json:"suspended_at"`
+	Username        string     `json:"username"`
+	WalletAddress   *string    `json:"wallet_address"`
+}
+
+type Attachment struct {
+	ContentType  string    `json:"content_type"`
+	CreatedAt    time.Time `json:"created_at"`
+	Filename     string    `json:"filename"`
+	Height       int64     `json:"height"`
+	ID           int64     `json:"id"`
+	PostID       int64     `json:"post_id"`
+	Size         int64     `json:"size"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	URL          string    `json:"url"`
+	UserID       int64     `json:"user_id"`
+	Width        int64     `json:"width"`
+}
+
+type Category struct {
+	Description string `json:"description"`
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	ParentID    *int64 `json:"parent_id"`
+	Slug        string `json:"slug"`
+}
+
+type CategoryNode struct {
+	Children    []CategoryNode `json:"children"`
+	Description string         `json:"description"`
+	ID          int64          `json:"id"`
+	Name        string         `json:"name"`
+	ParentID    *int64         `json:"parent_id"`
+	Slug        string         `json:"slug"`
+}
+
+type Comment struct {
+	Content  string     `json:"content"`
+	Depth    int64      `json:"depth"`
+	EditedAt *time.Time `json:"edited_at"`
+	ID       int64      `json:"id"`
+	ParentID *int64     `json:"parent_id"`
+	PostID   int64      `json:"post_id"`
+	UserID   int64      `json:"user_id"`
+}
+
+type CommentNode struct {
+	Content   string        `json:"content"`
+	CreatedAt time.Time     `json:"created_at"`
+	Deleted   bool          `json:"deleted"`
+	EditedAt  *time.Time    `json:"edited_at"`
+	ID        int64         `json:"id"`
+	ParentID  *int64        `json:"parent_id"`
+	Replies   []CommentNode `json:"replies"`
+	UserID    int64         `json:"user_id"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Rule    string `json:"rule"`
+}
+
+type Notification struct {
+	Actor     string     `json:"actor"`
+	ActorID   int64      `json:"actor_id"`
+	CommentID int64      `json:"comment_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ID        int64      `json:"id"`
+	PostID    int64      `json:"post_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	Type      string     `json:"type"`
+}
+
+type PageMeta struct {
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor"`
+	Page       int64  `json:"page"`
+	PageSize   int64  `json:"page_size"`
+	Total      int64  `json:"total"`
+}
+
+type Post struct {
+	CategoryID   *int64 `json:"category_id"`
+	CommentCount int64  `json:"comment_count"`
+	Content      string `json:"content"`
+	// 创建时间, 格式 2006-01-02 15:04:05
+	Created     string     `json:"created"`
+	ID          int64      `json:"id"`
+	LikeCount   int64      `json:"like_count"`
+	PublishAt   *time.Time `json:"publish_at"`
+	PublishedAt *time.Time `json:"published_at"`
+	Status      string     `json:"status"`
+	Tags        []Tag      `json:"tags"`
+	Title       string     `json:"title"`
+	// 修改时间, 格式 2006-01-02 15:04:05
+	Updated   string `json:"updated"`
+	UserID    int64  `json:"user_id"`
+	ViewCount int64  `json:"view_count"`
+}
+
+type PostDetail struct {
+	Bookmarked   bool   `json:"bookmarked"`
+	CategoryID   *int64 `json:"category_id"`
+	CommentCount int64  `json:"comment_count"`
+	// format为html时为渲染后的html
+	Content string `json:"content"`
+	// 创建时间, 格式 2006-01-02 15:04:05
+	Created     string     `json:"created"`
+	Format      string     `json:"format"`
+	ID          int64      `json:"id"`
+	LikeCount   int64      `json:"like_count"`
+	Liked       bool       `json:"liked"`
+	PublishAt   *time.Time `json:"publish_at"`
+	PublishedAt *time.Time `json:"published_at"`
+	Status      string     `json:"status"`
+	Tags        []Tag      `json:"tags"`
+	Title       string     `json:"title"`
+	TOC         []TOCItem  `json:"toc"`
+	// 修改时间, 格式 2006-01-02 15:04:05
+	Updated   string `json:"updated"`
+	UserID    int64  `json:"user_id"`
+	ViewCount int64  `json:"view_count"`
+}
+
+type PostRevision struct {
+	AuthorID  int64     `json:"author_id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+	Note      string    `json:"note"`
+	PostID    int64     `json:"post_id"`
+	Title     string    `json:"title"`
+	Version   int64     `json:"version"`
+}
+
+type Problem struct {
+	Code      string       `json:"code"`
+	Detail    string       `json:"detail"`
+	Errors    []FieldError `json:"errors"`
+	Instance  string       `json:"instance"`
+	RequestID string       `json:"request_id"`
+	Status    int64        `json:"status"`
+	Title     string       `json:"title"`
+	Type      string       `json:"type"`
+}
+
+type Profile struct {
+	AvatarURL     string    `json:"avatar_url"`
+	Bio           string    `json:"bio"`
+	CreatedAt     time.Time `json:"created_at"`
+	DisplayName   string    `json:"display_name"`
+	ID            int64     `json:"id"`
+	Username      string    `json:"username"`
+	WalletAddress *string   `json:"wallet_address"`
+}
+
+type RankedPost struct {
+	CategoryID   *int64 `json:"category_id"`
+	CommentCount int64  `json:"comment_count"`
+	Content      string `json:"content"`
+	// 创建时间, 格式 2006-01-02 15:04:05
+	Created     string     `json:"created"`
+	ID          int64      `json:"id"`
+	LikeCount   int64      `json:"like_count"`
+	PublishAt   *time.Time `json:"publish_at"`
+	PublishedAt *time.Time `json:"published_at"`
+	Score       int64      `json:"score"`
+	Status      string     `json:"status"`
+	Tags        []Tag      `json:"tags"`
+	Title       string     `json:"title"`
+	// 修改时间, 格式 2006-01-02 15:04:05
+	Updated   string `json:"updated"`
+	UserID    int64  `json:"user_id"`
+	ViewCount int64  `json:"view_count"`
+}
+
+type RevisionSummary struct {
+	AuthorID  int64     `json:"author_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Note      string    `json:"note"`
+	Title     string    `json:"title"`
+	Version   int64     `json:"version"`
+}
+
+type SearchHit struct {
+	ID      int64   `json:"id"`
+	PostID  int64   `json:"post_id"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+	Title   string  `json:"title"`
+	Type    string  `json:"type"`
+}
+
+type TOCItem struct {
+	Children []TOCItem `json:"children"`
+	ID       string    `json:"id"`
+	Level    int64     `json:"level"`
+	Title    string    `json:"title"`
+}
+
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type TagCount struct {
+	Count  int64  `json:"count"`
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Slug   string `json:"slug"`
+	Weight int64  `json:"weight"`
+}
+
+type UserSummary struct {
+	AvatarURL     string  `json:"avatar_url"`
+	DisplayName   string  `json:"display_name"`
+	ID            int64   `json:"id"`
+	Username      string  `json:"username"`
+	WalletAddress *string `json:"wallet_address"`
+}
+
+// BookmarkPostResponse BookmarkPost的响应
+type BookmarkPostResponse struct {
+	Bookmarked bool  `json:"bookmarked"`
+	PostID     int64 `json:"post_id"`
+	Success    bool  `json:"success"`
+}
+
+// BookmarkPost 收藏, 重复收藏不报错
+//
+// PUT /auth/post/{id}/bookmark
+func (c *Client) BookmarkPost(ctx context.Context, id int64) (*BookmarkPostResponse, error) {
+	var resp BookmarkPostResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/auth/post/%d/bookmark", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ChangePasswordRequest ChangePassword的请求参数
+type ChangePasswordRequest struct {
+	NewPassword string
+	OldPassword string
+}
+
+// ChangePasswordResponse ChangePassword的响应
+type ChangePasswordResponse struct {
+	// access token剩余有效秒数
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Success      bool   `json:"success"`
+	Token        string `json:"token"`
+}
+
+// ChangePassword 修改密码, 其他登录失效, 返回新的token对
+//
+// PUT /auth/password
+func (c *Client) ChangePassword(ctx context.Context, req *ChangePasswordRequest) (*ChangePasswordResponse, error) {
+	if req == nil {
+		req = &ChangePasswordRequest{}
+	}
+	form := url.Values{}
+	form.Set("new_password", formatValue(req.NewPassword))
+	form.Set("old_password", formatValue(req.OldPassword))
+	var resp ChangePasswordResponse
+	if err := c.do(ctx, "PUT", "/auth/password", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateCategoryRequest CreateCategory的请求参数
+type CreateCategoryRequest struct {
+	Description *string
+	Name        *string
+	// 上级分类id, 0表示顶级分类
+	ParentID *int64
+	Slug     *string
+}
+
+// CreateCategoryResponse CreateCategory的响应
+type CreateCategoryResponse struct {
+	Category *Category `json:"category"`
+	Success  bool      `json:"success"`
+}
+
+// CreateCategory 创建分类, 需要taxonomy:manage权限
+//
+// POST /auth/categories
+func (c *Client) CreateCategory(ctx context.Context, req *CreateCategoryRequest) (*CreateCategoryResponse, error) {
+	if req == nil {
+		req = &CreateCategoryRequest{}
+	}
+	form := url.Values{}
+	if req.Description != nil {
+		form.Set("description", formatValue(*req.Description))
+	}
+	if req.Name != nil {
+		form.Set("name", formatValue(*req.Name))
+	}
+	if req.ParentID != nil {
+		form.Set("parent_id", formatValue(*req.ParentID))
+	}
+	if req.Slug != nil {
+		form.Set("slug", formatValue(*req.Slug))
+	}
+	var resp CreateCategoryResponse
+	if err := c.do(ctx, "POST", "/auth/categories", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateCommentRequest CreateComment的请求参数
+type CreateCommentRequest struct {
+	Content  *string
+	ParentID *int64
+}
+
+// CreateCommentResponse CreateComment的响应
+type CreateCommentResponse struct {
+	Comment *Comment `json:"comment"`
+	Success bool     `json:"success"`
+}
+
+// CreateComment 发表评论, parent_id不为0时为回复, 需要comment:create权限
+//
+// POST /auth/post/{id}/comment
+func (c *Client) CreateComment(ctx context.Context, id int64, req *CreateCommentRequest) (*CreateCommentResponse, error) {
+	if req == nil {
+		req = &CreateCommentRequest{}
+	}
+	form := url.Values{}
+	if req.Content != nil {
+		form.Set("content", formatValue(*req.Content))
+	}
+	if req.ParentID != nil {
+		form.Set("parent_id", formatValue(*req.ParentID))
+	}
+	var resp CreateCommentResponse
+	if err := c.do(ctx, "POST", fmt.Sprintf("/auth/post/%d/comment", id), nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreatePostRequest CreatePost的请求参数
+type CreatePostRequest struct {
+	// 分类id, 0表示清空
+	CategoryID *int64
+	Content    string
+	// 定时发布时间, RFC3339格式, 只用于scheduled状态
+	PublishAt *time.Time
+	Status    *string
+	// 标签名, 多个用逗号分隔; 提交空字符串表示清空
+	Tags  *string
+	Title string
+}
+
+// CreatePostResponse CreatePost的响应
+type CreatePostResponse struct {
+	Post    *Post `json:"post"`
+	Success bool  `json:"success"`
+}
+
+// CreatePost 发表文章, 需要post:create权限
+//
+// POST /auth/post
+func (c *Client) CreatePost(ctx context.Context, req *CreatePostRequest) (*CreatePostResponse, error) {
+	if req == nil {
+		req = &CreatePostRequest{}
+	}
+	form := url.Values{}
+	if req.CategoryID != nil {
+		form.Set("category_id", formatValue(*req.CategoryID))
+	}
+	form.Set("content", formatValue(req.Content))
+	if req.PublishAt != nil {
+		form.Set("publish_at", formatValue(*req.PublishAt))
+	}
+	if req.Status != nil {
+		form.Set("status", formatValue(*req.Status))
+	}
+	if req.Tags != nil {
+		form.Set("tags", formatValue(*req.Tags))
+	}
+	form.Set("title", formatValue(req.Title))
+	var resp CreatePostResponse
+	if err := c.do(ctx, "POST", "/auth/post", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CreateTagRequest CreateTag的请求参数
+type CreateTagRequest struct {
+	Name string
+}
+
+// CreateTagResponse CreateTag的响应
+type CreateTagResponse struct {
+	Success bool `json:"success"`
+	Tag     *Tag `json:"tag"`
+}
+
+// CreateTag 创建标签, 需要taxonomy:manage权限
+//
+// POST /auth/tags
+func (c *Client) CreateTag(ctx context.Context, req *CreateTagRequest) (*CreateTagResponse, error) {
+	if req == nil {
+		req = &CreateTagRequest{}
+	}
+	form := url.Values{}
+	form.Set("name", formatValue(req.Name))
+	var resp CreateTagResponse
+	if err := c.do(ctx, "POST", "/auth/tags", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteAttachmentResponse DeleteAttachment的响应
+type DeleteAttachmentResponse struct {
+	AttachmentID int64 `json:"attachment_id"`
+	Success      bool  `json:"success"`
+}
+
+// DeleteAttachment 删除附件
+//
+// DELETE /auth/post/{id}/attachments/{aid}
+func (c *Client) DeleteAttachment(ctx context.Context, id int64, aid int64) (*DeleteAttachmentResponse, error) {
+	var resp DeleteAttachmentResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/post/%d/attachments/%d", id, aid), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteCategoryResponse DeleteCategory的响应
+type DeleteCategoryResponse struct {
+	CategoryID int64 `json:"category_id"`
+	Success    bool  `json:"success"`
+}
+
+// DeleteCategory 删除分类, 有子分类时不允许删除, 需要taxonomy:manage权限
+//
+// DELETE /auth/categories/{cid}
+func (c *Client) DeleteCategory(ctx context.Context, cid int64) (*DeleteCategoryResponse, error) {
+	var resp DeleteCategoryResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/categories/%d", cid), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteCommentResponse DeleteComment的响应
+type DeleteCommentResponse struct {
+	CommentID int64 `json:"comment_id"`
+	Success   bool  `json:"success"`
+}
+
+// DeleteComment 删除评论
+//
+// DELETE /auth/post/{id}/comment/{cid}
+func (c *Client) DeleteComment(ctx context.Context, id int64, cid int64) (*DeleteCommentResponse, error) {
+	var resp DeleteCommentResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/post/%d/comment/%d", id, cid), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteMeRequest DeleteMe的请求参数
+type DeleteMeRequest struct {
+	Message   *string
+	Password  *string
+	Signature *string
+}
+
+// DeleteMeResponse DeleteMe的响应
+type DeleteMeResponse struct {
+	Success bool `json:"success"`
+}
+
+// DeleteMe 注销账号, 文章和评论一并删除
+//
+// POST /auth/me/delete
+func (c *Client) DeleteMe(ctx context.Context, req *DeleteMeRequest) (*DeleteMeResponse, error) {
+	if req == nil {
+		req = &DeleteMeRequest{}
+	}
+	form := url.Values{}
+	if req.Message != nil {
+		form.Set("message", formatValue(*req.Message))
+	}
+	if req.Password != nil {
+		form.Set("password", formatValue(*req.Password))
+	}
+	if req.Signature != nil {
+		form.Set("signature", formatValue(*req.Signature))
+	}
+	var resp DeleteMeResponse
+	if err := c.do(ctx, "POST", "/auth/me/delete", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeletePostResponse DeletePost的响应
+type DeletePostResponse struct {
+	PostID  int64 `json:"post_id"`
+	Success bool  `json:"success"`
+}
+
+// DeletePost 删除文章
+//
+// DELETE /auth/post/{id}
+func (c *Client) DeletePost(ctx context.Context, id int64) (*DeletePostResponse, error) {
+	var resp DeletePostResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/post/%d", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteTagResponse DeleteTag的响应
+type DeleteTagResponse struct {
+	Success bool  `json:"success"`
+	TagID   int64 `json:"tag_id"`
+}
+
+// DeleteTag 删除标签, 同时解除与文章的关联, 需要taxonomy:manage权限
+//
+// DELETE /auth/tags/{tid}
+func (c *Client) DeleteTag(ctx context.Context, tid int64) (*DeleteTagResponse, error) {
+	var resp DeleteTagResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/tags/%d", tid), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DiffPostRevisionsRequest DiffPostRevisions的请求参数
+type DiffPostRevisionsRequest struct {
+	// 起始版本, 默认为to的上一版本
+	From *int64
+	// 目标版本, 默认为最新版本
+	To *int64
+}
+
+// DiffPostRevisionsResponse DiffPostRevisions的响应
+type DiffPostRevisionsResponse struct {
+	Diff    string `json:"diff"`
+	From    int64  `json:"from"`
+	Success bool   `json:"success"`
+	To      int64  `json:"to"`
+}
+
+// DiffPostRevisions 两个版本之间的unified diff
+//
+// GET /auth/post/{id}/diff
+func (c *Client) DiffPostRevisions(ctx context.Context, id int64, req *DiffPostRevisionsRequest) (*DiffPostRevisionsResponse, error) {
+	if req == nil {
+		req = &DiffPostRevisionsRequest{}
+	}
+	query := url.Values{}
+	if req.From != nil {
+		query.Set("from", formatValue(*req.From))
+	}
+	if req.To != nil {
+		query.Set("to", formatValue(*req.To))
+	}
+	var resp DiffPostRevisionsResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/post/%d/diff", id), query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// FeedRequest Feed的请求参数
+type FeedRequest struct {
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+	// 上一页返回的next_cursor, 提供时按游标分页并忽略page
+	Cursor *string
+}
+
+// FeedResponse Feed的响应
+type FeedResponse struct {
+	Meta    *PageMeta `json:"meta"`
+	Posts   []Post    `json:"posts"`
+	Success bool      `json:"success"`
+}
+
+// Feed 关注的用户发布的文章
+//
+// GET /auth/feed
+func (c *Client) Feed(ctx context.Context, req *FeedRequest) (*FeedResponse, error) {
+	if req == nil {
+		req = &FeedRequest{}
+	}
+	query := url.Values{}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	if req.Cursor != nil {
+		query.Set("cursor", formatValue(*req.Cursor))
+	}
+	var resp FeedResponse
+	if err := c.do(ctx, "GET", "/auth/feed", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// FollowUserResponse FollowUser的响应
+type FollowUserResponse struct {
+	Following bool  `json:"following"`
+	Success   bool  `json:"success"`
+	UserID    int64 `json:"user_id"`
+}
+
+// FollowUser 关注, 重复关注不报错
+//
+// PUT /auth/users/{id}/follow
+func (c *Client) FollowUser(ctx context.Context, id int64) (*FollowUserResponse, error) {
+	var resp FollowUserResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/auth/users/%d/follow", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ForgotPasswordRequest ForgotPassword的请求参数
+type ForgotPasswordRequest struct {
+	Email string
+}
+
+// ForgotPasswordResponse ForgotPassword的响应
+type ForgotPasswordResponse struct {
+	Message string `json:"message"`
+	Success bool   `json:"success"`
+}
+
+// ForgotPassword 忘记密码, 无论邮箱是否存在都返回成功
+//
+// POST /password/forgot
+func (c *Client) ForgotPassword(ctx context.Context, req *ForgotPasswordRequest) (*ForgotPasswordResponse, error) {
+	if req == nil {
+		req = &ForgotPasswordRequest{}
+	}
+	form := url.Values{}
+	form.Set("email", formatValue(req.Email))
+	var resp ForgotPasswordResponse
+	if err := c.do(ctx, "POST", "/password/forgot", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetCommentTreeRequest GetCommentTree的请求参数
+type GetCommentTreeRequest struct {
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+	// 上一页返回的next_cursor, 提供时按游标分页并忽略page
+	Cursor *string
+}
+
+// GetCommentTreeResponse GetCommentTree的响应
+type GetCommentTreeResponse struct {
+	CommentCount int64         `json:"comment_count"`
+	Comments     []CommentNode `json:"comments"`
+	Meta         *PageMeta     `json:"meta"`
+	Success      bool          `json:"success"`
+}
+
+// GetCommentTree 评论树, 按顶层评论分页
+//
+// GET /auth/post/{id}/comment/tree
+func (c *Client) GetCommentTree(ctx context.Context, id int64, req *GetCommentTreeRequest) (*GetCommentTreeResponse, error) {
+	if req == nil {
+		req = &GetCommentTreeRequest{}
+	}
+	query := url.Values{}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	if req.Cursor != nil {
+		query.Set("cursor", formatValue(*req.Cursor))
+	}
+	var resp GetCommentTreeResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/post/%d/comment/tree", id), query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetMeResponse GetMe的响应
+type GetMeResponse struct {
+	Success bool     `json:"success"`
+	User    *Account `json:"user"`
+}
+
+// GetMe 当前用户的资料
+//
+// GET /auth/me
+func (c *Client) GetMe(ctx context.Context) (*GetMeResponse, error) {
+	var resp GetMeResponse
+	if err := c.do(ctx, "GET", "/auth/me", nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetPostRequest GetPost的请求参数
+type GetPostRequest struct {
+	// 内容格式, 默认markdown; html时返回渲染后的内容和目录
+	Format *string
+}
+
+// GetPostResponse GetPost的响应
+type GetPostResponse struct {
+	Post    *PostDetail `json:"post"`
+	Success bool        `json:"success"`
+}
+
+// GetPost 文章详情
+//
+// GET /auth/post/{id}
+func (c *Client) GetPost(ctx context.Context, id int64, req *GetPostRequest) (*GetPostResponse, error) {
+	if req == nil {
+		req = &GetPostRequest{}
+	}
+	query := url.Values{}
+	if req.Format != nil {
+		query.Set("format", formatValue(*req.Format))
+	}
+	var resp GetPostResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/post/%d", id), query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetPostRevisionResponse GetPostRevision的响应
+type GetPostRevisionResponse struct {
+	Revision *PostRevision `json:"revision"`
+	Success  bool          `json:"success"`
+}
+
+// GetPostRevision 指定版本的内容
+//
+// GET /auth/post/{id}/revisions/{rev}
+func (c *Client) GetPostRevision(ctx context.Context, id int64, rev int64) (*GetPostRevisionResponse, error) {
+	var resp GetPostRevisionResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/post/%d/revisions/%d", id, rev), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetProfileResponse GetProfile的响应
+type GetProfileResponse struct {
+	Success bool     `json:"success"`
+	User    *Profile `json:"user"`
+}
+
+// GetProfile 用户的公开资料
+//
+// GET /auth/users/{id}
+func (c *Client) GetProfile(ctx context.Context, id int64) (*GetProfileResponse, error) {
+	var resp GetProfileResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/users/%d", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// LikePostResponse LikePost的响应
+type LikePostResponse struct {
+	LikeCount int64 `json:"like_count"`
+	Liked     bool  `json:"liked"`
+	PostID    int64 `json:"post_id"`
+	Success   bool  `json:"success"`
+}
+
+// LikePost 点赞, 重复点赞不报错
+//
+// PUT /auth/post/{id}/like
+func (c *Client) LikePost(ctx context.Context, id int64) (*LikePostResponse, error) {
+	var resp LikePostResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/auth/post/%d/like", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListAttachmentsResponse ListAttachments的响应
+type ListAttachmentsResponse struct {
+	Attachments []Attachment `json:"attachments"`
+	Success     bool         `json:"success"`
+}
+
+// ListAttachments 文章的附件
+//
+// GET /auth/post/{id}/attachments
+func (c *Client) ListAttachments(ctx context.Context, id int64) (*ListAttachmentsResponse, error) {
+	var resp ListAttachmentsResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/post/%d/attachments", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListBookmarksRequest ListBookmarks的请求参数
+type ListBookmarksRequest struct {
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+}
+
+// ListBookmarksResponse ListBookmarks的响应
+type ListBookmarksResponse struct {
+	Meta    *PageMeta `json:"meta"`
+	Posts   []Post    `json:"posts"`
+	Success bool      `json:"success"`
+}
+
+// ListBookmarks 当前用户的收藏, 按收藏时间倒序
+//
+// GET /auth/bookmarks
+func (c *Client) ListBookmarks(ctx context.Context, req *ListBookmarksRequest) (*ListBookmarksResponse, error) {
+	if req == nil {
+		req = &ListBookmarksRequest{}
+	}
+	query := url.Values{}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	var resp ListBookmarksResponse
+	if err := c.do(ctx, "GET", "/auth/bookmarks", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListCategoriesResponse ListCategories的响应
+type ListCategoriesResponse struct {
+	Categories []CategoryNode `json:"categories"`
+	Success    bool           `json:"success"`
+}
+
+// ListCategories 分类树
+//
+// GET /auth/categories
+func (c *Client) ListCategories(ctx context.Context) (*ListCategoriesResponse, error) {
+	var resp ListCategoriesResponse
+	if err := c.do(ctx, "GET", "/auth/categories", nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListCommentsRequest ListComments的请求参数
+type ListCommentsRequest struct {
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+	// 上一页返回的next_cursor, 提供时按游标分页并忽略page
+	Cursor *string
+}
+
+// ListCommentsResponse ListComments的响应
+type ListCommentsResponse struct {
+	Comments []Comment `json:"comments"`
+	Meta     *PageMeta `json:"meta"`
+	Success  bool      `json:"success"`
+}
+
+// ListComments 文章的评论列表
+//
+// GET /auth/post/{id}/comments
+func (c *Client) ListComments(ctx context.Context, id int64, req *ListCommentsRequest) (*ListCommentsResponse, error) {
+	if req == nil {
+		req = &ListCommentsRequest{}
+	}
+	query := url.Values{}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	if req.Cursor != nil {
+		query.Set("cursor", formatValue(*req.Cursor))
+	}
+	var resp ListCommentsResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/post/%d/comments", id), query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListFollowersRequest ListFollowers的请求参数
+type ListFollowersRequest struct {
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+}
+
+// ListFollowersResponse ListFollowers的响应
+type ListFollowersResponse struct {
+	Meta    *PageMeta     `json:"meta"`
+	Success bool          `json:"success"`
+	Users   []UserSummary `json:"users"`
+}
+
+// ListFollowers 关注该用户的人, 按关注时间倒序
+//
+// GET /auth/users/{id}/followers
+func (c *Client) ListFollowers(ctx context.Context, id int64, req *ListFollowersRequest) (*ListFollowersResponse, error) {
+	if req == nil {
+		req = &ListFollowersRequest{}
+	}
+	query := url.Values{}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	var resp ListFollowersResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/users/%d/followers", id), query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListFollowingRequest ListFollowing的请求参数
+type ListFollowingRequest struct {
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+}
+
+// ListFollowingResponse ListFollowing的响应
+type ListFollowingResponse struct {
+	Meta    *PageMeta     `json:"meta"`
+	Success bool          `json:"success"`
+	Users   []UserSummary `json:"users"`
+}
+
+// ListFollowing 该用户关注的人, 按关注时间倒序
+//
+// GET /auth/users/{id}/following
+func (c *Client) ListFollowing(ctx context.Context, id int64, req *ListFollowingRequest) (*ListFollowingResponse, error) {
+	if req == nil {
+		req = &ListFollowingRequest{}
+	}
+	query := url.Values{}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	var resp ListFollowingResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/users/%d/following", id), query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListNotificationsRequest ListNotifications的请求参数
+type ListNotificationsRequest struct {
+	// 为true时只返回未读通知
+	Unread *bool
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+	// 上一页返回的next_cursor, 提供时按游标分页并忽略page
+	Cursor *string
+}
+
+// ListNotificationsResponse ListNotifications的响应
+type ListNotificationsResponse struct {
+	Meta          *PageMeta      `json:"meta"`
+	Notifications []Notification `json:"notifications"`
+	Success       bool           `json:"success"`
+	UnreadCount   int64          `json:"unread_count"`
+}
+
+// ListNotifications 通知列表, 按时间倒序
+//
+// GET /auth/notifications
+func (c *Client) ListNotifications(ctx context.Context, req *ListNotificationsRequest) (*ListNotificationsResponse, error) {
+	if req == nil {
+		req = &ListNotificationsRequest{}
+	}
+	query := url.Values{}
+	if req.Unread != nil {
+		query.Set("unread", formatValue(*req.Unread))
+	}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	if req.Cursor != nil {
+		query.Set("cursor", formatValue(*req.Cursor))
+	}
+	var resp ListNotificationsResponse
+	if err := c.do(ctx, "GET", "/auth/notifications", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListPostRevisionsRequest ListPostRevisions的请求参数
+type ListPostRevisionsRequest struct {
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+}
+
+// ListPostRevisionsResponse ListPostRevisions的响应
+type ListPostRevisionsResponse struct {
+	Meta      *PageMeta         `json:"meta"`
+	Revisions []RevisionSummary `json:"revisions"`
+	Success   bool              `json:"success"`
+}
+
+// ListPostRevisions 文章的历史版本, 按版本号倒序
+//
+// GET /auth/post/{id}/revisions
+func (c *Client) ListPostRevisions(ctx context.Context, id int64, req *ListPostRevisionsRequest) (*ListPostRevisionsResponse, error) {
+	if req == nil {
+		req = &ListPostRevisionsRequest{}
+	}
+	query := url.Values{}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	var resp ListPostRevisionsResponse
+	if err := c.do(ctx, "GET", fmt.Sprintf("/auth/post/%d/revisions", id), query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListPostsRequest ListPosts的请求参数
+type ListPostsRequest struct {
+	// 作者id
+	AuthorID *int64
+	// 创建时间下限, RFC3339或2006-01-02格式
+	From *string
+	// 创建时间上限, RFC3339或2006-01-02格式, 日期格式包含当天
+	To *string
+	// 标题或内容包含的关键字
+	Q *string
+	// 标签名, 多个用逗号分隔
+	Tag *string
+	// 多个标签的匹配方式, 默认any
+	TagMatch *string
+	// 分类id, 包含子分类
+	CategoryID *int64
+	// 文章状态
+	Status *string
+	// 排序字段, -表示倒序, 默认-created_at
+	Sort *string
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+	// 上一页返回的next_cursor, 提供时按游标分页并忽略page
+	Cursor *string
+}
+
+// ListPostsResponse ListPosts的响应
+type ListPostsResponse struct {
+	Meta    *PageMeta `json:"meta"`
+	Posts   []Post    `json:"posts"`
+	Success bool      `json:"success"`
+}
+
+// ListPosts 文章列表, 未发布的文章只返回自己的
+//
+// GET /auth/posts
+func (c *Client) ListPosts(ctx context.Context, req *ListPostsRequest) (*ListPostsResponse, error) {
+	if req == nil {
+		req = &ListPostsRequest{}
+	}
+	query := url.Values{}
+	if req.AuthorID != nil {
+		query.Set("author_id", formatValue(*req.AuthorID))
+	}
+	if req.From != nil {
+		query.Set("from", formatValue(*req.From))
+	}
+	if req.To != nil {
+		query.Set("to", formatValue(*req.To))
+	}
+	if req.Q != nil {
+		query.Set("q", formatValue(*req.Q))
+	}
+	if req.Tag != nil {
+		query.Set("tag", formatValue(*req.Tag))
+	}
+	if req.TagMatch != nil {
+		query.Set("tag_match", formatValue(*req.TagMatch))
+	}
+	if req.CategoryID != nil {
+		query.Set("category_id", formatValue(*req.CategoryID))
+	}
+	if req.Status != nil {
+		query.Set("status", formatValue(*req.Status))
+	}
+	if req.Sort != nil {
+		query.Set("sort", formatValue(*req.Sort))
+	}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	if req.Cursor != nil {
+		query.Set("cursor", formatValue(*req.Cursor))
+	}
+	var resp ListPostsResponse
+	if err := c.do(ctx, "GET", "/auth/posts", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListTagsRequest ListTags的请求参数
+type ListTagsRequest struct {
+	// 返回数量, 不提供时返回全部标签
+	Limit *int64
+}
+
+// ListTagsResponse ListTags的响应
+type ListTagsResponse struct {
+	Success bool       `json:"success"`
+	Tags    []TagCount `json:"tags"`
+}
+
+// ListTags 标签云
+//
+// GET /auth/tags
+func (c *Client) ListTags(ctx context.Context, req *ListTagsRequest) (*ListTagsResponse, error) {
+	if req == nil {
+		req = &ListTagsRequest{}
+	}
+	query := url.Values{}
+	if req.Limit != nil {
+		query.Set("limit", formatValue(*req.Limit))
+	}
+	var resp ListTagsResponse
+	if err := c.do(ctx, "GET", "/auth/tags", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListUsersRequest ListUsers的请求参数
+type ListUsersRequest struct {
+	Keyword   *string
+	Role      *string
+	Suspended *bool
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+}
+
+// ListUsersResponse ListUsers的响应
+type ListUsersResponse struct {
+	Meta    *PageMeta `json:"meta"`
+	Success bool      `json:"success"`
+	Users   []Account `json:"users"`
+}
+
+// ListUsers 查询用户, 支持按关键字、角色和封禁状态过滤, 需要user:manage权限
+//
+// GET /auth/admin/users
+func (c *Client) ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error) {
+	if req == nil {
+		req = &ListUsersRequest{}
+	}
+	query := url.Values{}
+	if req.Keyword != nil {
+		query.Set("keyword", formatValue(*req.Keyword))
+	}
+	if req.Role != nil {
+		query.Set("role", formatValue(*req.Role))
+	}
+	if req.Suspended != nil {
+		query.Set("suspended", formatValue(*req.Suspended))
+	}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	var resp ListUsersResponse
+	if err := c.do(ctx, "GET", "/auth/admin/users", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// LoginRequest Login的请求参数
+type LoginRequest struct {
+	Password string
+	Username string
+}
+
+type LoginResponseUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// LoginResponse Login的响应
+type LoginResponse struct {
+	// access token剩余有效秒数
+	ExpiresIn    int64              `json:"expires_in"`
+	RefreshToken string             `json:"refresh_token"`
+	Success      bool               `json:"success"`
+	Token        string             `json:"token"`
+	User         *LoginResponseUser `json:"user"`
+}
+
+// Login 登录
+//
+// POST /login
+func (c *Client) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+	if req == nil {
+		req = &LoginRequest{}
+	}
+	form := url.Values{}
+	form.Set("password", formatValue(req.Password))
+	form.Set("username", formatValue(req.Username))
+	var resp LoginResponse
+	if err := c.do(ctx, "POST", "/login", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// LogoutRequest Logout的请求参数
+type LogoutRequest struct {
+	RefreshToken *string
+}
+
+// LogoutResponse Logout的响应
+type LogoutResponse struct {
+	Success bool `json:"success"`
+}
+
+// Logout 退出登录: 吊销当前access token, 若带上refresh token则一并作废
+//
+// POST /auth/logout
+func (c *Client) Logout(ctx context.Context, req *LogoutRequest) (*LogoutResponse, error) {
+	if req == nil {
+		req = &LogoutRequest{}
+	}
+	form := url.Values{}
+	if req.RefreshToken != nil {
+		form.Set("refresh_token", formatValue(*req.RefreshToken))
+	}
+	var resp LogoutResponse
+	if err := c.do(ctx, "POST", "/auth/logout", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MarkAllNotificationsReadResponse MarkAllNotificationsRead的响应
+type MarkAllNotificationsReadResponse struct {
+	Success bool  `json:"success"`
+	Updated int64 `json:"updated"`
+}
+
+// MarkAllNotificationsRead 全部标记已读
+//
+// POST /auth/notifications/read-all
+func (c *Client) MarkAllNotificationsRead(ctx context.Context) (*MarkAllNotificationsReadResponse, error) {
+	var resp MarkAllNotificationsReadResponse
+	if err := c.do(ctx, "POST", "/auth/notifications/read-all", nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MarkNotificationReadResponse MarkNotificationRead的响应
+type MarkNotificationReadResponse struct {
+	NotificationID int64 `json:"notification_id"`
+	Success        bool  `json:"success"`
+}
+
+// MarkNotificationRead 标记单条通知已读, 已读的通知重复标记不报错
+//
+// POST /auth/notifications/{nid}/read
+func (c *Client) MarkNotificationRead(ctx context.Context, nid int64) (*MarkNotificationReadResponse, error) {
+	var resp MarkNotificationReadResponse
+	if err := c.do(ctx, "POST", fmt.Sprintf("/auth/notifications/%d/read", nid), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MostLikedPostsRequest MostLikedPosts的请求参数
+type MostLikedPostsRequest struct {
+	// 返回数量, 默认10, 最大50
+	Limit *int64
+}
+
+// MostLikedPostsResponse MostLikedPosts的响应
+type MostLikedPostsResponse struct {
+	Posts   []RankedPost `json:"posts"`
+	Success bool         `json:"success"`
+}
+
+// MostLikedPosts 点赞最多的文章
+//
+// GET /auth/posts/most-liked
+func (c *Client) MostLikedPosts(ctx context.Context, req *MostLikedPostsRequest) (*MostLikedPostsResponse, error) {
+	if req == nil {
+		req = &MostLikedPostsRequest{}
+	}
+	query := url.Values{}
+	if req.Limit != nil {
+		query.Set("limit", formatValue(*req.Limit))
+	}
+	var resp MostLikedPostsResponse
+	if err := c.do(ctx, "GET", "/auth/posts/most-liked", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RefreshRequest Refresh的请求参数
+type RefreshRequest struct {
+	RefreshToken string
+}
+
+// RefreshResponse Refresh的响应
+type RefreshResponse struct {
+	// access token剩余有效秒数
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Success      bool   `json:"success"`
+	Token        string `json:"token"`
+}
+
+// Refresh 使用refresh token换取新的token对
+//
+// POST /refresh
+func (c *Client) Refresh(ctx context.Context, req *RefreshRequest) (*RefreshResponse, error) {
+	if req == nil {
+		req = &RefreshRequest{}
+	}
+	form := url.Values{}
+	form.Set("refresh_token", formatValue(req.RefreshToken))
+	var resp RefreshResponse
+	if err := c.do(ctx, "POST", "/refresh", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RegisterRequest Register的请求参数
+type RegisterRequest struct {
+	Email    *string
+	Password string
+	Username string
+}
+
+// RegisterResponse Register的响应
+type RegisterResponse struct {
+	// access token剩余有效秒数
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Success      bool   `json:"success"`
+	Token        string `json:"token"`
+}
+
+// Register 注册
+//
+// POST /register
+func (c *Client) Register(ctx context.Context, req *RegisterRequest) (*RegisterResponse, error) {
+	if req == nil {
+		req = &RegisterRequest{}
+	}
+	files := map[string]*File{}
+	form := url.Values{}
+	if req.Email != nil {
+		form.Set("email", formatValue(*req.Email))
+	}
+	form.Set("password", formatValue(req.Password))
+	form.Set("username", formatValue(req.Username))
+	var resp RegisterResponse
+	if err := c.do(ctx, "POST", "/register", nil, form, files, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ResendVerificationResponse ResendVerification的响应
+type ResendVerificationResponse struct {
+	Success bool `json:"success"`
+}
+
+// ResendVerification 重新发送验证邮件
+//
+// POST /auth/email/verification
+func (c *Client) ResendVerification(ctx context.Context) (*ResendVerificationResponse, error) {
+	var resp ResendVerificationResponse
+	if err := c.do(ctx, "POST", "/auth/email/verification", nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ResetPasswordRequest ResetPassword的请求参数
+type ResetPasswordRequest struct {
+	Password string
+	Token    string
+}
+
+// ResetPasswordResponse ResetPassword的响应
+type ResetPasswordResponse struct {
+	Success bool `json:"success"`
+}
+
+// ResetPassword 使用邮件中的token重置密码, 成功后需要重新登录
+//
+// POST /password/reset
+func (c *Client) ResetPassword(ctx context.Context, req *ResetPasswordRequest) (*ResetPasswordResponse, error) {
+	if req == nil {
+		req = &ResetPasswordRequest{}
+	}
+	form := url.Values{}
+	form.Set("password", formatValue(req.Password))
+	form.Set("token", formatValue(req.Token))
+	var resp ResetPasswordResponse
+	if err := c.do(ctx, "POST", "/password/reset", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RollbackPostResponse RollbackPost的响应
+type RollbackPostResponse struct {
+	Post    *Post `json:"post"`
+	Success bool  `json:"success"`
+}
+
+// RollbackPost 回滚到指定版本, 回滚本身也会生成一个新版本
+//
+// POST /auth/post/{id}/revisions/{rev}/rollback
+func (c *Client) RollbackPost(ctx context.Context, id int64, rev int64) (*RollbackPostResponse, error) {
+	var resp RollbackPostResponse
+	if err := c.do(ctx, "POST", fmt.Sprintf("/auth/post/%d/revisions/%d/rollback", id, rev), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SearchRequest Search的请求参数
+type SearchRequest struct {
+	// 关键字
+	Q *string
+	// 搜索范围, 默认all
+	Type *string
+	// 页码, 从1开始
+	Page *int64
+	// 每页数量, 默认20, 最大100
+	PageSize *int64
+}
+
+// SearchResponse Search的响应
+type SearchResponse struct {
+	Hits    []SearchHit `json:"hits"`
+	Meta    *PageMeta   `json:"meta"`
+	Success bool        `json:"success"`
+}
+
+// Search 全文搜索文章和评论
+//
+// GET /auth/search
+func (c *Client) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	if req == nil {
+		req = &SearchRequest{}
+	}
+	query := url.Values{}
+	if req.Q != nil {
+		query.Set("q", formatValue(*req.Q))
+	}
+	if req.Type != nil {
+		query.Set("type", formatValue(*req.Type))
+	}
+	if req.Page != nil {
+		query.Set("page", formatValue(*req.Page))
+	}
+	if req.PageSize != nil {
+		query.Set("page_size", formatValue(*req.PageSize))
+	}
+	var resp SearchResponse
+	if err := c.do(ctx, "GET", "/auth/search", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetUserRoleRequest SetUserRole的请求参数
+type SetUserRoleRequest struct {
+	Role string
+}
+
+type SetUserRoleResponseUser struct {
+	ID       int64  `json:"id"`
+	Role     string `json:"role"`
+	Username string `json:"username"`
+}
+
+// SetUserRoleResponse SetUserRole的响应
+type SetUserRoleResponse struct {
+	Success bool                     `json:"success"`
+	User    *SetUserRoleResponseUser `json:"user"`
+}
+
+// SetUserRole 修改用户角色, 需要user:manage权限
+//
+// PUT /auth/admin/users/{id}/role
+func (c *Client) SetUserRole(ctx context.Context, id int64, req *SetUserRoleRequest) (*SetUserRoleResponse, error) {
+	if req == nil {
+		req = &SetUserRoleRequest{}
+	}
+	form := url.Values{}
+	form.Set("role", formatValue(req.Role))
+	var resp SetUserRoleResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/auth/admin/users/%d/role", id), nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SiweLinkRequest SiweLink的请求参数
+type SiweLinkRequest struct {
+	Message   string
+	Signature string
+}
+
+// SiweLinkResponse SiweLink的响应
+type SiweLinkResponse struct {
+	Success       bool    `json:"success"`
+	WalletAddress *string `json:"wallet_address"`
+}
+
+// SiweLink 已登录用户绑定钱包地址
+//
+// POST /auth/siwe/link
+func (c *Client) SiweLink(ctx context.Context, req *SiweLinkRequest) (*SiweLinkResponse, error) {
+	if req == nil {
+		req = &SiweLinkRequest{}
+	}
+	form := url.Values{}
+	form.Set("message", formatValue(req.Message))
+	form.Set("signature", formatValue(req.Signature))
+	var resp SiweLinkResponse
+	if err := c.do(ctx, "POST", "/auth/siwe/link", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SiweNonceResponse SiweNonce的响应
+type SiweNonceResponse struct {
+	Nonce   string `json:"nonce"`
+	Success bool   `json:"success"`
+}
+
+// SiweNonce 获取SIWE登录nonce
+//
+// GET /siwe/nonce
+func (c *Client) SiweNonce(ctx context.Context) (*SiweNonceResponse, error) {
+	var resp SiweNonceResponse
+	if err := c.do(ctx, "GET", "/siwe/nonce", nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SiweVerifyRequest SiweVerify的请求参数
+type SiweVerifyRequest struct {
+	Message   string
+	Signature string
+}
+
+type SiweVerifyResponseUser struct {
+	ID            int64   `json:"id"`
+	Username      string  `json:"username"`
+	WalletAddress *string `json:"wallet_address"`
+}
+
+// SiweVerifyResponse SiweVerify的响应
+type SiweVerifyResponse struct {
+	// access token剩余有效秒数
+	ExpiresIn    int64                   `json:"expires_in"`
+	RefreshToken string                  `json:"refresh_token"`
+	Success      bool                    `json:"success"`
+	Token        string                  `json:"token"`
+	User         *SiweVerifyResponseUser `json:"user"`
+}
+
+// SiweVerify 校验SIWE签名并登录, 钱包地址未注册时自动创建用户
+//
+// POST /siwe/verify
+func (c *Client) SiweVerify(ctx context.Context, req *SiweVerifyRequest) (*SiweVerifyResponse, error) {
+	if req == nil {
+		req = &SiweVerifyRequest{}
+	}
+	form := url.Values{}
+	form.Set("message", formatValue(req.Message))
+	form.Set("signature", formatValue(req.Signature))
+	var resp SiweVerifyResponse
+	if err := c.do(ctx, "POST", "/siwe/verify", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SuspendUserResponse SuspendUser的响应
+type SuspendUserResponse struct {
+	Success bool     `json:"success"`
+	User    *Account `json:"user"`
+}
+
+// SuspendUser 封禁用户, 需要user:manage权限
+//
+// PUT /auth/admin/users/{id}/suspension
+func (c *Client) SuspendUser(ctx context.Context, id int64) (*SuspendUserResponse, error) {
+	var resp SuspendUserResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/auth/admin/users/%d/suspension", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// TrendingPostsRequest TrendingPosts的请求参数
+type TrendingPostsRequest struct {
+	// 返回数量, 默认10, 最大50
+	Limit *int64
+}
+
+// TrendingPostsResponse TrendingPosts的响应
+type TrendingPostsResponse struct {
+	Posts   []RankedPost `json:"posts"`
+	Success bool         `json:"success"`
+}
+
+// TrendingPosts 最近一段时间内的热门文章
+//
+// GET /auth/posts/trending
+func (c *Client) TrendingPosts(ctx context.Context, req *TrendingPostsRequest) (*TrendingPostsResponse, error) {
+	if req == nil {
+		req = &TrendingPostsRequest{}
+	}
+	query := url.Values{}
+	if req.Limit != nil {
+		query.Set("limit", formatValue(*req.Limit))
+	}
+	var resp TrendingPostsResponse
+	if err := c.do(ctx, "GET", "/auth/posts/trending", query, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UnbookmarkPostResponse UnbookmarkPost的响应
+type UnbookmarkPostResponse struct {
+	Bookmarked bool  `json:"bookmarked"`
+	PostID     int64 `json:"post_id"`
+	Success    bool  `json:"success"`
+}
+
+// UnbookmarkPost 取消收藏
+//
+// DELETE /auth/post/{id}/bookmark
+func (c *Client) UnbookmarkPost(ctx context.Context, id int64) (*UnbookmarkPostResponse, error) {
+	var resp UnbookmarkPostResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/post/%d/bookmark", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UnfollowUserResponse UnfollowUser的响应
+type UnfollowUserResponse struct {
+	Following bool  `json:"following"`
+	Success   bool  `json:"success"`
+	UserID    int64 `json:"user_id"`
+}
+
+// UnfollowUser 取消关注, 未关注时不报错
+//
+// DELETE /auth/users/{id}/follow
+func (c *Client) UnfollowUser(ctx context.Context, id int64) (*UnfollowUserResponse, error) {
+	var resp UnfollowUserResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/users/%d/follow", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UnlikePostResponse UnlikePost的响应
+type UnlikePostResponse struct {
+	LikeCount int64 `json:"like_count"`
+	Liked     bool  `json:"liked"`
+	PostID    int64 `json:"post_id"`
+	Success   bool  `json:"success"`
+}
+
+// UnlikePost 取消点赞
+//
+// DELETE /auth/post/{id}/like
+func (c *Client) UnlikePost(ctx context.Context, id int64) (*UnlikePostResponse, error) {
+	var resp UnlikePostResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/post/%d/like", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UnreadNotificationCountResponse UnreadNotificationCount的响应
+type UnreadNotificationCountResponse struct {
+	Success     bool  `json:"success"`
+	UnreadCount int64 `json:"unread_count"`
+}
+
+// UnreadNotificationCount 未读通知数
+//
+// GET /auth/notifications/unread-count
+func (c *Client) UnreadNotificationCount(ctx context.Context) (*UnreadNotificationCountResponse, error) {
+	var resp UnreadNotificationCountResponse
+	if err := c.do(ctx, "GET", "/auth/notifications/unread-count", nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UnsuspendUserResponse UnsuspendUser的响应
+type UnsuspendUserResponse struct {
+	Success bool     `json:"success"`
+	User    *Account `json:"user"`
+}
+
+// UnsuspendUser 解除封禁, 需要user:manage权限
+//
+// DELETE /auth/admin/users/{id}/suspension
+func (c *Client) UnsuspendUser(ctx context.Context, id int64) (*UnsuspendUserResponse, error) {
+	var resp UnsuspendUserResponse
+	if err := c.do(ctx, "DELETE", fmt.Sprintf("/auth/admin/users/%d/suspension", id), nil, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateCategoryRequest UpdateCategory的请求参数
+type UpdateCategoryRequest struct {
+	Description *string
+	Name        *string
+	// 上级分类id, 0表示顶级分类
+	ParentID *int64
+	Slug     *string
+}
+
+// UpdateCategoryResponse UpdateCategory的响应
+type UpdateCategoryResponse struct {
+	Category *Category `json:"category"`
+	Success  bool      `json:"success"`
+}
+
+// UpdateCategory 修改分类, 需要taxonomy:manage权限
+//
+// PUT /auth/categories/{cid}
+func (c *Client) UpdateCategory(ctx context.Context, cid int64, req *UpdateCategoryRequest) (*UpdateCategoryResponse, error) {
+	if req == nil {
+		req = &UpdateCategoryRequest{}
+	}
+	form := url.Values{}
+	if req.Description != nil {
+		form.Set("description", formatValue(*req.Description))
+	}
+	if req.Name != nil {
+		form.Set("name", formatValue(*req.Name))
+	}
+	if req.ParentID != nil {
+		form.Set("parent_id", formatValue(*req.ParentID))
+	}
+	if req.Slug != nil {
+		form.Set("slug", formatValue(*req.Slug))
+	}
+	var resp UpdateCategoryResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/auth/categories/%d", cid), nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateCommentRequest UpdateComment的请求参数
+type UpdateCommentRequest struct {
+	Content string
+}
+
+// UpdateCommentResponse UpdateComment的响应
+type UpdateCommentResponse struct {
+	Comment *Comment `json:"comment"`
+	Success bool     `json:"success"`
+}
+
+// UpdateComment 修改评论, 只有作者本人可以修改
+//
+// PUT /auth/post/{id}/comment/{cid}
+func (c *Client) UpdateComment(ctx context.Context, id int64, cid int64, req *UpdateCommentRequest) (*UpdateCommentResponse, error) {
+	if req == nil {
+		req = &UpdateCommentRequest{}
+	}
+	form := url.Values{}
+	form.Set("content", formatValue(req.Content))
+	var resp UpdateCommentResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/auth/post/%d/comment/%d", id, cid), nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdateMeRequest UpdateMe的请求参数
+type UpdateMeRequest struct {
+	AvatarURL   *string
+	Bio         *string
+	DisplayName *string
+	Email       *string
+}
+
+// UpdateMeResponse UpdateMe的响应
+type UpdateMeResponse struct {
+	Success bool     `json:"success"`
+	User    *Account `json:"user"`
+}
+
+// UpdateMe 修改昵称、头像、简介和邮箱, 未提交的字段不修改, 提交空字符串表示清空
+//
+// PUT /auth/me
+func (c *Client) UpdateMe(ctx context.Context, req *UpdateMeRequest) (*UpdateMeResponse, error) {
+	if req == nil {
+		req = &UpdateMeRequest{}
+	}
+	form := url.Values{}
+	if req.AvatarURL != nil {
+		form.Set("avatar_url", formatValue(*req.AvatarURL))
+	}
+	if req.Bio != nil {
+		form.Set("bio", formatValue(*req.Bio))
+	}
+	if req.DisplayName != nil {
+		form.Set("display_name", formatValue(*req.DisplayName))
+	}
+	if req.Email != nil {
+		form.Set("email", formatValue(*req.Email))
+	}
+	var resp UpdateMeResponse
+	if err := c.do(ctx, "PUT", "/auth/me", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UpdatePostRequest UpdatePost的请求参数
+type UpdatePostRequest struct {
+	// 分类id, 0表示清空
+	CategoryID *int64
+	Content    *string
+	// 定时发布时间, RFC3339格式, 只用于scheduled状态
+	PublishAt *time.Time
+	Status    *string
+	// 标签名, 多个用逗号分隔; 提交空字符串表示清空
+	Tags  *string
+	Title *string
+}
+
+// UpdatePostResponse UpdatePost的响应
+type UpdatePostResponse struct {
+	Post    *Post `json:"post"`
+	Success bool  `json:"success"`
+}
+
+// UpdatePost 修改文章, 未提交的字段不修改
+//
+// PUT /auth/post/{id}
+func (c *Client) UpdatePost(ctx context.Context, id int64, req *UpdatePostRequest) (*UpdatePostResponse, error) {
+	if req == nil {
+		req = &UpdatePostRequest{}
+	}
+	form := url.Values{}
+	if req.CategoryID != nil {
+		form.Set("category_id", formatValue(*req.CategoryID))
+	}
+	if req.Content != nil {
+		form.Set("content", formatValue(*req.Content))
+	}
+	if req.PublishAt != nil {
+		form.Set("publish_at", formatValue(*req.PublishAt))
+	}
+	if req.Status != nil {
+		form.Set("status", formatValue(*req.Status))
+	}
+	if req.Tags != nil {
+		form.Set("tags", formatValue(*req.Tags))
+	}
+	if req.Title != nil {
+		form.Set("title", formatValue(*req.Title))
+	}
+	var resp UpdatePostResponse
+	if err := c.do(ctx, "PUT", fmt.Sprintf("/auth/post/%d", id), nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UploadAttachmentRequest UploadAttachment的请求参数
+type UploadAttachmentRequest struct {
+	File *File
+}
+
+// UploadAttachmentResponse UploadAttachment的响应
+type UploadAttachmentResponse struct {
+	Attachment *Attachment `json:"attachment"`
+	Success    bool        `json:"success"`
+}
+
+// UploadAttachment 上传文章附件
+//
+// POST /auth/post/{id}/attachments
+func (c *Client) UploadAttachment(ctx context.Context, id int64, req *UploadAttachmentRequest) (*UploadAttachmentResponse, error) {
+	if req == nil {
+		req = &UploadAttachmentRequest{}
+	}
+	files := map[string]*File{}
+	if req.File != nil {
+		files["file"] = req.File
+	}
+	var resp UploadAttachmentResponse
+	if err := c.do(ctx, "POST", fmt.Sprintf("/auth/post/%d/attachments", id), nil, nil, files, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// VerifyEmailRequest VerifyEmail的请求参数
+type VerifyEmailRequest struct {
+	Token string
+}
+
+// VerifyEmailResponse VerifyEmail的响应
+type VerifyEmailResponse struct {
+	Email           string     `json:"email"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Success         bool       `json:"success"`
+}
+
+// VerifyEmail 使用邮件中的token验证邮箱
+//
+// POST /email/verify
+func (c *Client) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (*VerifyEmailResponse, error) {
+	if req == nil {
+		req = &VerifyEmailRequest{}
+	}
+	form := url.Values{}
+	form.Set("token", formatValue(req.Token))
+	var resp VerifyEmailResponse
+	if err := c.do(ctx, "POST", "/email/verify", nil, form, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/balanceM/web3study/gblog/client"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+// 注册用户并返回带token的客户端
+func newTestClient(t *testing.T, baseURL, username string) *client.Client {
+	t.Helper()
+	c := client.New(baseURL)
+	resp, err := c.Register(context.Background(), &client.RegisterRequest{Username: username, Password: "password123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Token = resp.Token
+	return c
+}
+
+// 断言err是指定状态码和错误码的接口错误
+func expectProblem(t *testing.T, err error, status int64, code string) {
+	t.Helper()
+	var p *client.Problem
+	if !errors.As(err, &p) {
+		t.Fatalf("err = %v, want *client.Problem", err)
+	}
+	if p.Status != status || p.Code != code {
+		t.Fatalf("problem = %d %s, want %d %s", p.Status, p.Code, status, code)
+	}
+}
+
+// client_gen.go需要与接口描述同步, 修改路由后在client目录执行go generate
+func TestGeneratedClientUpToDate(t *testing.T) {
+	want, err := generateClient(buildOpenAPI(apiRoutes), "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("client/client_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("client/client_gen.go is out of date, run go generate in client")
+	}
+
+	typ := reflect.TypeOf(&client.Client{})
+	for _, route := range apiRoutes {
+		if _, ok := typ.MethodByName(route.ID); !ok && route.Raw == "" {
+			t.Errorf("client has no method for %s %s", route.Method, route.Path)
+		}
+	}
+}
+
+func TestClientAccount(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	alice := newTestClient(t, srv.URL, "alice")
+
+	_, err := client.New(srv.URL).Register(ctx, &client.RegisterRequest{Username: "alice", Password: "password123"})
+	expectProblem(t, err, http.StatusConflict, "user_exists")
+
+	login, err := client.New(srv.URL).Login(ctx, &client.LoginRequest{Username: "alice", Password: "password123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.User.Username != "alice" || login.Token == "" {
+		t.Fatalf("unexpected login response: %+v", login)
+	}
+
+	me, err := alice.GetMe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.User.Username != "alice" || me.User.Role != string(RoleAuthor) {
+		t.Fatalf("unexpected me: %+v", me.User)
+	}
+
+	_, err = alice.DeleteMe(ctx, &client.DeleteMeRequest{Password: ptr("wrong-password")})
+	expectProblem(t, err, http.StatusBadRequest, "password_incorrect")
+	if _, err := alice.DeleteMe(ctx, &client.DeleteMeRequest{Password: ptr("password123")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 注销后登录失效, 也不能再登录
+	_, err = alice.GetMe(ctx)
+	expectProblem(t, err, http.StatusUnauthorized, "token_revoked")
+	_, err = client.New(srv.URL).Login(ctx, &client.LoginRequest{Username: "alice", Password: "password123"})
+	expectProblem(t, err, http.StatusUnauthorized, "invalid_login")
+}
+
+func TestClientPostsAndComments(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	alice := newTestClient(t, srv.URL, "alice")
+	bob := newTestClient(t, srv.URL, "bob")
+
+	created, err := alice.CreatePost(ctx, &client.CreatePostRequest{Title: "hello", Content: "# hello", Status: ptr("published"), Tags: ptr("go,web3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid := created.Post.ID
+	if len(created.Post.Tags) != 2 {
+		t.Fatalf("len(tags) = %d, want 2", len(created.Post.Tags))
+	}
+
+	if _, err := alice.UpdatePost(ctx, pid, &client.UpdatePostRequest{Title: ptr("hello again")}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = bob.UpdatePost(ctx, pid, &client.UpdatePostRequest{Title: ptr("mine")})
+	expectProblem(t, err, http.StatusForbidden, "post_not_owned")
+
+	got, err := bob.GetPost(ctx, pid, &client.GetPostRequest{Format: ptr("html")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Post.Title != "hello again" || !strings.Contains(got.Post.Content, "<h1") {
+		t.Fatalf("unexpected post: title %q, content %q", got.Post.Title, got.Post.Content)
+	}
+
+	root, err := bob.CreateComment(ctx, pid, &client.CreateCommentRequest{Content: ptr("first")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alice.CreateComment(ctx, pid, &client.CreateCommentRequest{Content: ptr("reply"), ParentID: ptr(root.Comment.ID)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bob.CreateComment(ctx, pid, &client.CreateCommentRequest{Content: ptr("second")}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = alice.UpdateComment(ctx, pid, root.Comment.ID, &client.UpdateCommentRequest{Content: "mine"})
+	expectProblem(t, err, http.StatusForbidden, "comment_not_owned")
+
+	// 评论树按顶层评论分页, 回复跟随顶层评论返回
+	tree, err := alice.GetCommentTree(ctx, pid, &client.GetCommentTreeRequest{PageSize: ptr(int64(1))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Comments) != 1 || len(tree.Comments[0].Replies) != 1 || !tree.Meta.HasMore || tree.Meta.Total != 2 {
+		t.Fatalf("unexpected first page: %+v, meta %+v", tree.Comments, tree.Meta)
+	}
+	tree, err = alice.GetCommentTree(ctx, pid, &client.GetCommentTreeRequest{PageSize: ptr(int64(1)), Cursor: ptr(tree.Meta.NextCursor)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Comments) != 1 || tree.Comments[0].Content != "second" || tree.Meta.HasMore {
+		t.Fatalf("unexpected second page: %+v, meta %+v", tree.Comments, tree.Meta)
+	}
+
+	if _, err := alice.DeletePost(ctx, pid); err != nil {
+		t.Fatal(err)
+	}
+	_, err = bob.GetPost(ctx, pid, nil)
+	expectProblem(t, err, http.StatusNotFound, "post_not_found")
+}
+
+func TestClientDraftAttachment(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	alice := newTestClient(t, srv.URL, "alice")
+	bob := newTestClient(t, srv.URL, "bob")
+
+	draft, err := alice.CreatePost(ctx, &client.CreatePostRequest{Title: "draft", Content: "draft", Status: ptr("draft")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploaded, err := alice.UploadAttachment(ctx, draft.Post.ID, &client.UploadAttachmentRequest{
+		File: &client.File{Name: "notes.txt", Content: strings.NewReader("hello")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bob.ListAttachments(ctx, draft.Post.ID)
+	expectProblem(t, err, http.StatusNotFound, "post_not_found")
+
+	// 文件下载不生成客户端方法, 直接请求
+	fetch := func(token string) int {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+uploaded.Attachment.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := fetch(""); status != http.StatusNotFound {
+		t.Fatalf("anonymous download = %d, want 404", status)
+	}
+	if status := fetch(bob.Token); status != http.StatusNotFound {
+		t.Fatalf("download by bob = %d, want 404", status)
+	}
+	if status := fetch(alice.Token); status != http.StatusOK {
+		t.Fatalf("download by author = %d, want 200", status)
+	}
+
+	if _, err := alice.UpdatePost(ctx, draft.Post.ID, &client.UpdatePostRequest{Status: ptr("published")}); err != nil {
+		t.Fatal(err)
+	}
+	if status := fetch(""); status != http.StatusOK {
+		t.Fatalf("anonymous download after publish = %d, want 200", status)
+	}
+}
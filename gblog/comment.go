@@ -1,37 +1,98 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type Comment struct {
 	gorm.Model
-	Content string
-	UserID  uint
-	User    User
-	PostID  uint
-	Post    Post
+	Content  string
+	UserID   uint
+	User     User
+	PostID   uint
+	Post     Post
+	ParentID *uint `gorm:"index"` // 回复的评论id, 顶层评论为空
+	Depth    int   // 嵌套深度, 顶层评论为0
+	EditedAt *time.Time
 }
 
 type CreateCommentReq struct {
-	Content string
-	PostID  uint
+	Content  string `form:"content"`
+	ParentID uint   `form:"parent_id"`
 }
 
-func CreateCommentHandler(c *gin.Context) {
-	pidStr := c.Param("id")
-	if pidStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "post id is null"})
+func commentJSON(comment *Comment) gin.H {
+	return gin.H{
+		"id":        comment.ID,
+		"content":   comment.Content,
+		"post_id":   comment.PostID,
+		"user_id":   comment.UserID,
+		"parent_id": comment.ParentID,
+		"depth":     comment.Depth,
+		"edited_at": comment.EditedAt,
+	}
+}
+
+// 评论推送的事件类型
+const commentEventCreated = "created"
+
+// 评论推送的消息, comment与接口返回的格式相同
+func commentEvent(event string, comment *Comment) ([]byte, error) {
+	return json.Marshal(gin.H{"event": event, "comment": commentJSON(comment)})
+}
+
+// WebSocket连接参数
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingInterval   = wsPongWait * 9 / 10
+	wsMaxMessageSize = 512
+)
+
+// WebSocket握手不受同源策略限制, 且浏览器会带上access_token, 因此只接受同域和allowedOrigins中的页面发起的连接;
+// 没有Origin头的是非浏览器客户端, 不做限制
+func newWSUpgrader(allowedOrigins []string) *websocket.Upgrader {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, o := range allowedOrigins {
+		allowed[strings.ToLower(strings.TrimSuffix(o, "/"))] = true
+	}
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			u, err := url.Parse(origin)
+			if err != nil || u.Host == "" {
+				return false
+			}
+			return strings.EqualFold(u.Host, r.Host) || allowed[strings.ToLower(u.Scheme+"://"+u.Host)]
+		},
+	}
+}
+
+func (h *Handler) CreateCommentHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
 		return
 	}
-	pid, err := strconv.ParseUint(pidStr, 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "post_id format is not correct"})
+
+	var req CreateCommentReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "CreateComment", bindError(err))
 		return
 	}
 
@@ -40,50 +101,230 @@ func CreateCommentHandler(c *gin.Context) {
 		return
 	}
 
-	comment := &Comment{
-		Content: c.PostForm("content"),
-		UserID:  uid,
-		PostID:  uint(pid),
+	comment, err := h.comments.Create(c.Request.Context(), uid, pid, req.ParentID, req.Content)
+	if err != nil {
+		respondError(c, "CreateComment", err)
+		return
 	}
-	if err := db.Create(&comment).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+
+	requestLogger(c).Info("CreateComment successfully", zap.Uint("comment_id", comment.ID), zap.Uint("post_id", pid))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"comment": commentJSON(comment),
+	})
+}
+
+type UpdateCommentReq struct {
+	Content string `form:"content" binding:"required"`
+}
+
+// 修改评论, 只有作者本人可以修改
+func (h *Handler) UpdateCommentHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	cid, ok := validateCommentID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateCommentReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "UpdateComment", bindError(err))
+		return
+	}
+
+	actor, ok := getCurrentActor(c)
+	if !ok {
+		return
+	}
+	comment, err := h.comments.Update(c.Request.Context(), actor, pid, cid, req.Content)
+	if err != nil {
+		respondError(c, "UpdateComment", err)
 		return
 	}
 
+	requestLogger(c).Info("UpdateComment successfully", zap.Uint("comment_id", comment.ID))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"comment": gin.H{
-			"content": comment.Content,
-			"post_id": comment.PostID,
-			"user_id": comment.UserID,
-		},
+		"comment": commentJSON(comment),
+	})
+}
+
+// 评论树, 按顶层评论分页
+func (h *Handler) GetCommentTreeHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		respondError(c, "GetCommentTree", err)
+		return
+	}
+
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tree, meta, err := h.comments.Tree(ctx, uid, pid, page)
+	if err != nil {
+		respondError(c, "GetCommentTree", err)
+		return
+	}
+	meta.Page = pageNum
+	count, err := h.comments.Count(ctx, pid)
+	if err != nil {
+		respondError(c, "GetCommentTree", err)
+		return
+	}
+
+	requestLogger(c).Info("GetCommentTree successfully", zap.Uint("post_id", pid))
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"comments":      tree,
+		"comment_count": count,
+		"meta":          meta,
 	})
 }
 
-func GetCommentsByPostID(c *gin.Context) {
-	pidStr := c.Param("id")
-	if pidStr == "" {
-		zap.L().Error("GetCommentsByPostID failed", zap.String("error", "post id is null"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "post id is null"})
+func (h *Handler) GetCommentsByPostID(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		requestLogger(c).Error("GetCommentsByPostID failed", zap.String("error", "validatePostID failed"))
 		return
 	}
-	pid, err := strconv.ParseUint(pidStr, 10, 64)
+
+	page, pageNum, err := parsePage(c)
 	if err != nil {
-		zap.L().Error("GetCommentsByPostID failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "post_id format is not correct"})
+		respondError(c, "GetCommentsByPostID", err)
 		return
 	}
 
-	var comments []Comment
-	if err := db.Where("post_id = ?", pid).Find(&comments).Error; err != nil {
-		zap.L().Error("GetCommentsByPostID failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	uid, ok := getCurrentUserID(c)
+	if !ok {
 		return
 	}
 
-	zap.L().Info("GetCommentsByPostID successfully", zap.Uint("post_id", uint(pid)))
+	comments, meta, err := h.comments.ListByPost(c.Request.Context(), uid, pid, page)
+	if err != nil {
+		respondError(c, "GetCommentsByPostID", err)
+		return
+	}
+	meta.Page = pageNum
+
+	items := make([]gin.H, 0, len(comments))
+	for i := range comments {
+		items = append(items, commentJSON(&comments[i]))
+	}
+	requestLogger(c).Info("GetCommentsByPostID successfully", zap.Uint("post_id", pid))
 	c.JSON(http.StatusOK, gin.H{
 		"success":  true,
-		"comments": comments,
+		"comments": items,
+		"meta":     meta,
 	})
 }
+
+// 解析路径中的评论id
+func validateCommentID(c *gin.Context) (uint, bool) {
+	cid, err := strconv.ParseUint(c.Param("cid"), 10, 64)
+	if err != nil {
+		writeProblem(c, invalidParam("cid", "comment_id format is not correct"))
+		return 0, false
+	}
+	return uint(cid), true
+}
+
+// 删除评论: 评论作者可删除自己的评论, 版主和管理员可删除任意评论
+func (h *Handler) DeleteCommentHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	cid, ok := validateCommentID(c)
+	if !ok {
+		return
+	}
+
+	actor, ok := getCurrentActor(c)
+	if !ok {
+		return
+	}
+	if err := h.comments.Delete(c.Request.Context(), actor, pid, cid); err != nil {
+		respondError(c, "DeleteComment", err)
+		return
+	}
+
+	requestLogger(c).Info("DeleteComment successfully", zap.Uint("comment_id", cid))
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"comment_id": cid,
+	})
+}
+
+// 文章新评论的WebSocket推送, 每条新评论发送一条json消息, 客户端发送的消息会被忽略
+func (h *Handler) CommentStreamHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	events, err := h.comments.Subscribe(ctx, uid, pid)
+	if err != nil {
+		respondError(c, "CommentStream", err)
+		return
+	}
+
+	conn, err := h.wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade已向客户端返回错误
+		requestLogger(c).Warn("CommentStream upgrade failed", zap.Uint("post_id", pid), zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	// 读取客户端的pong和关闭帧, 连接断开或超时未响应时结束推送
+	go func() {
+		defer cancel()
+		conn.SetReadLimit(wsMaxMessageSize)
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-events:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
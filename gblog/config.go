@@ -0,0 +1,665 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// 环境变量前缀, 例如 GBLOG_DATABASE_DSN
+const envPrefix = "GBLOG_"
+
+// Duration 支持在配置文件中以 "24h"、"30m" 形式书写时长
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+type Config struct {
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	JWT       JWTConfig       `yaml:"jwt" toml:"jwt"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Siwe      SiweConfig      `yaml:"siwe" toml:"siwe"`
+	RBAC      RBACConfig      `yaml:"rbac" toml:"rbac"`
+	Search    SearchConfig    `yaml:"search" toml:"search"`
+	Comment   CommentConfig   `yaml:"comment" toml:"comment"`
+	Post      PostConfig      `yaml:"post" toml:"post"`
+	Upload    UploadConfig    `yaml:"upload" toml:"upload"`
+	Feed      FeedConfig      `yaml:"feed" toml:"feed"`
+	Redis     RedisConfig     `yaml:"redis" toml:"redis"`
+	Realtime  RealtimeConfig  `yaml:"realtime" toml:"realtime"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" toml:"rate_limit"`
+	Mail      MailConfig      `yaml:"mail" toml:"mail"`
+	Account   AccountConfig   `yaml:"account" toml:"account"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+}
+
+type ServerConfig struct {
+	Addr           string   `yaml:"addr" toml:"addr"`                       // 监听地址, 如 ":8080"
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"` // 信任的反向代理IP或网段, 只有来自这些地址的X-Forwarded-For才会被采用
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"` // 除同域外允许建立WebSocket连接的页面来源, 如 "https://blog.example.com"
+}
+
+type DatabaseConfig struct {
+	Driver       string `yaml:"driver" toml:"driver"` // mysql / postgres / sqlite / memory
+	DSN          string `yaml:"dsn" toml:"dsn"`
+	MaxOpenConns int    `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns int    `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	// 启动时自动执行未执行的迁移; 关闭时需先运行 gblog migrate up
+	AutoMigrate bool `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+type JWTConfig struct {
+	Secret        string   `yaml:"secret" toml:"secret"`
+	Expire        Duration `yaml:"expire" toml:"expire"`                 // access token有效期
+	RefreshExpire Duration `yaml:"refresh_expire" toml:"refresh_expire"` // refresh token有效期
+	Issuer        string   `yaml:"issuer" toml:"issuer"`                 // 签发者
+}
+
+// Sign-In With Ethereum 配置
+type SiweConfig struct {
+	Domain      string   `yaml:"domain" toml:"domain"`             // 消息中必须出现的域名, 如 "blog.example.com"
+	NonceExpire Duration `yaml:"nonce_expire" toml:"nonce_expire"` // nonce有效期
+}
+
+// 角色权限配置
+type RBACConfig struct {
+	DefaultRole Role     `yaml:"default_role" toml:"default_role"` // 新用户的角色
+	Admins      []string `yaml:"admins" toml:"admins"`             // 启动时提升为管理员的用户名
+}
+
+// 全文搜索配置
+type SearchConfig struct {
+	// auto: mysql使用FULLTEXT索引, 其他驱动使用内置倒排索引
+	// fulltext: 强制使用MySQL FULLTEXT; index: 强制使用内置倒排索引
+	Engine string `yaml:"engine" toml:"engine"`
+}
+
+// 文章配置
+type PostConfig struct {
+	ScheduleInterval  Duration `yaml:"schedule_interval" toml:"schedule_interval"`     // 检查定时发布文章的间隔
+	ViewFlushInterval Duration `yaml:"view_flush_interval" toml:"view_flush_interval"` // 浏览数写入数据库的间隔
+	TrendingWindow    Duration `yaml:"trending_window" toml:"trending_window"`         // 热门文章统计的时间范围
+}
+
+// 上传配置
+type UploadConfig struct {
+	MaxSize        int           `yaml:"max_size" toml:"max_size"`               // 单个文件最大MB
+	AllowedTypes   []string      `yaml:"allowed_types" toml:"allowed_types"`     // 允许的MIME类型, 按文件内容识别
+	ThumbnailWidth int           `yaml:"thumbnail_width" toml:"thumbnail_width"` // 图片缩略图宽度
+	Store          string        `yaml:"store" toml:"store"`                     // local / s3
+	Dir            string        `yaml:"dir" toml:"dir"`                         // local存储目录
+	S3             S3StoreConfig `yaml:"s3" toml:"s3"`
+}
+
+// S3兼容的对象存储, 如MinIO
+type S3StoreConfig struct {
+	Endpoint  string `yaml:"endpoint" toml:"endpoint"` // 如 "127.0.0.1:9000"
+	AccessKey string `yaml:"access_key" toml:"access_key"`
+	SecretKey string `yaml:"secret_key" toml:"secret_key"`
+	Bucket    string `yaml:"bucket" toml:"bucket"` // 不存在时自动创建
+	Region    string `yaml:"region" toml:"region"`
+	UseSSL    bool   `yaml:"use_ssl" toml:"use_ssl"`
+}
+
+// 首页动态配置
+type FeedConfig struct {
+	Mode         string `yaml:"mode" toml:"mode"`                   // read: 读取时查询关注的作者的文章; write: 发布时写入关注者的时间线缓存
+	TimelineSize int    `yaml:"timeline_size" toml:"timeline_size"` // write模式下每个用户缓存的文章数
+}
+
+// Redis连接配置, 多实例部署时共享状态使用
+type RedisConfig struct {
+	Addr     string `yaml:"addr" toml:"addr"` // 如 "127.0.0.1:6379"
+	Password string `yaml:"password" toml:"password"`
+	DB       int    `yaml:"db" toml:"db"`
+}
+
+// 实时推送配置
+type RealtimeConfig struct {
+	Broker string `yaml:"broker" toml:"broker"` // memory: 进程内推送; redis: 通过Redis pub/sub在多个实例间推送
+}
+
+// OpenTelemetry链路追踪配置
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`         // none: 不导出; stdout: 输出到控制台; otlp: 通过OTLP/HTTP发送到collector
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`         // otlp collector地址, 如 "localhost:4318"
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`         // otlp不使用TLS
+	ServiceName string  `yaml:"service_name" toml:"service_name"` // 上报的服务名
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"` // 采样比例 0~1, 请求头中带有上游的采样结果时以上游为准
+}
+
+// 认证接口的限流配置
+type RateLimitConfig struct {
+	Store   string        `yaml:"store" toml:"store"`     // memory: 进程内; redis: 多实例共享
+	IP      RateLimit     `yaml:"ip" toml:"ip"`           // 每个IP访问登录、注册、刷新token等接口
+	Account RateLimit     `yaml:"account" toml:"account"` // 每个用户名的登录请求
+	Lockout LockoutConfig `yaml:"lockout" toml:"lockout"`
+}
+
+// 令牌桶, 每分钟补充Rate个令牌, 最多积累Burst个
+type RateLimit struct {
+	Rate  int `yaml:"rate" toml:"rate"`
+	Burst int `yaml:"burst" toml:"burst"`
+}
+
+// 登录失败锁定
+type LockoutConfig struct {
+	Threshold   int      `yaml:"threshold" toml:"threshold"`       // 连续失败多少次后锁定
+	Duration    Duration `yaml:"duration" toml:"duration"`         // 首次锁定时长, 之后每次失败翻倍
+	MaxDuration Duration `yaml:"max_duration" toml:"max_duration"` // 最长锁定时长, 也是失败计数的保留时间
+}
+
+// 邮件配置
+type MailConfig struct {
+	Driver string     `yaml:"driver" toml:"driver"` // console: 写入日志; file: 保存为.eml文件; smtp: 通过SMTP发送
+	From   string     `yaml:"from" toml:"from"`     // 发件人, 如 "GBlog <noreply@example.com>"
+	Dir    string     `yaml:"dir" toml:"dir"`       // file方式的保存目录
+	SMTP   SMTPConfig `yaml:"smtp" toml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" toml:"host"`
+	Port     int    `yaml:"port" toml:"port"` // 通常为587(STARTTLS)或25
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// 邮箱验证和重置密码配置
+type AccountConfig struct {
+	LinkBaseURL         string   `yaml:"link_base_url" toml:"link_base_url"`                 // 邮件中链接的前缀, 如 "https://blog.example.com"
+	VerifyEmailExpire   Duration `yaml:"verify_email_expire" toml:"verify_email_expire"`     // 邮箱验证链接有效期
+	ResetPasswordExpire Duration `yaml:"reset_password_expire" toml:"reset_password_expire"` // 重置密码链接有效期
+}
+
+// 评论配置
+type CommentConfig struct {
+	MaxDepth int `yaml:"max_depth" toml:"max_depth"` // 最大嵌套层数, 1表示不允许回复
+}
+
+type LogConfig struct {
+	Env        string `yaml:"env" toml:"env"`                 // dev / staging / prod
+	Filename   string `yaml:"filename" toml:"filename"`       // 日志文件路径
+	MaxSize    int    `yaml:"max_size" toml:"max_size"`       // 单个文件最大MB
+	MaxBackups int    `yaml:"max_backups" toml:"max_backups"` // 最多保留备份数
+	MaxAge     int    `yaml:"max_age" toml:"max_age"`         // 保留天数
+	Compress   bool   `yaml:"compress" toml:"compress"`       // 是否压缩旧日志
+}
+
+// 开发环境默认的jwt密钥, 非开发环境禁止使用
+const defaultJWTSecret = "gblog.com"
+
+// 默认配置
+func defaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr: ":8080",
+		},
+		// dsn包含数据库密码, 没有默认值, 必须通过配置文件、环境变量或命令行参数提供
+		Database: DatabaseConfig{
+			Driver: "mysql",
+		},
+		JWT: JWTConfig{
+			Secret:        defaultJWTSecret,
+			Expire:        Duration(15 * time.Minute),
+			RefreshExpire: Duration(7 * 24 * time.Hour),
+			Issuer:        "gblog",
+		},
+		Siwe: SiweConfig{
+			Domain:      "localhost:8080",
+			NonceExpire: Duration(10 * time.Minute),
+		},
+		RBAC: RBACConfig{
+			DefaultRole: RoleAuthor,
+		},
+		Search: SearchConfig{
+			Engine: searchEngineAuto,
+		},
+		Comment: CommentConfig{
+			MaxDepth: 5,
+		},
+		Post: PostConfig{
+			ScheduleInterval:  Duration(time.Minute),
+			ViewFlushInterval: Duration(10 * time.Second),
+			TrendingWindow:    Duration(7 * 24 * time.Hour),
+		},
+		Upload: UploadConfig{
+			MaxSize:        10,
+			AllowedTypes:   []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf", "text/plain"},
+			ThumbnailWidth: 320,
+			Store:          blobStoreLocal,
+			Dir:            "./uploads",
+		},
+		Feed: FeedConfig{
+			Mode:         feedModeRead,
+			TimelineSize: 500,
+		},
+		Redis: RedisConfig{
+			Addr: "127.0.0.1:6379",
+		},
+		Realtime: RealtimeConfig{
+			Broker: brokerMemory,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracingExporterNone,
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			ServiceName: "gblog",
+			SampleRatio: 1,
+		},
+		Mail: MailConfig{
+			Driver: mailDriverConsole,
+			From:   "GBlog <noreply@localhost>",
+			Dir:    "./mails",
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
+		Account: AccountConfig{
+			LinkBaseURL:         "http://localhost:8080",
+			VerifyEmailExpire:   Duration(24 * time.Hour),
+			ResetPasswordExpire: Duration(30 * time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			Store:   rateLimitStoreMemory,
+			IP:      RateLimit{Rate: 30, Burst: 10},
+			Account: RateLimit{Rate: 10, Burst: 5},
+			Lockout: LockoutConfig{
+				Threshold:   5,
+				Duration:    Duration(time.Minute),
+				MaxDuration: Duration(time.Hour),
+			},
+		},
+		Log: LogConfig{
+			Env:        "dev",
+			Filename:   "./logs/gblog.log",
+			MaxSize:    10,
+			MaxBackups: 30,
+			MaxAge:     7,
+			Compress:   true,
+		},
+	}
+}
+
+// 加载配置, 优先级: 命令行参数 > 环境变量 > 配置文件 > 默认值
+func LoadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("gblog", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "config file path (.yaml/.yml/.toml)")
+	addr := fs.String("addr", "", "listen address, e.g. :8080")
+	dbDriver := fs.String("db-driver", "", "database driver: mysql, postgres or sqlite")
+	dsn := fs.String("dsn", "", "database dsn")
+	jwtSecret := fs.String("jwt-secret", "", "jwt signing secret")
+	logEnv := fs.String("log-env", "", "log env: dev, staging or prod")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := loadConfigFile(*configPath, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	// 只覆盖显式传入的参数
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Server.Addr = *addr
+		case "db-driver":
+			cfg.Database.Driver = *dbDriver
+		case "dsn":
+			cfg.Database.DSN = *dsn
+		case "jwt-secret":
+			cfg.JWT.Secret = *jwtSecret
+		case "log-env":
+			cfg.Log.Env = *logEnv
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// 按扩展名解析yaml或toml配置文件
+func loadConfigFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("unsupported config file type: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return nil
+}
+
+// 使用环境变量覆盖配置
+func applyEnv(cfg *Config) error {
+	strs := map[string]*string{
+		"SERVER_ADDR":           &cfg.Server.Addr,
+		"DATABASE_DRIVER":       &cfg.Database.Driver,
+		"DATABASE_DSN":          &cfg.Database.DSN,
+		"JWT_SECRET":            &cfg.JWT.Secret,
+		"JWT_ISSUER":            &cfg.JWT.Issuer,
+		"SIWE_DOMAIN":           &cfg.Siwe.Domain,
+		"SEARCH_ENGINE":         &cfg.Search.Engine,
+		"UPLOAD_STORE":          &cfg.Upload.Store,
+		"UPLOAD_DIR":            &cfg.Upload.Dir,
+		"UPLOAD_S3_ENDPOINT":    &cfg.Upload.S3.Endpoint,
+		"UPLOAD_S3_ACCESS_KEY":  &cfg.Upload.S3.AccessKey,
+		"UPLOAD_S3_SECRET_KEY":  &cfg.Upload.S3.SecretKey,
+		"UPLOAD_S3_BUCKET":      &cfg.Upload.S3.Bucket,
+		"UPLOAD_S3_REGION":      &cfg.Upload.S3.Region,
+		"FEED_MODE":             &cfg.Feed.Mode,
+		"REDIS_ADDR":            &cfg.Redis.Addr,
+		"REDIS_PASSWORD":        &cfg.Redis.Password,
+		"REALTIME_BROKER":       &cfg.Realtime.Broker,
+		"TRACING_EXPORTER":      &cfg.Tracing.Exporter,
+		"TRACING_ENDPOINT":      &cfg.Tracing.Endpoint,
+		"TRACING_SERVICE_NAME":  &cfg.Tracing.ServiceName,
+		"RATE_LIMIT_STORE":      &cfg.RateLimit.Store,
+		"MAIL_DRIVER":           &cfg.Mail.Driver,
+		"MAIL_FROM":             &cfg.Mail.From,
+		"MAIL_DIR":              &cfg.Mail.Dir,
+		"MAIL_SMTP_HOST":        &cfg.Mail.SMTP.Host,
+		"MAIL_SMTP_USERNAME":    &cfg.Mail.SMTP.Username,
+		"MAIL_SMTP_PASSWORD":    &cfg.Mail.SMTP.Password,
+		"ACCOUNT_LINK_BASE_URL": &cfg.Account.LinkBaseURL,
+		"LOG_ENV":               &cfg.Log.Env,
+		"LOG_FILENAME":          &cfg.Log.Filename,
+	}
+	for key, p := range strs {
+		if v, ok := os.LookupEnv(envPrefix + key); ok {
+			*p = v
+		}
+	}
+
+	ints := map[string]*int{
+		"DATABASE_MAX_OPEN_CONNS":      &cfg.Database.MaxOpenConns,
+		"DATABASE_MAX_IDLE_CONNS":      &cfg.Database.MaxIdleConns,
+		"LOG_MAX_SIZE":                 &cfg.Log.MaxSize,
+		"LOG_MAX_BACKUPS":              &cfg.Log.MaxBackups,
+		"LOG_MAX_AGE":                  &cfg.Log.MaxAge,
+		"COMMENT_MAX_DEPTH":            &cfg.Comment.MaxDepth,
+		"UPLOAD_MAX_SIZE":              &cfg.Upload.MaxSize,
+		"UPLOAD_THUMBNAIL_WIDTH":       &cfg.Upload.ThumbnailWidth,
+		"FEED_TIMELINE_SIZE":           &cfg.Feed.TimelineSize,
+		"REDIS_DB":                     &cfg.Redis.DB,
+		"MAIL_SMTP_PORT":               &cfg.Mail.SMTP.Port,
+		"RATE_LIMIT_IP_RATE":           &cfg.RateLimit.IP.Rate,
+		"RATE_LIMIT_IP_BURST":          &cfg.RateLimit.IP.Burst,
+		"RATE_LIMIT_ACCOUNT_RATE":      &cfg.RateLimit.Account.Rate,
+		"RATE_LIMIT_ACCOUNT_BURST":     &cfg.RateLimit.Account.Burst,
+		"RATE_LIMIT_LOCKOUT_THRESHOLD": &cfg.RateLimit.Lockout.Threshold,
+	}
+	for key, p := range ints {
+		if v, ok := os.LookupEnv(envPrefix + key); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("env %s%s: %w", envPrefix, key, err)
+			}
+			*p = n
+		}
+	}
+
+	if v, ok := os.LookupEnv(envPrefix + "RBAC_DEFAULT_ROLE"); ok {
+		cfg.RBAC.DefaultRole = Role(v)
+	}
+	if v, ok := os.LookupEnv(envPrefix + "RBAC_ADMINS"); ok {
+		cfg.RBAC.Admins = splitList(v)
+	}
+	if v, ok := os.LookupEnv(envPrefix + "SERVER_TRUSTED_PROXIES"); ok {
+		cfg.Server.TrustedProxies = splitList(v)
+	}
+	if v, ok := os.LookupEnv(envPrefix + "SERVER_ALLOWED_ORIGINS"); ok {
+		cfg.Server.AllowedOrigins = splitList(v)
+	}
+	if v, ok := os.LookupEnv(envPrefix + "UPLOAD_ALLOWED_TYPES"); ok {
+		cfg.Upload.AllowedTypes = splitList(v)
+	}
+
+	durations := map[string]*Duration{
+		"JWT_EXPIRE":                      &cfg.JWT.Expire,
+		"JWT_REFRESH_EXPIRE":              &cfg.JWT.RefreshExpire,
+		"SIWE_NONCE_EXPIRE":               &cfg.Siwe.NonceExpire,
+		"POST_SCHEDULE_INTERVAL":          &cfg.Post.ScheduleInterval,
+		"POST_VIEW_FLUSH_INTERVAL":        &cfg.Post.ViewFlushInterval,
+		"POST_TRENDING_WINDOW":            &cfg.Post.TrendingWindow,
+		"RATE_LIMIT_LOCKOUT_DURATION":     &cfg.RateLimit.Lockout.Duration,
+		"ACCOUNT_VERIFY_EMAIL_EXPIRE":     &cfg.Account.VerifyEmailExpire,
+		"ACCOUNT_RESET_PASSWORD_EXPIRE":   &cfg.Account.ResetPasswordExpire,
+		"RATE_LIMIT_LOCKOUT_MAX_DURATION": &cfg.RateLimit.Lockout.MaxDuration,
+	}
+	for key, p := range durations {
+		if v, ok := os.LookupEnv(envPrefix + key); ok {
+			if err := p.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("env %s%s: %w", envPrefix, key, err)
+			}
+		}
+	}
+	bools := map[string]*bool{
+		"DATABASE_AUTO_MIGRATE": &cfg.Database.AutoMigrate,
+		"LOG_COMPRESS":          &cfg.Log.Compress,
+		"TRACING_INSECURE":      &cfg.Tracing.Insecure,
+		"UPLOAD_S3_USE_SSL":     &cfg.Upload.S3.UseSSL,
+	}
+	for key, p := range bools {
+		if v, ok := os.LookupEnv(envPrefix + key); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("env %s%s: %w", envPrefix, key, err)
+			}
+			*p = b
+		}
+	}
+	if v, ok := os.LookupEnv(envPrefix + "TRACING_SAMPLE_RATIO"); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("env %sTRACING_SAMPLE_RATIO: %w", envPrefix, err)
+		}
+		cfg.Tracing.SampleRatio = f
+	}
+	return nil
+}
+
+// 解析逗号分隔的列表, 忽略空项
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// 是否有组件需要连接Redis
+func (c *Config) UseRedis() bool {
+	return c.Realtime.Broker == brokerRedis || c.RateLimit.Store == rateLimitStoreRedis
+}
+
+// 启动时校验配置
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	if _, ok := storageDrivers[c.Database.Driver]; !ok && c.Database.Driver != memoryDriver {
+		errs = append(errs, fmt.Errorf("database.driver must be one of %s, got %q", strings.Join(storageDriverNames(), ", "), c.Database.Driver))
+	}
+	for _, o := range c.Server.AllowedOrigins {
+		if u, err := url.Parse(o); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			errs = append(errs, fmt.Errorf("server.allowed_origins must be scheme://host[:port], got %q", o))
+		}
+	}
+	if c.Database.DSN == "" && c.Database.Driver != memoryDriver {
+		errs = append(errs, errors.New("database.dsn is required"))
+	}
+	switch c.Log.Env {
+	case "dev", "staging", "prod":
+	default:
+		errs = append(errs, fmt.Errorf("log.env must be dev, staging or prod, got %q", c.Log.Env))
+	}
+	if c.JWT.Secret == "" {
+		errs = append(errs, errors.New("jwt.secret is required"))
+	} else if c.Log.Env != "dev" && c.JWT.Secret == defaultJWTSecret {
+		errs = append(errs, errors.New("jwt.secret must be changed outside dev"))
+	}
+	if c.JWT.Expire <= 0 {
+		errs = append(errs, errors.New("jwt.expire must be positive"))
+	}
+	if c.JWT.RefreshExpire <= c.JWT.Expire {
+		errs = append(errs, errors.New("jwt.refresh_expire must be longer than jwt.expire"))
+	}
+	if c.JWT.Issuer == "" {
+		errs = append(errs, errors.New("jwt.issuer is required"))
+	}
+	switch c.Search.Engine {
+	case searchEngineAuto, searchEngineIndex:
+	case searchEngineFulltext:
+		if c.Database.Driver != "mysql" {
+			errs = append(errs, fmt.Errorf("search.engine fulltext requires mysql, got %q", c.Database.Driver))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("search.engine must be auto, fulltext or index, got %q", c.Search.Engine))
+	}
+	if c.Post.ScheduleInterval <= 0 || c.Post.ViewFlushInterval <= 0 || c.Post.TrendingWindow <= 0 {
+		errs = append(errs, errors.New("post.schedule_interval, post.view_flush_interval and post.trending_window must be positive"))
+	}
+	if c.Upload.MaxSize <= 0 || c.Upload.ThumbnailWidth <= 0 {
+		errs = append(errs, errors.New("upload.max_size and upload.thumbnail_width must be positive"))
+	}
+	if len(c.Upload.AllowedTypes) == 0 {
+		errs = append(errs, errors.New("upload.allowed_types is required"))
+	}
+	switch c.Upload.Store {
+	case blobStoreLocal:
+		if c.Upload.Dir == "" {
+			errs = append(errs, errors.New("upload.dir is required for local store"))
+		}
+	case blobStoreS3:
+		if c.Upload.S3.Endpoint == "" || c.Upload.S3.Bucket == "" {
+			errs = append(errs, errors.New("upload.s3.endpoint and upload.s3.bucket are required for s3 store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("upload.store must be local or s3, got %q", c.Upload.Store))
+	}
+	if c.Feed.Mode != feedModeRead && c.Feed.Mode != feedModeWrite {
+		errs = append(errs, fmt.Errorf("feed.mode must be read or write, got %q", c.Feed.Mode))
+	}
+	if c.Feed.TimelineSize <= 0 {
+		errs = append(errs, errors.New("feed.timeline_size must be positive"))
+	}
+	if c.Realtime.Broker != brokerMemory && c.Realtime.Broker != brokerRedis {
+		errs = append(errs, fmt.Errorf("realtime.broker must be memory or redis, got %q", c.Realtime.Broker))
+	}
+	if c.RateLimit.Store != rateLimitStoreMemory && c.RateLimit.Store != rateLimitStoreRedis {
+		errs = append(errs, fmt.Errorf("rate_limit.store must be memory or redis, got %q", c.RateLimit.Store))
+	}
+	if c.UseRedis() && c.Redis.Addr == "" {
+		errs = append(errs, errors.New("redis.addr is required when realtime.broker or rate_limit.store is redis"))
+	}
+	if c.RateLimit.IP.Rate <= 0 || c.RateLimit.IP.Burst <= 0 || c.RateLimit.Account.Rate <= 0 || c.RateLimit.Account.Burst <= 0 {
+		errs = append(errs, errors.New("rate_limit.ip and rate_limit.account rate and burst must be positive"))
+	}
+	if c.RateLimit.Lockout.Threshold <= 0 || c.RateLimit.Lockout.Duration <= 0 || c.RateLimit.Lockout.MaxDuration < c.RateLimit.Lockout.Duration {
+		errs = append(errs, errors.New("rate_limit.lockout.threshold and duration must be positive, max_duration must not be shorter than duration"))
+	}
+	switch c.Mail.Driver {
+	case mailDriverConsole:
+		if c.Log.Env != "dev" {
+			errs = append(errs, errors.New("mail.driver console writes tokens to the log, use file or smtp outside dev"))
+		}
+	case mailDriverFile:
+		if c.Mail.Dir == "" {
+			errs = append(errs, errors.New("mail.dir is required for file driver"))
+		}
+	case mailDriverSMTP:
+		if c.Mail.SMTP.Host == "" || c.Mail.SMTP.Port <= 0 {
+			errs = append(errs, errors.New("mail.smtp.host and mail.smtp.port are required for smtp driver"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail.driver must be console, file or smtp, got %q", c.Mail.Driver))
+	}
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("mail.from is required"))
+	}
+	if c.Account.LinkBaseURL == "" {
+		errs = append(errs, errors.New("account.link_base_url is required"))
+	}
+	if c.Account.VerifyEmailExpire <= 0 || c.Account.ResetPasswordExpire <= 0 {
+		errs = append(errs, errors.New("account.verify_email_expire and account.reset_password_expire must be positive"))
+	}
+	switch c.Tracing.Exporter {
+	case tracingExporterNone, tracingExporterStdout:
+	case tracingExporterOTLP:
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint is required for otlp exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+	if c.Comment.MaxDepth < 1 {
+		errs = append(errs, errors.New("comment.max_depth must be at least 1"))
+	}
+	if c.Siwe.Domain == "" {
+		errs = append(errs, errors.New("siwe.domain is required"))
+	}
+	if c.Siwe.NonceExpire <= 0 {
+		errs = append(errs, errors.New("siwe.nonce_expire must be positive"))
+	}
+	if !c.RBAC.DefaultRole.Valid() || c.RBAC.DefaultRole == RoleAdmin {
+		errs = append(errs, fmt.Errorf("rbac.default_role must be reader, author or moderator, got %q", c.RBAC.DefaultRole))
+	}
+	if c.Log.Filename == "" {
+		errs = append(errs, errors.New("log.filename is required"))
+	}
+	if c.Log.MaxSize <= 0 || c.Log.MaxBackups < 0 || c.Log.MaxAge < 0 {
+		errs = append(errs, errors.New("log.max_size must be positive, log.max_backups and log.max_age must not be negative"))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 点赞, 联合主键保证每个用户对每篇文章只有一条
+type PostLike struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	PostID    uint      `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// 收藏
+type Bookmark struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false"`
+	PostID    uint      `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// 排行榜默认和最大返回数量
+const (
+	defaultRankLimit = 10
+	maxRankLimit     = 50
+)
+
+// 点赞, 重复点赞不报错
+func (h *Handler) LikePostHandler(c *gin.Context) {
+	h.setLike(c, true)
+}
+
+// 取消点赞, 未点赞时不报错
+func (h *Handler) UnlikePostHandler(c *gin.Context) {
+	h.setLike(c, false)
+}
+
+func (h *Handler) setLike(c *gin.Context, liked bool) {
+	op := "LikePost"
+	if !liked {
+		op = "UnlikePost"
+	}
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var count int64
+	var err error
+	if liked {
+		count, err = h.engagement.Like(c.Request.Context(), uid, pid)
+	} else {
+		count, err = h.engagement.Unlike(c.Request.Context(), uid, pid)
+	}
+	if err != nil {
+		respondError(c, op, err)
+		return
+	}
+
+	requestLogger(c).Info(op+" successfully", zap.Uint("post_id", pid))
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"post_id":    pid,
+		"liked":      liked,
+		"like_count": count,
+	})
+}
+
+// 收藏, 重复收藏不报错
+func (h *Handler) BookmarkPostHandler(c *gin.Context) {
+	h.setBookmark(c, true)
+}
+
+// 取消收藏, 未收藏时不报错
+func (h *Handler) UnbookmarkPostHandler(c *gin.Context) {
+	h.setBookmark(c, false)
+}
+
+func (h *Handler) setBookmark(c *gin.Context, bookmarked bool) {
+	op := "BookmarkPost"
+	if !bookmarked {
+		op = "UnbookmarkPost"
+	}
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	var err error
+	if bookmarked {
+		err = h.engagement.Bookmark(c.Request.Context(), uid, pid)
+	} else {
+		err = h.engagement.Unbookmark(c.Request.Context(), uid, pid)
+	}
+	if err != nil {
+		respondError(c, op, err)
+		return
+	}
+
+	requestLogger(c).Info(op+" successfully", zap.Uint("post_id", pid))
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"post_id":    pid,
+		"bookmarked": bookmarked,
+	})
+}
+
+// 当前用户的收藏, 按收藏时间倒序
+func (h *Handler) ListBookmarksHandler(c *gin.Context) {
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		writeProblem(c, err)
+		return
+	}
+	if page.After != nil {
+		writeProblem(c, invalidParam("cursor", "bookmarks do not support cursor"))
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	posts, total, err := h.engagement.Bookmarks(c.Request.Context(), uid, page)
+	if err != nil {
+		respondError(c, "ListBookmarks", err)
+		return
+	}
+
+	items := make([]gin.H, 0, len(posts))
+	for i := range posts {
+		items = append(items, postJSON(&posts[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"posts":   items,
+		"meta": PageMeta{
+			Total:    total,
+			Page:     pageNum,
+			PageSize: page.Limit,
+			HasMore:  int64(page.Offset+len(posts)) < total,
+		},
+	})
+}
+
+// 点赞最多的文章
+func (h *Handler) MostLikedPostsHandler(c *gin.Context) {
+	limit, ok := parseRankLimit(c)
+	if !ok {
+		return
+	}
+	ranked, err := h.engagement.MostLiked(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, "MostLikedPosts", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"posts":   rankedJSON(ranked),
+	})
+}
+
+// 最近一段时间内的热门文章
+func (h *Handler) TrendingPostsHandler(c *gin.Context) {
+	limit, ok := parseRankLimit(c)
+	if !ok {
+		return
+	}
+	ranked, err := h.engagement.Trending(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, "TrendingPosts", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"posts":   rankedJSON(ranked),
+	})
+}
+
+func parseRankLimit(c *gin.Context) (int, bool) {
+	limit := defaultRankLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeProblem(c, invalidParam("limit", "limit must be a positive integer"))
+			return 0, false
+		}
+		limit = min(n, maxRankLimit)
+	}
+	return limit, true
+}
+
+func rankedJSON(ranked []RankedPost) []gin.H {
+	items := make([]gin.H, 0, len(ranked))
+	for _, r := range ranked {
+		item := postJSON(r.Post)
+		item["score"] = r.Score
+		items = append(items, item)
+	}
+	return items
+}
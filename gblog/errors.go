@@ -0,0 +1,88 @@
+package main
+
+import "fmt"
+
+// 错误类别, 决定返回的http状态码, 见problem.go中的kindStatus
+type ErrorKind int
+
+const (
+	KindInternal         ErrorKind = iota // 服务端错误, 不向客户端返回错误详情
+	KindInvalid                           // 请求参数不正确
+	KindUnauthorized                      // 未认证或认证失败
+	KindForbidden                         // 无权限
+	KindNotFound                          // 资源不存在
+	KindConflict                          // 与现有数据冲突
+	KindTooLarge                          // 请求体过大
+	KindUnsupportedMedia                  // 不支持的文件类型
+	KindTooManyRequests                   // 请求过于频繁
+)
+
+// 应用错误: 类别 + 稳定的错误码 + 可以返回给客户端的说明
+//
+// 业务错误定义为 *AppError 类型的哨兵变量, 用 errors.Is 判断; 其它错误按服务端错误处理,
+// 只记录日志, 不会把 err.Error() 返回给客户端
+type AppError struct {
+	Kind    ErrorKind
+	Code    string // 错误码, 如 post_not_found, 客户端据此判断错误类型
+	Message string
+	Fields  []FieldError // 参数校验失败的字段
+	Err     error        // 原始错误, 只用于日志
+}
+
+// 参数校验失败的字段
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+func newAppError(kind ErrorKind, code, message string) *AppError {
+	return &AppError{Kind: kind, Code: code, Message: message}
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error { return e.Err }
+
+// 错误码相同即视为同一错误, 使Withf等返回的副本仍能与哨兵变量匹配
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code == e.Code
+}
+
+// 返回追加了说明的副本
+func (e *AppError) Withf(format string, args ...any) *AppError {
+	cp := *e
+	cp.Message = e.Message + ": " + fmt.Sprintf(format, args...)
+	return &cp
+}
+
+// 返回记录了原始错误的副本, 原始错误不会返回给客户端
+func (e *AppError) Wrap(err error) *AppError {
+	cp := *e
+	cp.Err = err
+	return &cp
+}
+
+// 通用错误
+var (
+	ErrInternal        = newAppError(KindInternal, "internal_error", "internal server error")
+	ErrInvalidRequest  = newAppError(KindInvalid, "invalid_request", "request is not valid")
+	ErrValidation      = newAppError(KindInvalid, "validation_failed", "request parameters are not valid")
+	ErrUnauthenticated = newAppError(KindUnauthorized, "unauthenticated", "authentication is required")
+	ErrRouteNotFound   = newAppError(KindNotFound, "route_not_found", "route not found")
+	ErrRateLimited     = newAppError(KindTooManyRequests, "rate_limited", "too many requests, please try again later")
+)
+
+// 单个参数不正确, 用于路径和查询参数
+func invalidParam(field, message string) *AppError {
+	err := *ErrValidation
+	err.Message = message
+	err.Fields = []FieldError{{Field: field, Message: message}}
+	return &err
+}
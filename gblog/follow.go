@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 关注关系, FollowerID关注了FolloweeID
+type Follow struct {
+	FollowerID uint      `gorm:"primaryKey;autoIncrement:false"`
+	FolloweeID uint      `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+// 解析路径中的用户id
+func validateUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		writeProblem(c, invalidParam("id", "user_id format is not correct"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// 关注列表中的用户信息
+func userSummaryJSON(u *User) gin.H {
+	return gin.H{
+		"id":             u.ID,
+		"username":       u.Username,
+		"display_name":   u.DisplayName,
+		"avatar_url":     u.AvatarURL,
+		"wallet_address": u.WalletAddress,
+	}
+}
+
+// 关注用户, 重复关注不报错
+func (h *Handler) FollowUserHandler(c *gin.Context) {
+	id, ok := validateUserID(c)
+	if !ok {
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.feed.Follow(c.Request.Context(), uid, id); err != nil {
+		respondError(c, "FollowUser", err)
+		return
+	}
+
+	requestLogger(c).Info("FollowUser successfully", zap.Uint("followee_id", id))
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"user_id":   id,
+		"following": true,
+	})
+}
+
+// 取消关注, 未关注时不报错
+func (h *Handler) UnfollowUserHandler(c *gin.Context) {
+	id, ok := validateUserID(c)
+	if !ok {
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.feed.Unfollow(c.Request.Context(), uid, id); err != nil {
+		respondError(c, "UnfollowUser", err)
+		return
+	}
+
+	requestLogger(c).Info("UnfollowUser successfully", zap.Uint("followee_id", id))
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"user_id":   id,
+		"following": false,
+	})
+}
+
+// 关注该用户的人, 按关注时间倒序
+func (h *Handler) ListFollowersHandler(c *gin.Context) {
+	h.listFollows(c, "ListFollowers", h.feed.Followers)
+}
+
+// 该用户关注的人, 按关注时间倒序
+func (h *Handler) ListFollowingHandler(c *gin.Context) {
+	h.listFollows(c, "ListFollowing", h.feed.Following)
+}
+
+func (h *Handler) listFollows(c *gin.Context, op string, list func(ctx context.Context, userID uint, page Page) ([]User, int64, error)) {
+	id, ok := validateUserID(c)
+	if !ok {
+		return
+	}
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		writeProblem(c, err)
+		return
+	}
+	if page.After != nil {
+		writeProblem(c, invalidParam("cursor", "follow list does not support cursor"))
+		return
+	}
+
+	users, total, err := list(c.Request.Context(), id, page)
+	if err != nil {
+		respondError(c, op, err)
+		return
+	}
+
+	items := make([]gin.H, 0, len(users))
+	for i := range users {
+		items = append(items, userSummaryJSON(&users[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"users":   items,
+		"meta": PageMeta{
+			Total:    total,
+			Page:     pageNum,
+			PageSize: page.Limit,
+			HasMore:  int64(page.Offset+len(users)) < total,
+		},
+	})
+}
+
+// 首页动态: 关注的作者已发布的文章, 新文章在前
+func (h *Handler) FeedHandler(c *gin.Context) {
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		writeProblem(c, err)
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	posts, meta, err := h.feed.Feed(c.Request.Context(), uid, page)
+	if err != nil {
+		respondError(c, "Feed", err)
+		return
+	}
+	meta.Page = pageNum
+
+	items := make([]gin.H, 0, len(posts))
+	for i := range posts {
+		items = append(items, postJSON(&posts[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"posts":   items,
+		"meta":    meta,
+	})
+}
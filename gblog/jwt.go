@@ -1,47 +1,67 @@
 package main
 
 import (
-	"net/http"
+	"crypto/rand"
+	"encoding/base64"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
 )
 
-var jwtSecrect = []byte("gblog.com")
+// jwt配置, 启动时由配置文件加载
+var jwtConf JWTConfig
+
+var (
+	ErrTokenMissing   = newAppError(KindUnauthorized, "token_missing", "authorization header has no token")
+	ErrTokenMalformed = newAppError(KindUnauthorized, "token_malformed", "authorization header must be a bearer token")
+	ErrTokenInvalid   = newAppError(KindUnauthorized, "token_invalid", "token is invalid or expired")
+	ErrTokenRevoked   = newAppError(KindUnauthorized, "token_revoked", "token is revoked")
+)
 
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	Role     Role   `json:"role"`
 	jwt.RegisteredClaims
 }
 
-// 生成token
-func GenerateToken(userID uint, username string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
+// 生成access token, 每个token带唯一的jti用于吊销
+func GenerateToken(userID uint, username string, role Role) (string, *Claims, error) {
+	expirationTime := time.Now().Add(time.Duration(jwtConf.Expire))
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
 
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()), // 签发时间
 			NotBefore: jwt.NewNumericDate(time.Now()), // 生效时间
-			Issuer:    "gblog",                        // 签发者
+			Issuer:    jwtConf.Issuer,                 // 签发者
+			ID:        jti,                            // token唯一标识
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecrect)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(jwtConf.Secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
 }
 
 // 解析验证token
 func ParseToken(tokenString string) (*Claims, error) {
 	// 解析
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecrect, nil
-	})
+		return []byte(jwtConf.Secret), nil
+	}, jwt.WithIssuer(jwtConf.Issuer), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 	if err != nil {
 		return nil, err
 	}
@@ -53,30 +73,75 @@ func ParseToken(tokenString string) (*Claims, error) {
 	return nil, jwt.ErrSignatureInvalid
 }
 
-func JwtAuthMiddleware() gin.HandlerFunc {
+// 生成url安全的随机字符串
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// 浏览器的WebSocket和EventSource无法设置请求头, 长连接接口允许通过access_token参数传递token
+func QueryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			return
+		}
+		if token := c.Query("access_token"); token != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// 可选登录, 带token时与JwtAuthMiddleware相同, 不带token时作为未登录用户继续
+func OptionalJwtAuthMiddleware(auth *AuthService) gin.HandlerFunc {
+	required := JwtAuthMiddleware(auth)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			return
+		}
+		required(c)
+	}
+}
+
+func JwtAuthMiddleware(auth *AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.Request.Header.Get("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Header don't have token"})
-			c.Abort()
+			writeProblem(c, ErrTokenMissing)
 			return
 		}
 
 		parts := strings.SplitN(authHeader, " ", 2)
 		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token is illegal"})
-			c.Abort()
+			writeProblem(c, ErrTokenMalformed)
 			return
 		}
 
 		claims, err := ParseToken(parts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token is invalid"})
+			writeProblem(c, ErrTokenInvalid)
+			return
+		}
+
+		// 检查token是否已被吊销
+		revoked, err := auth.IsRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			respondError(c, "check token revoked", err)
+			return
+		}
+		if revoked {
+			writeProblem(c, ErrTokenRevoked)
+			return
 		}
 
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("role", claims.Role)
 		c.Set("tokenExpiresAt", claims.ExpiresAt)
+		c.Set("claims", claims)
+		addLogFields(c, zap.Uint("user_id", claims.UserID))
 
 		c.Next()
 	}
@@ -1,9 +1,23 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
 	"os"
+	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -12,8 +26,9 @@ import (
 var logger *zap.Logger
 
 // 初始化日志配置
-func InitLogger(env string) {
+func InitLogger(cfg LogConfig) {
 	var core zapcore.Core
+	env := cfg.Env
 
 	// 日志输出格式：JSON（生产）或控制台（开发）
 	encoderConfig := zapcore.EncoderConfig{
@@ -47,7 +62,7 @@ func InitLogger(env string) {
 	}
 
 	// 输出目标：控制台 + 文件（按大小/时间切割）
-	writeSyncer := getLogWriter()
+	writeSyncer := getLogWriter(cfg)
 	core = zapcore.NewCore(encoder, writeSyncer, level)
 
 	// 开发环境额外开启调用者信息和堆栈跟踪
@@ -66,14 +81,14 @@ func customTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 }
 
 // 日志文件输出配置（自动切割、压缩、清理）
-func getLogWriter() zapcore.WriteSyncer {
+func getLogWriter(cfg LogConfig) zapcore.WriteSyncer {
 	// 使用lumberjack实现日志轮转
 	lumberJackLogger := &lumberjack.Logger{
-		Filename:   "./logs/gblog.log", // 日志文件路径
-		MaxSize:    10,                 // 单个文件最大10MB
-		MaxBackups: 30,                 // 最多保留30个备份文件
-		MaxAge:     7,                  // 保留7天
-		Compress:   true,               // 压缩旧日志
+		Filename:   cfg.Filename,   // 日志文件路径
+		MaxSize:    cfg.MaxSize,    // 单个文件最大MB
+		MaxBackups: cfg.MaxBackups, // 最多保留备份文件数
+		MaxAge:     cfg.MaxAge,     // 保留天数
+		Compress:   cfg.Compress,   // 压缩旧日志
 	}
 
 	// 同时输出到控制台和文件
@@ -82,3 +97,109 @@ func getLogWriter() zapcore.WriteSyncer {
 		zapcore.AddSync(lumberJackLogger), // 文件
 	)
 }
+
+// 请求id的请求头和响应头
+const requestIDHeader = "X-Request-ID"
+
+var requestIDRe = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type loggerKey struct{}
+
+// 把日志对象放入context
+func WithLogger(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// 取出请求的日志对象, 不在请求中时返回全局日志
+func LoggerFrom(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return zap.L()
+}
+
+// 当前请求的日志对象, 已带有request_id、method、route、client_ip, 认证后带有user_id
+func requestLogger(c *gin.Context) *zap.Logger {
+	return LoggerFrom(c.Request.Context())
+}
+
+// 向请求的日志对象追加字段
+func addLogFields(c *gin.Context, fields ...zap.Field) {
+	ctx := c.Request.Context()
+	c.Request = c.Request.WithContext(WithLogger(ctx, LoggerFrom(ctx).With(fields...)))
+}
+
+// 请求中间件: 分配或沿用X-Request-ID, 创建请求的span和日志对象, 结束后记录访问日志
+func RequestMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(requestIDHeader)
+		if !requestIDRe.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Header(requestIDHeader, id)
+
+		route := c.FullPath()
+		spanName := c.Request.Method + " " + route
+		if route == "" {
+			spanName = c.Request.Method
+		}
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(c.Request.URL.Path),
+				semconv.ClientAddress(c.ClientIP()),
+				attribute.String("http.request_id", id),
+			))
+		defer span.End()
+
+		fields := []zap.Field{
+			zap.String("request_id", id),
+			zap.String("method", c.Request.Method),
+			zap.String("route", route),
+			zap.String("client_ip", c.ClientIP()),
+		}
+		if sc := span.SpanContext(); sc.IsValid() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+		}
+		c.Request = c.Request.WithContext(WithLogger(ctx, zap.L().With(fields...)))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if uid, ok := c.Get("userID"); ok {
+			span.SetAttributes(semconv.UserID(fmt.Sprint(uid)))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		l := requestLogger(c).With(
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("size", c.Writer.Size()),
+		)
+		switch {
+		case status >= http.StatusInternalServerError:
+			l.Error("request")
+		case status >= http.StatusBadRequest:
+			l.Warn("request")
+		default:
+			l.Info("request")
+		}
+	}
+}
+
+// 生成请求id
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
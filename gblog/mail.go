@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 邮件发送方式, 见MailConfig.Driver
+const (
+	mailDriverConsole = "console"
+	mailDriverFile    = "file"
+	mailDriverSMTP    = "smtp"
+)
+
+// 纯文本邮件
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// 发送邮件
+type Mailer interface {
+	Send(ctx context.Context, mail *Mail) error
+}
+
+// 根据配置创建邮件发送
+func NewMailer(cfg MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case mailDriverSMTP:
+		return &SMTPMailer{cfg: cfg.SMTP, from: cfg.From}, nil
+	case mailDriverFile:
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create mail dir %s: %w", cfg.Dir, err)
+		}
+		return &FileMailer{dir: cfg.Dir, from: cfg.From}, nil
+	default:
+		return &ConsoleMailer{from: cfg.From}, nil
+	}
+}
+
+// 生成RFC 5322格式的邮件内容
+func (m *Mail) message(from string, date time.Time) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// 通过SMTP发送, 服务器支持时自动使用STARTTLS
+type SMTPMailer struct {
+	cfg  SMTPConfig
+	from string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, mail *Mail) error {
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// smtp.Client不支持context, 通过连接的deadline限制整个会话, ctx取消时立即中断读写
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.cfg.Host}); err != nil {
+			return err
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(mail.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(mail.message(m.from, time.Now())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// 每封邮件保存为一个.eml文件, 用于本地测试
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func (m *FileMailer) Send(ctx context.Context, mail *Mail) error {
+	now := time.Now()
+	suffix, err := randomToken(4)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000"), suffix)
+	return os.WriteFile(filepath.Join(m.dir, name), mail.message(m.from, now), 0o644)
+}
+
+// 只把邮件写入日志, 邮件中包含验证和重置密码的token, 只允许在开发环境使用
+type ConsoleMailer struct {
+	from string
+}
+
+func (m *ConsoleMailer) Send(ctx context.Context, mail *Mail) error {
+	zap.L().Info("mail", zap.String("from", m.from), zap.String("to", mail.To), zap.String("subject", mail.Subject), zap.String("body", mail.Body))
+	return nil
+}
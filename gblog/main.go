@@ -1,42 +1,259 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
-	"gorm.io/driver/mysql"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
-// 初始化数据库操作对象
-func initDB() *gorm.DB {
-	dsn := "root:liu123@tcp(127.0.0.1:3306)/gblog?charset=utf8mb4&parseTime=true"
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+// 初始化数据库操作对象; 默认只检查表结构是否为最新, 开启auto_migrate时自动执行迁移
+func initDB(ctx context.Context, cfg DatabaseConfig) (*gorm.DB, error) {
+	db, err := openStorage(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init db failed: %w", err)
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.AutoMigrate {
+		if err := migrator.Check(ctx); err != nil {
+			return nil, err
+		}
+		return db, nil
+	}
+	ran, err := migrator.Up(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("migrate db failed: %w", err)
+	}
+	for _, m := range ran {
+		zap.L().Info("migration applied", zap.Uint("version", m.Version), zap.String("name", m.Name))
+	}
+	return db, nil
+}
+
+// 根据配置选择存储实现, memory驱动不连接数据库
+func initRepositories(ctx context.Context, cfg *Config) (*Repositories, error) {
+	if cfg.Database.Driver == memoryDriver {
+		return NewMemoryRepositories(), nil
+	}
+	db, err := initDB(ctx, cfg.Database)
 	if err != nil {
-		panic("Init db failed!")
+		return nil, err
 	}
-	db.AutoMigrate(&User{}, &Post{}, &Comment{})
-	return db
+	repos := NewGormRepositories(db)
+	if repos.Search, err = initSearcher(ctx, cfg, db, repos); err != nil {
+		return nil, fmt.Errorf("init search failed: %w", err)
+	}
+	return repos, nil
 }
 
-var db = initDB()
+// 根据配置选择搜索引擎, 内置倒排索引需从数据库重建
+func initSearcher(ctx context.Context, cfg *Config, db *gorm.DB, repos *Repositories) (Searcher, error) {
+	engine := cfg.Search.Engine
+	if engine == searchEngineAuto {
+		engine = searchEngineIndex
+		if cfg.Database.Driver == "mysql" {
+			engine = searchEngineFulltext
+		}
+	}
+	if engine == searchEngineFulltext {
+		return NewFulltextSearcher(db)
+	}
+	return buildSearchIndex(ctx, repos.Posts, repos.Comments)
+}
+
+// 周期性执行后台任务, ctx取消后退出
+func runPeriodic(ctx context.Context, interval time.Duration, name string, fn func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil {
+				zap.L().Error(name+" failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// gblog migrate up|down|status [n] [flags]: 执行、回滚数据库迁移或查看状态
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: gblog migrate up|down|status [n] [flags]")
+	}
+	cmd, args := args[0], args[1:]
+	n := 0
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v <= 0 {
+			return fmt.Errorf("migration count must be a positive integer: %s", args[0])
+		}
+		n, args = v, args[1:]
+	}
+	cfg, err := LoadConfig(args)
+	if err != nil {
+		return err
+	}
+	if cfg.Database.Driver == memoryDriver {
+		return errors.New("memory driver does not need migrations")
+	}
+	db, err := openStorage(cfg.Database)
+	if err != nil {
+		return err
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var ran []Migration
+	switch cmd {
+	case "up":
+		ran, err = migrator.Up(ctx, n)
+	case "down":
+		ran, err = migrator.Down(ctx, n)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := "-"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, s.State, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, available: up, down, status", cmd)
+	}
+	for _, m := range ran {
+		fmt.Printf("%s %04d_%s\n", cmd, m.Version, m.Name)
+	}
+	if err == nil && len(ran) == 0 {
+		fmt.Println("nothing to migrate")
+	}
+	return err
+}
+
+// gblog openapi [-o file] [-client file]: 输出OpenAPI文档, 或生成Go客户端代码
+func runOpenAPI(args []string) error {
+	fs := flag.NewFlagSet("openapi", flag.ContinueOnError)
+	out := fs.String("o", "", "write the OpenAPI document to file instead of stdout")
+	clientOut := fs.String("client", "", "generate the Go client into file")
+	pkg := fs.String("package", "client", "package name of the generated client")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	doc := buildOpenAPI(apiRoutes)
+	if *clientOut != "" {
+		src, err := generateClient(doc, *pkg)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(*clientOut, src, 0o644)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
 
 func main() {
-	InitLogger("dev")   // 初始化日志
-	defer logger.Sync() // 程序退出时刷新缓冲区
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		if err := runOpenAPI(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := LoadConfig(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-	r := gin.Default()
-	r.POST("/register", PasswordEncrypt(), registerHandler)
-	r.POST("/login", loginHandler)
+	InitLogger(cfg.Log) // 初始化日志
+	defer logger.Sync() // 程序退出时刷新缓冲区
 
-	auth := r.Group("/auth")
-	auth.Use(JwtAuthMiddleware())
+	jwtConf = cfg.JWT
+	ctx := context.Background()
+	shutdownTracing, err := InitTracing(ctx, cfg.Tracing)
+	if err != nil {
+		zap.L().Fatal("init tracing failed", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+	repos, err := initRepositories(ctx, cfg)
+	if err != nil {
+		zap.L().Fatal("init db failed", zap.Error(err))
+	}
+	if repos.Blobs, err = NewBlobStore(ctx, cfg.Upload); err != nil {
+		zap.L().Fatal("init blob store failed", zap.Error(err))
+	}
+	var redisClient *redis.Client
+	if cfg.UseRedis() {
+		if redisClient, err = NewRedisClient(ctx, cfg.Redis); err != nil {
+			zap.L().Fatal("init redis failed", zap.Error(err))
+		}
+	}
+	if repos.Broker, err = NewBroker(ctx, cfg.Realtime, redisClient); err != nil {
+		zap.L().Fatal("init broker failed", zap.Error(err))
+	}
+	repos.RateLimits = NewRateLimitStore(cfg.RateLimit, redisClient)
+	if repos.Mailer, err = NewMailer(cfg.Mail); err != nil {
+		zap.L().Fatal("init mailer failed", zap.Error(err))
+	}
 
-	auth.POST("/post", CreatePostHandler)
-	auth.PUT("/post/:id", UpdatePostHandler)
-	auth.GET("/post/:id", GetPostHandler)
-	auth.DELETE("/post/:id", DeletePostHandler)
+	svc := NewServices(repos, cfg)
+	if err := svc.Users.PromoteAdmins(ctx, cfg.RBAC.Admins); err != nil {
+		zap.L().Fatal("promote admins failed", zap.Error(err))
+	}
+	// 定期清理过期的token和nonce
+	go runPeriodic(ctx, time.Hour, "purge expired tokens", svc.Auth.PurgeExpired)
+	go runPeriodic(ctx, time.Hour, "purge expired siwe nonces", svc.Siwe.PurgeExpired)
+	go runPeriodic(ctx, time.Duration(cfg.Post.ScheduleInterval), "publish scheduled posts", svc.Posts.PublishDue)
+	go runPeriodic(ctx, time.Duration(cfg.Post.ViewFlushInterval), "flush post views", svc.Engagement.FlushViews)
 
-	auth.POST("/post/:id/comment", CreateCommentHandler)
-	auth.GET("/post/:id/comments", GetCommentsByPostID)
+	h := NewHandler(svc)
+	r, err := NewRouter(h, cfg)
+	if err != nil {
+		zap.L().Fatal("init router failed", zap.Error(err))
+	}
 
-	r.Run(":8080")
+	if err := r.Run(cfg.Server.Addr); err != nil {
+		zap.L().Fatal("server stopped", zap.Error(err))
+	}
 }
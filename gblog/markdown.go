@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// 目录项, 子标题嵌套在上级标题下
+type TOCItem struct {
+	Level    int        `json:"level"`
+	Title    string     `json:"title"`
+	ID       string     `json:"id"` // 标题锚点
+	Children []*TOCItem `json:"children,omitempty"`
+}
+
+// Markdown渲染器: GFM语法, 原始html被忽略, 输出再经白名单过滤
+type MarkdownRenderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+func NewMarkdownRenderer() *MarkdownRenderer {
+	policy := bluemonday.UGCPolicy()
+	// 代码块的语言标记, 供前端高亮
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	return &MarkdownRenderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(extension.GFM),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		),
+		policy: policy,
+	}
+}
+
+// 渲染为过滤后的html并生成目录
+func (r *MarkdownRenderer) Render(source string) (string, []*TOCItem, error) {
+	src := []byte(source)
+	doc := r.md.Parser().Parse(text.NewReader(src))
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, src, doc); err != nil {
+		return "", nil, err
+	}
+	return r.policy.Sanitize(buf.String()), buildTOC(doc, src), nil
+}
+
+// 按标题层级生成目录树, 跳级的标题挂在最近的上级标题下
+func buildTOC(doc ast.Node, src []byte) []*TOCItem {
+	toc := make([]*TOCItem, 0)
+	var stack []*TOCItem
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		heading, ok := n.(*ast.Heading)
+		if !ok {
+			continue
+		}
+		item := &TOCItem{Level: heading.Level, Title: nodeText(heading, src)}
+		if id, ok := heading.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				item.ID = string(b)
+			}
+		}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= item.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			toc = append(toc, item)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, item)
+		}
+		stack = append(stack, item)
+	}
+	return toc
+}
+
+// 节点的纯文本, 去掉强调、链接等标记
+func nodeText(n ast.Node, src []byte) string {
+	var buf bytes.Buffer
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := n.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(src))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
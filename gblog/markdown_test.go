@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownRenderSanitize(t *testing.T) {
+	r := NewMarkdownRenderer()
+	tests := []struct {
+		name, source string
+		want, reject []string
+	}{
+		{"script", "hello\n\n<script>alert(1)</script>", []string{"<p>hello</p>"}, []string{"<script", "alert(1)"}},
+		{"inline script", "hello <script>alert(1)</script>", []string{"hello"}, []string{"<script"}},
+		{"javascript link", "[click](javascript:alert(1))", []string{"click"}, []string{"javascript:"}},
+		{"javascript autolink", "<javascript:alert(1)>", nil, []string{`href="javascript:`}},
+		{"onerror", `<img src="x" onerror="alert(1)">`, nil, []string{"onerror"}},
+		{"https link", "[gblog](https://example.com/a?b=1)", []string{`<a href="https://example.com/a?b=1"`}, nil},
+		{"image", "![logo](https://example.com/logo.png)", []string{`<img src="https://example.com/logo.png" alt="logo"`}, nil},
+		{"code language", "```go\nfmt.Println()\n```", []string{`<code class="language-go">`}, nil},
+		{"code language with symbols", "```c++\nint x;\n```", []string{`<code class="language-c++">`}, nil},
+		{"table", "| a |\n| - |\n| b |", []string{"<table>", "<td>b</td>"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, _, err := r.Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(html, s) {
+					t.Errorf("html %q does not contain %q", html, s)
+				}
+			}
+			for _, s := range tt.reject {
+				if strings.Contains(html, s) {
+					t.Errorf("html %q contains %q", html, s)
+				}
+			}
+		})
+	}
+}
+
+// goldmark已忽略原始html, 白名单是第二层防护, 单独检查
+func TestMarkdownPolicy(t *testing.T) {
+	policy := NewMarkdownRenderer().policy
+	tests := []struct {
+		name, html, want string
+	}{
+		{"script", `<p>a</p><script>alert(1)</script>`, `<p>a</p>`},
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `x`},
+		{"onerror", `<img src="https://example.com/x.png" onerror="alert(1)">`, `<img src="https://example.com/x.png">`},
+		{"onclick", `<p onclick="alert(1)">a</p>`, `<p>a</p>`},
+		{"style", `<p style="color:red">a</p>`, `<p>a</p>`},
+		{"code language", `<code class="language-go">x</code>`, `<code class="language-go">x</code>`},
+		{"code language symbols", `<code class="language-c#">x</code>`, `<code class="language-c#">x</code>`},
+		{"code other class", `<code class="evil">x</code>`, `<code>x</code>`},
+		{"code extra class", `<code class="language-go evil">x</code>`, `<code>x</code>`},
+		{"code class injection", `<code class="language-go&#34;onmouseover=&#34;alert(1)">x</code>`, `<code>x</code>`},
+		{"class on other element", `<pre class="language-go">x</pre>`, `<pre>x</pre>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Sanitize(tt.html); got != tt.want {
+				t.Fatalf("Sanitize(%q) = %q, want %q", tt.html, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownTOC(t *testing.T) {
+	source := strings.Join([]string{
+		"# Intro",
+		"text",
+		"## Install **Go**",
+		"#### Skipped `level`",
+		"## Install **Go**",
+		"```",
+		"# not a heading",
+		"```",
+		"> # quoted heading",
+		"# Usage",
+	}, "\n")
+	_, toc, err := NewMarkdownRenderer().Render(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(toc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"level":1,"title":"Intro","id":"intro","children":[` +
+		`{"level":2,"title":"Install Go","id":"install-go","children":[{"level":4,"title":"Skipped level","id":"skipped-level"}]},` +
+		`{"level":2,"title":"Install Go","id":"install-go-1"}]},` +
+		`{"level":1,"title":"Usage","id":"usage"}]`
+	if string(got) != want {
+		t.Fatalf("toc = %s\nwant  %s", got, want)
+	}
+
+	_, toc, err = NewMarkdownRenderer().Render("no headings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if toc == nil || len(toc) != 0 {
+		t.Fatalf("toc without headings = %v, want empty", toc)
+	}
+}
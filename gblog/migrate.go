@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 各数据库的迁移脚本, 目录名为gorm方言名: migrations/<dialect>/<version>_<name>.up.sql 和 .down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+var (
+	ErrMigrationChecksum = errors.New("applied migration has been modified")
+	ErrMigrationMissing  = errors.New("applied migration not found in migration files")
+	ErrMigrationPending  = errors.New("database schema is not up to date, run `gblog migrate up`")
+)
+
+// 迁移状态
+const (
+	migrationApplied  = "applied"
+	migrationPending  = "pending"
+	migrationModified = "modified" // 执行后脚本被修改过
+	migrationMissing  = "missing"  // 已执行但找不到脚本
+)
+
+// 一个版本的迁移脚本
+type Migration struct {
+	Version  uint
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // up和down脚本的sha256
+}
+
+// 已执行的迁移记录
+type SchemaMigration struct {
+	Version   uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	Checksum  string `gorm:"size:64"`
+	AppliedAt time.Time
+}
+
+// 迁移的执行状态, 用于 gblog migrate status
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	State     string
+	AppliedAt *time.Time
+}
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// 读取方言对应的迁移脚本, 按版本升序
+func loadMigrations(files fs.FS, dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database %q: %w", dialect, err)
+	}
+	byVersion := make(map[uint]*Migration)
+	for _, e := range entries {
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", path.Join(dir, e.Name()))
+		}
+		v, err := strconv.ParseUint(m[1], 10, 32)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("invalid migration version %s", path.Join(dir, e.Name()))
+		}
+		data, err := fs.ReadFile(files, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[uint(v)]
+		if !ok {
+			mig = &Migration{Version: uint(v), Name: m[2]}
+			byVersion[uint(v)] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration version %d has different names: %s, %s", v, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down scripts", mig.Version, mig.Name)
+		}
+		// down脚本被修改后回滚的结果也会不同, 一并校验
+		sum := sha256.Sum256([]byte(mig.Up + "\x00" + mig.Down))
+		mig.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// 把脚本拆成单条语句, 每条语句以行尾的分号结束, 忽略--开头的注释行
+func splitStatements(script string) []string {
+	var stmts []string
+	var cur strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		}
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// 数据库迁移, 执行记录保存在schema_migrations表
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	now        func() time.Time
+}
+
+// 根据数据库方言加载迁移脚本
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles, db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, now: time.Now}, nil
+}
+
+// 读取已执行的迁移, 记录表不存在时创建
+func (m *Migrator) applied(ctx context.Context) (map[uint]SchemaMigration, error) {
+	db := m.db.WithContext(ctx)
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		if err := db.Migrator().CreateTable(&SchemaMigration{}); err != nil {
+			return nil, fmt.Errorf("create schema_migrations: %w", err)
+		}
+	}
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[uint]SchemaMigration, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// 所有迁移的状态, 按版本升序
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Version: mig.Version, Name: mig.Name, State: migrationPending}
+		if r, ok := applied[mig.Version]; ok {
+			s.State, s.AppliedAt = migrationApplied, &r.AppliedAt
+			if r.Checksum != mig.Checksum {
+				s.State = migrationModified
+			}
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	for _, r := range applied {
+		statuses = append(statuses, MigrationStatus{Version: r.Version, Name: r.Name, State: migrationMissing, AppliedAt: &r.AppliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// 校验已执行的脚本未被修改且都存在
+func verifyStatuses(statuses []MigrationStatus) error {
+	for _, s := range statuses {
+		switch s.State {
+		case migrationModified:
+			return fmt.Errorf("%w: %04d_%s", ErrMigrationChecksum, s.Version, s.Name)
+		case migrationMissing:
+			return fmt.Errorf("%w: %04d_%s", ErrMigrationMissing, s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// 启动时检查数据库结构是否为最新
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if err := verifyStatuses(statuses); err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if s.State == migrationPending {
+			return fmt.Errorf("%w: %04d_%s is pending", ErrMigrationPending, s.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// 按版本顺序执行未执行的迁移, n<=0时全部执行; 返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context, n int) ([]Migration, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyStatuses(statuses); err != nil {
+		return nil, err
+	}
+	done := make(map[uint]bool)
+	for _, s := range statuses {
+		if s.State == migrationApplied {
+			done[s.Version] = true
+		}
+	}
+
+	var ran []Migration
+	for _, mig := range m.migrations {
+		if done[mig.Version] {
+			continue
+		}
+		if n > 0 && len(ran) == n {
+			break
+		}
+		err := m.run(ctx, mig.Up, func(tx *gorm.DB) error {
+			return tx.Create(&SchemaMigration{Version: mig.Version, Name: mig.Name, Checksum: mig.Checksum, AppliedAt: m.now()}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migrate up %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		ran = append(ran, mig)
+	}
+	return ran, nil
+}
+
+// 按版本倒序回滚最近执行的n个迁移, n<=0时回滚一个; 返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	n = max(n, 1)
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyStatuses(statuses); err != nil {
+		return nil, err
+	}
+	byVersion := make(map[uint]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	var ran []Migration
+	for i := len(statuses) - 1; i >= 0 && len(ran) < n; i-- {
+		if statuses[i].State != migrationApplied {
+			continue
+		}
+		mig := byVersion[statuses[i].Version]
+		err := m.run(ctx, mig.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&SchemaMigration{}, mig.Version).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migrate down %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+		ran = append(ran, mig)
+	}
+	return ran, nil
+}
+
+// 在事务中执行脚本并更新执行记录; MySQL的DDL会隐式提交, 失败时无法完整回滚
+func (m *Migrator) run(ctx context.Context, script string, record func(tx *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range splitStatements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("%w\n%s", err, stmt)
+			}
+		}
+		return record(tx)
+	})
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 迁移后的表结构需要包含的模型
+var migratedModels = []any{
+	&User{}, &Post{}, &Comment{}, &PostRevision{}, &Attachment{}, &PostLike{}, &Bookmark{}, &Follow{},
+	&Notification{}, &Tag{}, &Category{}, &RefreshToken{}, &RevokedToken{}, &UserToken{}, &SiweNonce{},
+}
+
+// 最初版本由AutoMigrate创建的表
+type baselineUser struct {
+	gorm.Model
+	Username string `gorm:"unique"`
+	Password string
+	Email    string
+}
+
+type baselinePost struct {
+	gorm.Model
+	Title   string
+	Content string
+	UserID  uint
+	User    baselineUser
+}
+
+type baselineComment struct {
+	gorm.Model
+	Content string
+	UserID  uint
+	User    baselineUser
+	PostID  uint
+	Post    baselinePost
+}
+
+func (baselineUser) TableName() string    { return "users" }
+func (baselinePost) TableName() string    { return "posts" }
+func (baselineComment) TableName() string { return "comments" }
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "gblog.db")), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// 检查每个模型的字段和索引都已存在
+func checkSchema(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	m := db.Migrator()
+	for _, model := range migratedModels {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		table := stmt.Schema.Table
+		if !m.HasTable(model) {
+			t.Errorf("table %s not found", table)
+			continue
+		}
+		for _, f := range stmt.Schema.Fields {
+			if f.DBName != "" && !m.HasColumn(model, f.DBName) {
+				t.Errorf("column %s.%s not found", table, f.DBName)
+			}
+		}
+		for _, idx := range stmt.Schema.ParseIndexes() {
+			if !m.HasIndex(model, idx.Name) {
+				t.Errorf("index %s on %s not found", idx.Name, table)
+			}
+		}
+	}
+	if !m.HasTable("post_tags") {
+		t.Error("table post_tags not found")
+	}
+}
+
+// 迁移到最新版本后表结构完整, 全部回滚后只剩迁移记录表
+func testMigrateUpAndDown(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ran, err := migrator.Up(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(migrator.migrations) {
+		t.Fatalf("ran %d migrations, want %d", len(ran), len(migrator.migrations))
+	}
+	checkSchema(t, db)
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrator.Down(ctx, len(ran)); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range tables {
+		if table != "schema_migrations" && table != "sqlite_sequence" {
+			t.Errorf("table %s left after rolling back all migrations", table)
+		}
+	}
+	if err := migrator.Check(ctx); !errors.Is(err, ErrMigrationPending) {
+		t.Fatalf("Check after down = %v, want ErrMigrationPending", err)
+	}
+}
+
+// 最初版本AutoMigrate创建的数据库可以直接执行迁移, 数据保留
+func testMigrateUpFromBaselineAutoMigrate(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	if err := db.AutoMigrate(&baselineUser{}, &baselinePost{}, &baselineComment{}); err != nil {
+		t.Fatal(err)
+	}
+	user := baselineUser{Username: "alice", Password: "hash", Email: "alice@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	post := baselinePost{Title: "hello", Content: "world", UserID: user.ID}
+	if err := db.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&baselineComment{Content: "first", UserID: user.ID, PostID: post.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	checkSchema(t, db)
+
+	var u User
+	if err := db.First(&u, user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "alice" || u.Role != RoleAuthor {
+		t.Fatalf("user after migrate = %s %s, want alice author", u.Username, u.Role)
+	}
+	var p Post
+	if err := db.First(&p, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != PostPublished || p.PublishedAt == nil {
+		t.Fatalf("post after migrate: status %s, published_at %v", p.Status, p.PublishedAt)
+	}
+	var c Comment
+	if err := db.Where("post_id = ?", post.ID).First(&c).Error; err != nil {
+		t.Fatal(err)
+	}
+	if c.ParentID != nil || c.Content != "first" {
+		t.Fatalf("comment after migrate: parent %v, content %q", c.ParentID, c.Content)
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	testMigrateUpAndDown(t, openTestDB(t))
+}
+
+func TestMigrateUpFromBaselineAutoMigrate(t *testing.T) {
+	testMigrateUpFromBaselineAutoMigrate(t, openTestDB(t))
+}
+
+// 连接环境变量指定的空数据库, 未设置时跳过; 测试结束后删除创建的表
+func openEnvTestDB(t *testing.T, driver, env string) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s is not set", env)
+	}
+	db, err := gorm.Open(storageDrivers[driver](dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 防止误用有数据的库
+	if len(tables) > 0 {
+		t.Fatalf("database in %s must be empty, found tables %v", env, tables)
+	}
+	t.Cleanup(func() {
+		tables, err := db.Migrator().GetTables()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for _, table := range tables {
+			if err := db.Migrator().DropTable(table); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	return db
+}
+
+// MySQL和Postgres的迁移需要真实的数据库, 如:
+//
+//	GBLOG_TEST_MYSQL_DSN='root:pass@tcp(127.0.0.1:3306)/gblog_test?charset=utf8mb4&parseTime=True&loc=Local'
+//	GBLOG_TEST_POSTGRES_DSN='host=127.0.0.1 user=postgres password=pass dbname=gblog_test sslmode=disable'
+func TestMigrateOtherDrivers(t *testing.T) {
+	for _, tt := range []struct{ driver, env string }{
+		{"mysql", "GBLOG_TEST_MYSQL_DSN"},
+		{"postgres", "GBLOG_TEST_POSTGRES_DSN"},
+	} {
+		t.Run(tt.driver, func(t *testing.T) {
+			t.Run("up and down", func(t *testing.T) {
+				testMigrateUpAndDown(t, openEnvTestDB(t, tt.driver, tt.env))
+			})
+			t.Run("from baseline", func(t *testing.T) {
+				testMigrateUpFromBaselineAutoMigrate(t, openEnvTestDB(t, tt.driver, tt.env))
+			})
+		})
+	}
+}
+
+// 只在部分数据库中存在的迁移版本
+var dialectOnlyMigrations = map[string][]uint{
+	"mysql": {16}, // FULLTEXT索引
+}
+
+// 每个数据库的迁移都有up和down脚本, 版本连续, 除特有的版本外与SQLite版本号和名称一致
+func TestMigrationFilesConsistent(t *testing.T) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := loadMigrations(migrationFiles, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[uint]string, len(want))
+	for _, m := range want {
+		names[m.Version] = m.Name
+	}
+
+	for _, e := range entries {
+		dialect := e.Name()
+		if _, ok := storageDrivers[dialect]; !ok || !e.IsDir() {
+			t.Errorf("migrations/%s is not a directory of a database driver", dialect)
+			continue
+		}
+		// loadMigrations检查文件名和up、down是否成对
+		migrations, err := loadMigrations(migrationFiles, dialect)
+		if err != nil {
+			t.Errorf("%s: %v", dialect, err)
+			continue
+		}
+		got := make(map[uint]string, len(migrations))
+		for i, m := range migrations {
+			if m.Version != uint(i+1) {
+				t.Errorf("%s: migration %04d_%s, want version %d", dialect, m.Version, m.Name, i+1)
+			}
+			got[m.Version] = m.Name
+			if name, ok := names[m.Version]; ok && name != m.Name {
+				t.Errorf("%s: migration %d is named %s, want %s", dialect, m.Version, m.Name, name)
+			} else if !ok && !slices.Contains(dialectOnlyMigrations[dialect], m.Version) {
+				t.Errorf("%s: migration %04d_%s is missing in sqlite", dialect, m.Version, m.Name)
+			}
+		}
+		for v, name := range names {
+			if _, ok := got[v]; !ok {
+				t.Errorf("%s: migration %04d_%s is missing", dialect, v, name)
+			}
+		}
+	}
+	for driver := range storageDrivers {
+		if _, err := fs.Stat(migrationFiles, "migrations/"+driver); err != nil {
+			t.Errorf("no migrations for driver %s", driver)
+		}
+	}
+}
+
+// 修改down脚本也视为修改了已执行的迁移
+func TestMigrationChecksumCoversDown(t *testing.T) {
+	files := fstest.MapFS{
+		"migrations/sqlite/0001_init.up.sql":   {Data: []byte("CREATE TABLE t (id integer);")},
+		"migrations/sqlite/0001_init.down.sql": {Data: []byte("DROP TABLE t;")},
+	}
+	before, err := loadMigrations(files, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files["migrations/sqlite/0001_init.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE IF EXISTS t;")}
+	after, err := loadMigrations(files, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before[0].Checksum == after[0].Checksum {
+		t.Fatal("checksum did not change after modifying the down script")
+	}
+}
@@ -0,0 +1,5 @@
+-- 删除初始表, 数据会丢失
+
+DROP TABLE IF EXISTS `comments`;
+DROP TABLE IF EXISTS `posts`;
+DROP TABLE IF EXISTS `users`;
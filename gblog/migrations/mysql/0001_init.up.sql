@@ -0,0 +1,43 @@
+-- 初始表结构, 与最初版本AutoMigrate创建的users、posts、comments表一致;
+-- 已有这些表的数据库执行时会跳过, 之后的迁移在其上增加字段和表
+-- MySQL的DDL不支持事务, 执行失败时需手动清理已创建的表
+
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `username` varchar(191),
+  `password` longtext,
+  `email` longtext,
+  PRIMARY KEY (`id`),
+  INDEX `idx_users_deleted_at` (`deleted_at`),
+  CONSTRAINT `uni_users_username` UNIQUE (`username`)
+);
+
+CREATE TABLE IF NOT EXISTS `posts` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `title` longtext,
+  `content` longtext,
+  `user_id` bigint unsigned,
+  PRIMARY KEY (`id`),
+  INDEX `idx_posts_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_posts_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `comments` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `content` longtext,
+  `user_id` bigint unsigned,
+  `post_id` bigint unsigned,
+  PRIMARY KEY (`id`),
+  INDEX `idx_comments_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_comments_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+  CONSTRAINT `fk_comments_post` FOREIGN KEY (`post_id`) REFERENCES `posts`(`id`)
+);
@@ -0,0 +1,25 @@
+-- refresh token和已吊销的access token
+
+CREATE TABLE `refresh_tokens` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `token_hash` varchar(64),
+  `family_id` varchar(64),
+  `access_jti` varchar(64),
+  `access_expires_at` datetime(3) NULL,
+  `expires_at` datetime(3) NULL,
+  `revoked_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_refresh_tokens_user_id` (`user_id`),
+  UNIQUE INDEX `idx_refresh_tokens_token_hash` (`token_hash`),
+  INDEX `idx_refresh_tokens_family_id` (`family_id`)
+);
+
+CREATE TABLE `revoked_tokens` (
+  `jti` varchar(64),
+  `expires_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`jti`),
+  INDEX `idx_revoked_tokens_expires_at` (`expires_at`)
+);
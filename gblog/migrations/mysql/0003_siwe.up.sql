@@ -0,0 +1,14 @@
+-- 钱包登录: 用户的钱包地址和登录nonce
+
+ALTER TABLE `users`
+  ADD COLUMN `wallet_address` varchar(42);
+
+CREATE UNIQUE INDEX `idx_users_wallet_address` ON `users` (`wallet_address`);
+
+CREATE TABLE `siwe_nonces` (
+  `nonce` varchar(64),
+  `expires_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`nonce`),
+  INDEX `idx_siwe_nonces_expires_at` (`expires_at`)
+);
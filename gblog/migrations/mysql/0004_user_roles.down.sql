@@ -0,0 +1,4 @@
+-- 回滚用户角色
+
+ALTER TABLE `users`
+  DROP COLUMN `role`;
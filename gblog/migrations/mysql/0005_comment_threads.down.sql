@@ -0,0 +1,7 @@
+-- 回滚嵌套评论
+
+DROP INDEX `idx_comments_parent_id` ON `comments`;
+ALTER TABLE `comments`
+  DROP COLUMN `edited_at`,
+  DROP COLUMN `depth`,
+  DROP COLUMN `parent_id`;
@@ -0,0 +1,36 @@
+-- 标签和分类
+
+ALTER TABLE `posts`
+  ADD COLUMN `category_id` bigint unsigned;
+
+CREATE INDEX `idx_posts_category_id` ON `posts` (`category_id`);
+
+CREATE TABLE `tags` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(32),
+  `slug` varchar(64),
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_tags_slug` (`slug`)
+);
+
+CREATE TABLE `post_tags` (
+  `post_id` bigint unsigned,
+  `tag_id` bigint unsigned,
+  PRIMARY KEY (`post_id`,`tag_id`),
+  CONSTRAINT `fk_post_tags_post` FOREIGN KEY (`post_id`) REFERENCES `posts`(`id`),
+  CONSTRAINT `fk_post_tags_tag` FOREIGN KEY (`tag_id`) REFERENCES `tags`(`id`)
+);
+
+CREATE TABLE `categories` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `name` varchar(64),
+  `slug` varchar(64),
+  `description` longtext,
+  `parent_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_categories_slug` (`slug`),
+  INDEX `idx_categories_parent_id` (`parent_id`)
+);
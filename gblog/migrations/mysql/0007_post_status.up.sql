@@ -0,0 +1,12 @@
+-- 文章状态和定时发布, 已有文章为已发布
+
+ALTER TABLE `posts`
+  ADD COLUMN `status` varchar(16) DEFAULT 'published',
+  ADD COLUMN `publish_at` datetime(3) NULL,
+  ADD COLUMN `published_at` datetime(3) NULL;
+
+CREATE INDEX `idx_posts_status` ON `posts` (`status`);
+
+CREATE INDEX `idx_posts_publish_at` ON `posts` (`publish_at`);
+
+UPDATE `posts` SET `published_at` = `created_at`;
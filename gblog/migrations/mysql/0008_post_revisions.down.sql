@@ -0,0 +1,3 @@
+-- 回滚文章历史版本
+
+DROP TABLE IF EXISTS `post_revisions`;
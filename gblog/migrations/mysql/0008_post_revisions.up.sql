@@ -0,0 +1,14 @@
+-- 文章历史版本
+
+CREATE TABLE `post_revisions` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `post_id` bigint unsigned,
+  `version` bigint,
+  `title` longtext,
+  `content` longtext,
+  `author_id` bigint unsigned,
+  `note` varchar(64),
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_post_version` (`post_id`,`version`)
+);
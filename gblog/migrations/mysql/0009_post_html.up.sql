@@ -0,0 +1,6 @@
+-- Markdown渲染结果和目录, 旧文章在读取时渲染
+-- 渲染后的html通常比源文长, 与content一样使用longtext, text最多64KB
+
+ALTER TABLE `posts`
+  ADD COLUMN `content_html` longtext,
+  ADD COLUMN `toc` longtext;
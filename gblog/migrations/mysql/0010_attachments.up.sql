@@ -0,0 +1,19 @@
+-- 文章附件
+
+CREATE TABLE `attachments` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `post_id` bigint unsigned,
+  `user_id` bigint unsigned,
+  `filename` varchar(255),
+  `content_type` varchar(100),
+  `size` bigint,
+  `file_key` varchar(128),
+  `thumb_key` varchar(128),
+  `width` bigint,
+  `height` bigint,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_attachments_post_id` (`post_id`),
+  UNIQUE INDEX `idx_attachments_file_key` (`file_key`),
+  INDEX `idx_attachments_thumb_key` (`thumb_key`)
+);
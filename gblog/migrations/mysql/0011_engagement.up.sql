@@ -0,0 +1,22 @@
+-- 点赞、收藏和浏览数
+
+ALTER TABLE `posts`
+  ADD COLUMN `view_count` bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE `post_likes` (
+  `user_id` bigint unsigned,
+  `post_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`user_id`,`post_id`),
+  INDEX `idx_post_likes_post_id` (`post_id`),
+  INDEX `idx_post_likes_created_at` (`created_at`)
+);
+
+CREATE TABLE `bookmarks` (
+  `user_id` bigint unsigned,
+  `post_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`user_id`,`post_id`),
+  INDEX `idx_bookmarks_post_id` (`post_id`),
+  INDEX `idx_bookmarks_created_at` (`created_at`)
+);
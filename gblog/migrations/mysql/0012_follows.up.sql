@@ -0,0 +1,10 @@
+-- 关注关系
+
+CREATE TABLE `follows` (
+  `follower_id` bigint unsigned,
+  `followee_id` bigint unsigned,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`follower_id`,`followee_id`),
+  INDEX `idx_follows_followee_id` (`followee_id`),
+  INDEX `idx_follows_created_at` (`created_at`)
+);
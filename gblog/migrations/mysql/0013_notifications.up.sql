@@ -0,0 +1,14 @@
+-- 通知
+
+CREATE TABLE `notifications` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `actor_id` bigint unsigned,
+  `type` varchar(16),
+  `post_id` bigint unsigned,
+  `comment_id` bigint unsigned,
+  `read_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_notification_user_read` (`user_id`,`read_at`)
+);
@@ -0,0 +1,7 @@
+-- 回滚邮箱验证和找回密码
+
+DROP TABLE IF EXISTS `user_tokens`;
+DROP INDEX `idx_users_email` ON `users`;
+ALTER TABLE `users`
+  DROP COLUMN `email_verified_at`;
+ALTER TABLE `users` MODIFY COLUMN `email` longtext;
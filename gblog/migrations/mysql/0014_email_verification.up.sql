@@ -0,0 +1,23 @@
+-- 邮箱验证和找回密码: 邮箱改为定长并加索引, 一次性token
+
+ALTER TABLE `users` MODIFY COLUMN `email` varchar(255);
+
+ALTER TABLE `users`
+  ADD COLUMN `email_verified_at` datetime(3) NULL;
+
+CREATE INDEX `idx_users_email` ON `users` (`email`);
+
+CREATE TABLE `user_tokens` (
+  `id` bigint unsigned AUTO_INCREMENT,
+  `user_id` bigint unsigned,
+  `purpose` varchar(16),
+  `token_hash` varchar(64),
+  `email` varchar(255),
+  `expires_at` datetime(3) NULL,
+  `used_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_user_tokens_user_id` (`user_id`),
+  UNIQUE INDEX `idx_user_tokens_token_hash` (`token_hash`),
+  INDEX `idx_user_tokens_expires_at` (`expires_at`)
+);
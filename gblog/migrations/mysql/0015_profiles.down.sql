@@ -0,0 +1,8 @@
+-- 回滚用户资料和封禁
+
+DROP INDEX `idx_users_suspended_at` ON `users`;
+ALTER TABLE `users`
+  DROP COLUMN `suspended_at`,
+  DROP COLUMN `bio`,
+  DROP COLUMN `avatar_url`,
+  DROP COLUMN `display_name`;
@@ -0,0 +1,5 @@
+-- 删除全文搜索的FULLTEXT索引, 之后使用fulltext搜索引擎时无法启动
+
+DROP INDEX `ft_comments_content` ON `comments`;
+DROP INDEX `ft_posts_title` ON `posts`;
+DROP INDEX `ft_posts_title_content` ON `posts`;
@@ -0,0 +1,8 @@
+-- 全文搜索使用的FULLTEXT索引, ngram分词器支持中文; 只有MySQL使用, 其他数据库使用内置倒排索引
+-- 已有大量数据时建索引耗时较长
+
+CREATE FULLTEXT INDEX `ft_posts_title_content` ON `posts` (`title`, `content`) WITH PARSER ngram;
+
+CREATE FULLTEXT INDEX `ft_posts_title` ON `posts` (`title`) WITH PARSER ngram;
+
+CREATE FULLTEXT INDEX `ft_comments_content` ON `comments` (`content`) WITH PARSER ngram;
@@ -0,0 +1,42 @@
+-- 初始表结构, 与最初版本AutoMigrate创建的users、posts、comments表一致;
+-- 已有这些表的数据库执行时会跳过, 之后的迁移在其上增加字段和表
+
+CREATE TABLE IF NOT EXISTS "users" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "username" text,
+  "password" text,
+  "email" text,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "uni_users_username" UNIQUE ("username")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "posts" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "title" text,
+  "content" text,
+  "user_id" bigint,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_posts_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_posts_deleted_at" ON "posts" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "comments" (
+  "id" bigserial,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  "deleted_at" timestamptz,
+  "content" text,
+  "user_id" bigint,
+  "post_id" bigint,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "fk_comments_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+  CONSTRAINT "fk_comments_post" FOREIGN KEY ("post_id") REFERENCES "posts"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_comments_deleted_at" ON "comments" ("deleted_at");
@@ -0,0 +1,4 @@
+-- 回滚refresh token和已吊销的access token
+
+DROP TABLE IF EXISTS "revoked_tokens";
+DROP TABLE IF EXISTS "refresh_tokens";
@@ -0,0 +1,25 @@
+-- refresh token和已吊销的access token
+
+CREATE TABLE "refresh_tokens" (
+  "id" bigserial,
+  "user_id" bigint,
+  "token_hash" varchar(64),
+  "family_id" varchar(64),
+  "access_jti" varchar(64),
+  "access_expires_at" timestamptz,
+  "expires_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+CREATE UNIQUE INDEX "idx_refresh_tokens_token_hash" ON "refresh_tokens" ("token_hash");
+CREATE INDEX "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
+
+CREATE TABLE "revoked_tokens" (
+  "jti" varchar(64),
+  "expires_at" timestamptz,
+  "created_at" timestamptz,
+  PRIMARY KEY ("jti")
+);
+CREATE INDEX "idx_revoked_tokens_expires_at" ON "revoked_tokens" ("expires_at");
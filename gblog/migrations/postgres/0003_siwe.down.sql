@@ -0,0 +1,6 @@
+-- 回滚钱包登录
+
+DROP TABLE IF EXISTS "siwe_nonces";
+DROP INDEX "idx_users_wallet_address";
+ALTER TABLE "users"
+  DROP COLUMN "wallet_address";
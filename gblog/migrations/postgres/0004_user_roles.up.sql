@@ -0,0 +1,4 @@
+-- 用户角色, 已有用户为author
+
+ALTER TABLE "users"
+  ADD COLUMN "role" varchar(16) DEFAULT 'author';
@@ -0,0 +1,8 @@
+-- 嵌套评论: 父评论、层级和修改时间
+
+ALTER TABLE "comments"
+  ADD COLUMN "parent_id" bigint,
+  ADD COLUMN "depth" bigint,
+  ADD COLUMN "edited_at" timestamptz;
+
+CREATE INDEX "idx_comments_parent_id" ON "comments" ("parent_id");
@@ -0,0 +1,8 @@
+-- 回滚标签和分类
+
+DROP TABLE IF EXISTS "categories";
+DROP TABLE IF EXISTS "post_tags";
+DROP TABLE IF EXISTS "tags";
+DROP INDEX "idx_posts_category_id";
+ALTER TABLE "posts"
+  DROP COLUMN "category_id";
@@ -0,0 +1,36 @@
+-- 标签和分类
+
+ALTER TABLE "posts"
+  ADD COLUMN "category_id" bigint;
+
+CREATE INDEX "idx_posts_category_id" ON "posts" ("category_id");
+
+CREATE TABLE "tags" (
+  "id" bigserial,
+  "name" varchar(32),
+  "slug" varchar(64),
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_tags_slug" ON "tags" ("slug");
+
+CREATE TABLE "post_tags" (
+  "post_id" bigint,
+  "tag_id" bigint,
+  PRIMARY KEY ("post_id","tag_id"),
+  CONSTRAINT "fk_post_tags_post" FOREIGN KEY ("post_id") REFERENCES "posts"("id"),
+  CONSTRAINT "fk_post_tags_tag" FOREIGN KEY ("tag_id") REFERENCES "tags"("id")
+);
+
+CREATE TABLE "categories" (
+  "id" bigserial,
+  "name" varchar(64),
+  "slug" varchar(64),
+  "description" text,
+  "parent_id" bigint,
+  "created_at" timestamptz,
+  "updated_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_categories_parent_id" ON "categories" ("parent_id");
+CREATE UNIQUE INDEX "idx_categories_slug" ON "categories" ("slug");
@@ -0,0 +1,8 @@
+-- 回滚文章状态和定时发布
+
+DROP INDEX "idx_posts_publish_at";
+DROP INDEX "idx_posts_status";
+ALTER TABLE "posts"
+  DROP COLUMN "published_at",
+  DROP COLUMN "publish_at",
+  DROP COLUMN "status";
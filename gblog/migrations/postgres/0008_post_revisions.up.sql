@@ -0,0 +1,14 @@
+-- 文章历史版本
+
+CREATE TABLE "post_revisions" (
+  "id" bigserial,
+  "post_id" bigint,
+  "version" bigint,
+  "title" text,
+  "content" text,
+  "author_id" bigint,
+  "note" varchar(64),
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_post_version" ON "post_revisions" ("post_id","version");
@@ -0,0 +1,5 @@
+-- 回滚Markdown渲染结果和目录
+
+ALTER TABLE "posts"
+  DROP COLUMN "toc",
+  DROP COLUMN "content_html";
@@ -0,0 +1,3 @@
+-- 回滚文章附件
+
+DROP TABLE IF EXISTS "attachments";
@@ -0,0 +1,19 @@
+-- 文章附件
+
+CREATE TABLE "attachments" (
+  "id" bigserial,
+  "post_id" bigint,
+  "user_id" bigint,
+  "filename" varchar(255),
+  "content_type" varchar(100),
+  "size" bigint,
+  "file_key" varchar(128),
+  "thumb_key" varchar(128),
+  "width" bigint,
+  "height" bigint,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_attachments_thumb_key" ON "attachments" ("thumb_key");
+CREATE UNIQUE INDEX "idx_attachments_file_key" ON "attachments" ("file_key");
+CREATE INDEX "idx_attachments_post_id" ON "attachments" ("post_id");
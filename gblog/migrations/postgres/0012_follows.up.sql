@@ -0,0 +1,10 @@
+-- 关注关系
+
+CREATE TABLE "follows" (
+  "follower_id" bigint,
+  "followee_id" bigint,
+  "created_at" timestamptz,
+  PRIMARY KEY ("follower_id","followee_id")
+);
+CREATE INDEX "idx_follows_created_at" ON "follows" ("created_at");
+CREATE INDEX "idx_follows_followee_id" ON "follows" ("followee_id");
@@ -0,0 +1,3 @@
+-- 回滚通知
+
+DROP TABLE IF EXISTS "notifications";
@@ -0,0 +1,14 @@
+-- 通知
+
+CREATE TABLE "notifications" (
+  "id" bigserial,
+  "user_id" bigint,
+  "actor_id" bigint,
+  "type" varchar(16),
+  "post_id" bigint,
+  "comment_id" bigint,
+  "read_at" timestamptz,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_notification_user_read" ON "notifications" ("user_id","read_at");
@@ -0,0 +1,23 @@
+-- 邮箱验证和找回密码: 邮箱改为定长并加索引, 一次性token
+
+ALTER TABLE "users" ALTER COLUMN "email" TYPE varchar(255);
+
+ALTER TABLE "users"
+  ADD COLUMN "email_verified_at" timestamptz;
+
+CREATE INDEX "idx_users_email" ON "users" ("email");
+
+CREATE TABLE "user_tokens" (
+  "id" bigserial,
+  "user_id" bigint,
+  "purpose" varchar(16),
+  "token_hash" varchar(64),
+  "email" varchar(255),
+  "expires_at" timestamptz,
+  "used_at" timestamptz,
+  "created_at" timestamptz,
+  PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_user_tokens_expires_at" ON "user_tokens" ("expires_at");
+CREATE UNIQUE INDEX "idx_user_tokens_token_hash" ON "user_tokens" ("token_hash");
+CREATE INDEX "idx_user_tokens_user_id" ON "user_tokens" ("user_id");
@@ -0,0 +1,9 @@
+-- 用户资料和封禁
+
+ALTER TABLE "users"
+  ADD COLUMN "display_name" varchar(64),
+  ADD COLUMN "avatar_url" varchar(512),
+  ADD COLUMN "bio" varchar(500),
+  ADD COLUMN "suspended_at" timestamptz;
+
+CREATE INDEX "idx_users_suspended_at" ON "users" ("suspended_at");
@@ -0,0 +1,24 @@
+-- refresh token和已吊销的access token
+
+CREATE TABLE `refresh_tokens` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `token_hash` text,
+  `family_id` text,
+  `access_jti` text,
+  `access_expires_at` datetime,
+  `expires_at` datetime,
+  `revoked_at` datetime,
+  `created_at` datetime
+);
+CREATE INDEX `idx_refresh_tokens_family_id` ON `refresh_tokens`(`family_id`);
+CREATE UNIQUE INDEX `idx_refresh_tokens_token_hash` ON `refresh_tokens`(`token_hash`);
+CREATE INDEX `idx_refresh_tokens_user_id` ON `refresh_tokens`(`user_id`);
+
+CREATE TABLE `revoked_tokens` (
+  `jti` text,
+  `expires_at` datetime,
+  `created_at` datetime,
+  PRIMARY KEY (`jti`)
+);
+CREATE INDEX `idx_revoked_tokens_expires_at` ON `revoked_tokens`(`expires_at`);
@@ -0,0 +1,13 @@
+-- 钱包登录: 用户的钱包地址和登录nonce
+
+ALTER TABLE `users` ADD COLUMN `wallet_address` text;
+
+CREATE UNIQUE INDEX `idx_users_wallet_address` ON `users`(`wallet_address`);
+
+CREATE TABLE `siwe_nonces` (
+  `nonce` text,
+  `expires_at` datetime,
+  `created_at` datetime,
+  PRIMARY KEY (`nonce`)
+);
+CREATE INDEX `idx_siwe_nonces_expires_at` ON `siwe_nonces`(`expires_at`);
@@ -0,0 +1,3 @@
+-- 用户角色, 已有用户为author
+
+ALTER TABLE `users` ADD COLUMN `role` text DEFAULT 'author';
@@ -0,0 +1,6 @@
+-- 回滚嵌套评论
+
+DROP INDEX `idx_comments_parent_id`;
+ALTER TABLE `comments` DROP COLUMN `edited_at`;
+ALTER TABLE `comments` DROP COLUMN `depth`;
+ALTER TABLE `comments` DROP COLUMN `parent_id`;
@@ -0,0 +1,9 @@
+-- 嵌套评论: 父评论、层级和修改时间
+
+ALTER TABLE `comments` ADD COLUMN `parent_id` integer;
+
+ALTER TABLE `comments` ADD COLUMN `depth` integer;
+
+ALTER TABLE `comments` ADD COLUMN `edited_at` datetime;
+
+CREATE INDEX `idx_comments_parent_id` ON `comments`(`parent_id`);
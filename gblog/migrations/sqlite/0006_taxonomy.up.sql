@@ -0,0 +1,33 @@
+-- 标签和分类
+
+ALTER TABLE `posts` ADD COLUMN `category_id` integer;
+
+CREATE INDEX `idx_posts_category_id` ON `posts`(`category_id`);
+
+CREATE TABLE `tags` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text,
+  `slug` text,
+  `created_at` datetime
+);
+CREATE UNIQUE INDEX `idx_tags_slug` ON `tags`(`slug`);
+
+CREATE TABLE `post_tags` (
+  `post_id` integer,
+  `tag_id` integer,
+  PRIMARY KEY (`post_id`,`tag_id`),
+  CONSTRAINT `fk_post_tags_tag` FOREIGN KEY (`tag_id`) REFERENCES `tags`(`id`),
+  CONSTRAINT `fk_post_tags_post` FOREIGN KEY (`post_id`) REFERENCES `posts`(`id`)
+);
+
+CREATE TABLE `categories` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `name` text,
+  `slug` text,
+  `description` text,
+  `parent_id` integer,
+  `created_at` datetime,
+  `updated_at` datetime
+);
+CREATE INDEX `idx_categories_parent_id` ON `categories`(`parent_id`);
+CREATE UNIQUE INDEX `idx_categories_slug` ON `categories`(`slug`);
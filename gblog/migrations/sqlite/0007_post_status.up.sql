@@ -0,0 +1,13 @@
+-- 文章状态和定时发布, 已有文章为已发布
+
+ALTER TABLE `posts` ADD COLUMN `status` text DEFAULT 'published';
+
+ALTER TABLE `posts` ADD COLUMN `publish_at` datetime;
+
+ALTER TABLE `posts` ADD COLUMN `published_at` datetime;
+
+CREATE INDEX `idx_posts_status` ON `posts`(`status`);
+
+CREATE INDEX `idx_posts_publish_at` ON `posts`(`publish_at`);
+
+UPDATE `posts` SET `published_at` = `created_at`;
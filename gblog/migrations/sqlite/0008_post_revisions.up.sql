@@ -0,0 +1,13 @@
+-- 文章历史版本
+
+CREATE TABLE `post_revisions` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `post_id` integer,
+  `version` integer,
+  `title` text,
+  `content` text,
+  `author_id` integer,
+  `note` text,
+  `created_at` datetime
+);
+CREATE UNIQUE INDEX `idx_post_version` ON `post_revisions`(`post_id`,`version`);
@@ -0,0 +1,4 @@
+-- 回滚Markdown渲染结果和目录
+
+ALTER TABLE `posts` DROP COLUMN `toc`;
+ALTER TABLE `posts` DROP COLUMN `content_html`;
@@ -0,0 +1,5 @@
+-- Markdown渲染结果和目录, 旧文章在读取时渲染
+
+ALTER TABLE `posts` ADD COLUMN `content_html` text;
+
+ALTER TABLE `posts` ADD COLUMN `toc` text;
@@ -0,0 +1,18 @@
+-- 文章附件
+
+CREATE TABLE `attachments` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `post_id` integer,
+  `user_id` integer,
+  `filename` text,
+  `content_type` text,
+  `size` integer,
+  `file_key` text,
+  `thumb_key` text,
+  `width` integer,
+  `height` integer,
+  `created_at` datetime
+);
+CREATE INDEX `idx_attachments_thumb_key` ON `attachments`(`thumb_key`);
+CREATE UNIQUE INDEX `idx_attachments_file_key` ON `attachments`(`file_key`);
+CREATE INDEX `idx_attachments_post_id` ON `attachments`(`post_id`);
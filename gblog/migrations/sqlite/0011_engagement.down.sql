@@ -0,0 +1,5 @@
+-- 回滚点赞、收藏和浏览数
+
+DROP TABLE IF EXISTS `bookmarks`;
+DROP TABLE IF EXISTS `post_likes`;
+ALTER TABLE `posts` DROP COLUMN `view_count`;
@@ -0,0 +1,21 @@
+-- 点赞、收藏和浏览数
+
+ALTER TABLE `posts` ADD COLUMN `view_count` integer NOT NULL DEFAULT 0;
+
+CREATE TABLE `post_likes` (
+  `user_id` integer,
+  `post_id` integer,
+  `created_at` datetime,
+  PRIMARY KEY (`user_id`,`post_id`)
+);
+CREATE INDEX `idx_post_likes_created_at` ON `post_likes`(`created_at`);
+CREATE INDEX `idx_post_likes_post_id` ON `post_likes`(`post_id`);
+
+CREATE TABLE `bookmarks` (
+  `user_id` integer,
+  `post_id` integer,
+  `created_at` datetime,
+  PRIMARY KEY (`user_id`,`post_id`)
+);
+CREATE INDEX `idx_bookmarks_created_at` ON `bookmarks`(`created_at`);
+CREATE INDEX `idx_bookmarks_post_id` ON `bookmarks`(`post_id`);
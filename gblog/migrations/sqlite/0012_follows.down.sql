@@ -0,0 +1,3 @@
+-- 回滚关注关系
+
+DROP TABLE IF EXISTS `follows`;
@@ -0,0 +1,13 @@
+-- 通知
+
+CREATE TABLE `notifications` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `actor_id` integer,
+  `type` text,
+  `post_id` integer,
+  `comment_id` integer,
+  `read_at` datetime,
+  `created_at` datetime
+);
+CREATE INDEX `idx_notification_user_read` ON `notifications`(`user_id`,`read_at`);
@@ -0,0 +1,19 @@
+-- 邮箱验证和找回密码: 邮箱改为定长并加索引, 一次性token
+
+ALTER TABLE `users` ADD COLUMN `email_verified_at` datetime;
+
+CREATE INDEX `idx_users_email` ON `users`(`email`);
+
+CREATE TABLE `user_tokens` (
+  `id` integer PRIMARY KEY AUTOINCREMENT,
+  `user_id` integer,
+  `purpose` text,
+  `token_hash` text,
+  `email` text,
+  `expires_at` datetime,
+  `used_at` datetime,
+  `created_at` datetime
+);
+CREATE INDEX `idx_user_tokens_expires_at` ON `user_tokens`(`expires_at`);
+CREATE UNIQUE INDEX `idx_user_tokens_token_hash` ON `user_tokens`(`token_hash`);
+CREATE INDEX `idx_user_tokens_user_id` ON `user_tokens`(`user_id`);
@@ -0,0 +1,7 @@
+-- 回滚用户资料和封禁
+
+DROP INDEX `idx_users_suspended_at`;
+ALTER TABLE `users` DROP COLUMN `suspended_at`;
+ALTER TABLE `users` DROP COLUMN `bio`;
+ALTER TABLE `users` DROP COLUMN `avatar_url`;
+ALTER TABLE `users` DROP COLUMN `display_name`;
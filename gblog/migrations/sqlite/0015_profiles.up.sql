@@ -0,0 +1,11 @@
+-- 用户资料和封禁
+
+ALTER TABLE `users` ADD COLUMN `display_name` text;
+
+ALTER TABLE `users` ADD COLUMN `avatar_url` text;
+
+ALTER TABLE `users` ADD COLUMN `bio` text;
+
+ALTER TABLE `users` ADD COLUMN `suspended_at` datetime;
+
+CREATE INDEX `idx_users_suspended_at` ON `users`(`suspended_at`);
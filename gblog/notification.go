@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type NotificationType string
+
+const (
+	NotifyComment NotificationType = "comment" // 文章收到评论
+	NotifyReply   NotificationType = "reply"   // 评论收到回复
+	NotifyFollow  NotificationType = "follow"  // 被关注
+	NotifyMention NotificationType = "mention" // 在文章或评论中被@
+)
+
+// 通知, UserID为接收者, ActorID为触发者
+type Notification struct {
+	ID        uint             `gorm:"primarykey" json:"id"`
+	UserID    uint             `gorm:"index:idx_notification_user_read" json:"-"`
+	ActorID   uint             `json:"actor_id"`
+	Actor     string           `gorm:"-" json:"actor"` // 触发者用户名, 由service填充
+	Type      NotificationType `gorm:"size:16" json:"type"`
+	PostID    uint             `json:"post_id,omitempty"`
+	CommentID uint             `json:"comment_id,omitempty"`
+	ReadAt    *time.Time       `gorm:"index:idx_notification_user_read" json:"read_at"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// SSE心跳间隔, 防止代理断开空闲连接
+const notificationHeartbeat = 30 * time.Second
+
+// 通知列表, 按时间倒序; unread=true时只返回未读通知
+func (h *Handler) ListNotificationsHandler(c *gin.Context) {
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		writeProblem(c, err)
+		return
+	}
+	unreadOnly := false
+	if v := c.Query("unread"); v != "" {
+		if unreadOnly, err = strconv.ParseBool(v); err != nil {
+			writeProblem(c, invalidParam("unread", "unread must be a boolean"))
+			return
+		}
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	notifications, meta, err := h.notifications.List(ctx, uid, unreadOnly, page)
+	if err != nil {
+		respondError(c, "ListNotifications", err)
+		return
+	}
+	meta.Page = pageNum
+	unread, err := h.notifications.UnreadCount(ctx, uid)
+	if err != nil {
+		respondError(c, "ListNotifications", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"notifications": notifications,
+		"unread_count":  unread,
+		"meta":          meta,
+	})
+}
+
+func (h *Handler) UnreadNotificationCountHandler(c *gin.Context) {
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+	count, err := h.notifications.UnreadCount(c.Request.Context(), uid)
+	if err != nil {
+		respondError(c, "UnreadNotificationCount", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":      true,
+		"unread_count": count,
+	})
+}
+
+// 标记单条通知已读, 已读的通知重复标记不报错
+func (h *Handler) MarkNotificationReadHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("nid"), 10, 64)
+	if err != nil {
+		writeProblem(c, invalidParam("nid", "notification_id format is not correct"))
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.notifications.MarkRead(c.Request.Context(), uid, uint(id)); err != nil {
+		respondError(c, "MarkNotificationRead", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"notification_id": id,
+	})
+}
+
+func (h *Handler) MarkAllNotificationsReadHandler(c *gin.Context) {
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+	n, err := h.notifications.MarkAllRead(c.Request.Context(), uid)
+	if err != nil {
+		respondError(c, "MarkAllNotificationsRead", err)
+		return
+	}
+
+	requestLogger(c).Info("MarkAllNotificationsRead successfully", zap.Int64("count", n))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"updated": n,
+	})
+}
+
+// 通知的SSE推送: 连接后先发送unread事件, 之后每条新通知发送notification事件
+func (h *Handler) NotificationStreamHandler(c *gin.Context) {
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	events, err := h.notifications.Subscribe(ctx, uid)
+	if err != nil {
+		respondError(c, "NotificationStream", err)
+		return
+	}
+	// 先订阅再查询, 避免漏掉两者之间产生的通知
+	count, err := h.notifications.UnreadCount(ctx, uid)
+	if err != nil {
+		respondError(c, "NotificationStream", err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // 关闭nginx缓冲
+	c.SSEvent("unread", gin.H{"unread_count": count})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(notificationHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case msg, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent("notification", json.RawMessage(msg))
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// OpenAPI 3.0 文档, 只包含用到的字段
+type OpenAPI struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// 接口描述, 与NewRouter注册的路由一一对应, 用于生成OpenAPI文档和客户端
+type apiRoute struct {
+	Method  string
+	Path    string // gin路由路径, 如 /auth/post/:id
+	ID      string // operationId, 也是生成的客户端方法名
+	Tag     string
+	Summary string
+	Public  bool       // 不需要token
+	Query   []apiParam // 查询参数
+	Form    []apiParam // 表单参数, 以x-www-form-urlencoded或multipart/form-data提交
+	// 表单包含文件, 以multipart/form-data提交
+	Multipart bool
+	// 200响应中success之外的字段
+	Result map[string]*Schema
+	// 非json响应的Content-Type, 如文件下载和SSE, WebSocket接口为rawWebSocket; 客户端不生成这类接口
+	Raw string
+}
+
+const rawWebSocket = "websocket"
+
+type apiParam struct {
+	Name        string
+	Schema      *Schema
+	Required    bool
+	Description string
+}
+
+func str() *Schema                  { return &Schema{Type: "string"} }
+func integer() *Schema              { return &Schema{Type: "integer", Format: "int64"} }
+func boolean() *Schema              { return &Schema{Type: "boolean"} }
+func arrayOf(items *Schema) *Schema { return &Schema{Type: "array", Items: items} }
+func ref(name string) *Schema       { return &Schema{Ref: "#/components/schemas/" + name} }
+func enum(values ...string) *Schema { return &Schema{Type: "string", Enum: values} }
+
+func dateTime() *Schema { return &Schema{Type: "string", Format: "date-time"} }
+
+func nullable(s *Schema) *Schema {
+	s.Nullable = true
+	return s
+}
+
+func object(props map[string]*Schema) *Schema {
+	return &Schema{Type: "object", Properties: props}
+}
+
+func param(name string, s *Schema, description string) apiParam {
+	return apiParam{Name: name, Schema: s, Description: description}
+}
+
+// 分页查询参数, 见parsePage
+var pageParams = []apiParam{
+	param("page", integer(), "页码, 从1开始"),
+	param("page_size", integer(), fmt.Sprintf("每页数量, 默认%d, 最大%d", defaultPageSize, maxPageSize)),
+	param("cursor", str(), "上一页返回的next_cursor, 提供时按游标分页并忽略page"),
+}
+
+// 只支持页码分页的接口
+var offsetPageParams = pageParams[:2]
+
+// 类型对应的枚举值
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(Role("")):             {string(RoleAdmin), string(RoleModerator), string(RoleAuthor), string(RoleReader)},
+	reflect.TypeOf(PostStatus("")):       {string(PostDraft), string(PostScheduled), string(PostPublished), string(PostArchived)},
+	reflect.TypeOf(NotificationType("")): {string(NotifyComment), string(NotifyReply), string(NotifyFollow), string(NotifyMention)},
+}
+
+// 枚举类型的schema, 如 enumOf(PostStatus(""))
+func enumOf(v any) *Schema {
+	return enum(schemaEnums[reflect.TypeOf(v)]...)
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// 根据Go类型生成schema, 具名结构体登记到components中并返回引用
+type schemaBuilder struct {
+	components map[string]*Schema
+}
+
+func (b *schemaBuilder) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return dateTime()
+	case deletedAtType:
+		return nullable(dateTime())
+	}
+	if values, ok := schemaEnums[t]; ok {
+		return enum(values...)
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := b.schemaOf(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.Struct:
+		if _, ok := b.components[t.Name()]; !ok {
+			// 先占位, 处理递归引用
+			b.components[t.Name()] = &Schema{}
+			*b.components[t.Name()] = *b.structSchema(t)
+		}
+		return ref(t.Name())
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return arrayOf(b.schemaOf(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schemaOf(t.Elem())}
+	case reflect.String:
+		return str()
+	case reflect.Bool:
+		return boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return integer()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s := integer()
+		s.Minimum = new(float64)
+		return s
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	default:
+		return &Schema{}
+	}
+}
+
+// 按encoding/json的规则展开结构体字段, 匿名嵌入的结构体字段提升到外层
+func (b *schemaBuilder) structSchema(t reflect.Type) *Schema {
+	s := object(map[string]*Schema{})
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			for k, v := range b.structSchema(f.Type).Properties {
+				s.Properties[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = b.schemaOf(f.Type)
+	}
+	return s
+}
+
+// 根据请求结构体的form和binding标签生成表单或查询参数
+func formParams(v any) []apiParam {
+	t := reflect.TypeOf(v)
+	b := &schemaBuilder{components: map[string]*Schema{}}
+	var params []apiParam
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		s := b.schemaOf(f.Type)
+		s.Nullable = false
+		p := apiParam{Name: name, Schema: s}
+		for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+			rule, arg, _ := strings.Cut(rule, "=")
+			n, _ := strconv.Atoi(arg)
+			switch rule {
+			case "required":
+				p.Required = true
+			case "min":
+				s.MinLength = &n
+			case "max":
+				s.MaxLength = &n
+			case "email":
+				s.Format = "email"
+			case "eq":
+				// 或规则, 如 eq=|http_url 表示可以为空
+				if _, alt, ok := strings.Cut(arg, "|"); ok && alt == "http_url" {
+					s.Format = "uri"
+				} else if alt == "email" {
+					s.Format = "email"
+				}
+			}
+		}
+		params = append(params, p)
+	}
+	return params
+}
+
+// 响应中直接序列化的类型, 以及由xxxJSON函数手工组装的对象
+func componentSchemas() map[string]*Schema {
+	b := &schemaBuilder{components: map[string]*Schema{}}
+	for _, v := range []any{
+		Problem{}, PageMeta{}, Tag{}, TagCount{}, Category{}, CategoryNode{}, PostRevision{},
+		Notification{}, SearchHit{}, CommentNode{}, TOCItem{},
+	} {
+		b.schemaOf(reflect.TypeOf(v))
+	}
+
+	// 见postJSON
+	post := func() map[string]*Schema {
+		return map[string]*Schema{
+			"id":            integer(),
+			"title":         str(),
+			"content":       str(),
+			"user_id":       integer(),
+			"category_id":   nullable(integer()),
+			"tags":          arrayOf(ref("Tag")),
+			"status":        enumOf(PostStatus("")),
+			"publish_at":    nullable(dateTime()),
+			"published_at":  nullable(dateTime()),
+			"comment_count": integer(),
+			"like_count":    integer(),
+			"view_count":    integer(),
+			"created":       {Type: "string", Description: "创建时间, 格式 2006-01-02 15:04:05"},
+			"updated":       {Type: "string", Description: "修改时间, 格式 2006-01-02 15:04:05"},
+		}
+	}
+	b.components["Post"] = object(post())
+	detail := post()
+	detail["format"] = enum(postFormatMarkdown, postFormatHTML)
+	detail["liked"] = boolean()
+	detail["bookmarked"] = boolean()
+	detail["toc"] = arrayOf(ref("TOCItem"))
+	detail["content"].Description = "format为html时为渲染后的html"
+	b.components["PostDetail"] = object(detail)
+	ranked := post()
+	ranked["score"] = integer()
+	b.components["RankedPost"] = object(ranked)
+
+	// 见commentJSON
+	b.components["Comment"] = object(map[string]*Schema{
+		"id":        integer(),
+		"content":   str(),
+		"post_id":   integer(),
+		"user_id":   integer(),
+		"parent_id": nullable(integer()),
+		"depth":     integer(),
+		"edited_at": nullable(dateTime()),
+	})
+	// 见attachmentJSON
+	b.components["Attachment"] = object(map[string]*Schema{
+		"id":            integer(),
+		"post_id":       integer(),
+		"user_id":       integer(),
+		"filename":      str(),
+		"content_type":  str(),
+		"size":          integer(),
+		"width":         integer(),
+		"height":        integer(),
+		"url":           str(),
+		"thumbnail_url": str(),
+		"created_at":    dateTime(),
+	})
+	// 见userSummaryJSON、profileJSON、accountJSON
+	summary := map[string]*Schema{
+		"id":             integer(),
+		"username":       str(),
+		"display_name":   str(),
+		"avatar_url":     str(),
+		"wallet_address": nullable(str()),
+	}
+	b.components["UserSummary"] = object(summary)
+	profile := map[string]*Schema{"bio": str(), "created_at": dateTime()}
+	for k, v := range summary {
+		profile[k] = v
+	}
+	b.components["Profile"] = object(profile)
+	account := map[string]*Schema{
+		"email":             str(),
+		"email_verified_at": nullable(dateTime()),
+		"role":              enumOf(Role("")),
+		"suspended_at":      nullable(dateTime()),
+	}
+	for k, v := range profile {
+		account[k] = v
+	}
+	b.components["Account"] = object(account)
+	// 见ListPostRevisionsHandler
+	b.components["RevisionSummary"] = object(map[string]*Schema{
+		"version":    integer(),
+		"title":      str(),
+		"author_id":  integer(),
+		"note":       str(),
+		"created_at": dateTime(),
+	})
+	return b.components
+}
+
+// 见tokenPairResponse
+var tokenPairResult = map[string]*Schema{
+	"token":         str(),
+	"refresh_token": str(),
+	"expires_in":    {Type: "integer", Format: "int64", Description: "access token剩余有效秒数"},
+}
+
+var pathParamRe = regexp.MustCompile(`[:*](\w+)`)
+
+// 生成OpenAPI文档
+func buildOpenAPI(routes []apiRoute) *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: "3.0.3",
+		Info:    OpenAPIInfo{Title: "GBlog API", Version: "1.0.0"},
+		Paths:   map[string]map[string]*Operation{},
+		Components: Components{
+			Schemas: componentSchemas(),
+			SecuritySchemes: map[string]*SecurityScheme{
+				"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	for _, r := range routes {
+		op := &Operation{
+			OperationID: r.ID,
+			Summary:     r.Summary,
+			Tags:        []string{r.Tag},
+			Responses: map[string]*Response{
+				"default": {Description: "错误", Content: map[string]*MediaType{problemContentType: {Schema: ref("Problem")}}},
+			},
+		}
+		if !r.Public {
+			op.Security = []map[string][]string{{"bearerAuth": {}}}
+		}
+
+		for _, m := range pathParamRe.FindAllStringSubmatch(r.Path, -1) {
+			s := integer()
+			if m[0][0] == '*' {
+				s = str()
+			}
+			op.Parameters = append(op.Parameters, &Parameter{Name: m[1], In: "path", Required: true, Schema: s})
+		}
+		for _, p := range r.Query {
+			op.Parameters = append(op.Parameters, &Parameter{Name: p.Name, In: "query", Required: p.Required, Description: p.Description, Schema: p.Schema})
+		}
+
+		if len(r.Form) > 0 {
+			body := object(map[string]*Schema{})
+			for _, p := range r.Form {
+				s := *p.Schema
+				if p.Description != "" {
+					s.Description = p.Description
+				}
+				body.Properties[p.Name] = &s
+				if p.Required {
+					body.Required = append(body.Required, p.Name)
+				}
+			}
+			contentType := "application/x-www-form-urlencoded"
+			if r.Multipart {
+				contentType = "multipart/form-data"
+			}
+			op.RequestBody = &RequestBody{Required: len(body.Required) > 0, Content: map[string]*MediaType{contentType: {Schema: body}}}
+		}
+
+		if r.Raw == rawWebSocket {
+			op.Responses["101"] = &Response{Description: "升级为WebSocket连接"}
+		} else if r.Raw != "" {
+			op.Responses["200"] = &Response{Description: "成功", Content: map[string]*MediaType{r.Raw: {}}}
+		} else {
+			result := map[string]*Schema{"success": boolean()}
+			for k, v := range r.Result {
+				result[k] = v
+			}
+			op.Responses["200"] = &Response{Description: "成功", Content: map[string]*MediaType{"application/json": {Schema: object(result)}}}
+		}
+
+		path := pathParamRe.ReplaceAllString(r.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+		doc.Paths[path][strings.ToLower(r.Method)] = op
+	}
+	return doc
+}
+
+// 校验注册的路由与接口描述一致, 避免文档和实现不同步
+func checkAPIRoutes(registered gin.RoutesInfo, routes []apiRoute) error {
+	documented := make(map[string]bool, len(routes))
+	ids := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		documented[r.Method+" "+r.Path] = true
+		if ids[r.ID] {
+			return fmt.Errorf("openapi: duplicate operation id %s", r.ID)
+		}
+		ids[r.ID] = true
+	}
+	var missing []string
+	for _, r := range registered {
+		key := r.Method + " " + r.Path
+		if !documented[key] {
+			missing = append(missing, key+" (not documented)")
+		}
+		delete(documented, key)
+	}
+	for key := range documented {
+		missing = append(missing, key+" (not registered)")
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("openapi: routes and apiRoutes are out of sync: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// 返回OpenAPI文档
+func openAPIHandler(doc *OpenAPI) (gin.HandlerFunc, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}, nil
+}
+
+// Swagger UI页面, 静态资源来自unpkg
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>GBlog API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui", persistAuthorization: true});
+  </script>
+</body>
+</html>
+`
+
+func swaggerUIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// 生成的Go字段名中保持大写的缩写
+var goInitialisms = map[string]string{
+	"id":   "ID",
+	"url":  "URL",
+	"html": "HTML",
+	"toc":  "TOC",
+	"ip":   "IP",
+	"api":  "API",
+	"json": "JSON",
+}
+
+// snake_case转为Go导出名, 如 thumbnail_url -> ThumbnailURL
+func goName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' }) {
+		if v, ok := goInitialisms[strings.ToLower(part)]; ok {
+			b.WriteString(v)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 根据OpenAPI文档生成Go客户端代码
+//
+// 生成的代码依赖同一个包中手写的Client.do、File和formatValue; 非json响应的接口(文件下载、SSE、WebSocket)不生成
+type clientGenerator struct {
+	decls   []string
+	imports map[string]bool
+}
+
+func generateClient(doc *OpenAPI, pkg string) ([]byte, error) {
+	g := &clientGenerator{imports: map[string]bool{"context": true}}
+
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		g.structType(name, "", doc.Components.Schemas[name])
+	}
+
+	type pathOp struct {
+		path, method string
+		op           *Operation
+	}
+	var ops []pathOp
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			ops = append(ops, pathOp{path, method, op})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].op.OperationID < ops[j].op.OperationID })
+	for _, o := range ops {
+		g.operation(o.path, strings.ToUpper(o.method), o.op)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"gblog openapi\"; DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	for _, imp := range sortedKeys(g.imports) {
+		fmt.Fprintf(&buf, "\t%q\n", imp)
+	}
+	buf.WriteString(")\n")
+	for _, decl := range g.decls {
+		buf.WriteString("\n" + decl)
+	}
+	return format.Source(buf.Bytes())
+}
+
+// 生成结构体, 内联的对象类型以 父类型名+字段名 命名
+func (g *clientGenerator) structType(name, doc string, s *Schema) {
+	var b strings.Builder
+	if doc != "" {
+		fmt.Fprintf(&b, "// %s %s\n", name, doc)
+	}
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	for _, prop := range sortedKeys(s.Properties) {
+		p := s.Properties[prop]
+		if p.Description != "" {
+			fmt.Fprintf(&b, "\t// %s\n", p.Description)
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", goName(prop), g.goType(p, name+goName(prop)), prop)
+	}
+	b.WriteString("}\n")
+	g.decls = append(g.decls, b.String())
+}
+
+// schema对应的Go类型; 可为null的标量和引用的对象用指针
+func (g *clientGenerator) goType(s *Schema, hint string) string {
+	if s.Ref != "" {
+		return "*" + strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	}
+	var t string
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			t = "time.Time"
+		case "byte":
+			return "[]byte"
+		case "binary":
+			return "*File"
+		default:
+			t = "string"
+		}
+	case "integer":
+		t = "int64"
+	case "number":
+		t = "float64"
+	case "boolean":
+		t = "bool"
+	case "array":
+		return "[]" + strings.TrimPrefix(g.goType(s.Items, hint+"Item"), "*")
+	case "object":
+		if s.Properties != nil {
+			g.structType(hint, "", s)
+			return "*" + hint
+		}
+		if s.AdditionalProperties != nil {
+			return "map[string]" + g.goType(s.AdditionalProperties, hint+"Value")
+		}
+		fallthrough
+	default:
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	}
+	if s.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// 请求参数, 必填参数用值类型, 可选参数用指针, nil表示不提交
+type clientParam struct {
+	name, in, description string
+	schema                *Schema
+	required              bool
+	goType                string
+}
+
+// 生成一个接口的请求类型、响应类型和方法
+func (g *clientGenerator) operation(path, method string, op *Operation) {
+	ok200 := op.Responses["200"]
+	if ok200 == nil || ok200.Content["application/json"] == nil {
+		return
+	}
+	name := op.OperationID
+
+	var pathParams, params []clientParam
+	for _, p := range op.Parameters {
+		cp := clientParam{name: p.Name, in: p.In, description: p.Description, schema: p.Schema, required: p.Required}
+		if p.In == "path" {
+			pathParams = append(pathParams, cp)
+		} else {
+			params = append(params, cp)
+		}
+	}
+	multipart := false
+	if op.RequestBody != nil {
+		for contentType, media := range op.RequestBody.Content {
+			multipart = contentType == "multipart/form-data"
+			for _, prop := range sortedKeys(media.Schema.Properties) {
+				cp := clientParam{name: prop, in: "form", schema: media.Schema.Properties[prop]}
+				cp.description = cp.schema.Description
+				for _, r := range media.Schema.Required {
+					cp.required = cp.required || r == prop
+				}
+				params = append(params, cp)
+			}
+		}
+	}
+
+	reqType := name + "Request"
+	respType := name + "Response"
+	if len(params) > 0 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "// %s %s的请求参数\n", reqType, name)
+		fmt.Fprintf(&b, "type %s struct {\n", reqType)
+		for i, p := range params {
+			if p.description != "" {
+				fmt.Fprintf(&b, "\t// %s\n", p.description)
+			}
+			t := g.goType(p.schema, reqType+goName(p.name))
+			params[i].goType = t
+			if !p.required && !strings.HasPrefix(t, "*") && !strings.HasPrefix(t, "[]") {
+				t = "*" + t
+			}
+			fmt.Fprintf(&b, "\t%s %s\n", goName(p.name), t)
+		}
+		b.WriteString("}\n")
+		g.decls = append(g.decls, b.String())
+	}
+	g.structType(respType, name+"的响应", ok200.Content["application/json"].Schema)
+
+	// 方法
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s %s\n//\n// %s %s\n", name, op.Summary, method, path)
+	args := []string{"ctx context.Context"}
+	pathFmt, pathArgs := path, []string{}
+	for _, p := range pathParams {
+		verb, t := "%d", "int64"
+		if p.schema.Type == "string" {
+			verb, t = "%s", "string"
+		}
+		args = append(args, goParamName(p.name)+" "+t)
+		pathFmt = strings.Replace(pathFmt, "{"+p.name+"}", verb, 1)
+		pathArgs = append(pathArgs, goParamName(p.name))
+	}
+	if len(params) > 0 {
+		args = append(args, "req *"+reqType)
+	}
+	fmt.Fprintf(&b, "func (c *Client) %s(%s) (*%s, error) {\n", name, strings.Join(args, ", "), respType)
+	if len(params) > 0 {
+		// 参数都可选时允许传nil
+		fmt.Fprintf(&b, "\tif req == nil {\n\t\treq = &%s{}\n\t}\n", reqType)
+	}
+
+	// files不为nil时Client.do以multipart提交
+	query, form, files := "nil", "nil", "nil"
+	if multipart {
+		b.WriteString("\tfiles := map[string]*File{}\n")
+		files = "files"
+	}
+	for _, p := range params {
+		target := "query"
+		switch {
+		case p.in == "form" && p.schema.Format == "binary":
+			fmt.Fprintf(&b, "\tif req.%s != nil {\n\t\tfiles[%q] = req.%s\n\t}\n", goName(p.name), p.name, goName(p.name))
+			continue
+		case p.in == "form":
+			target = "form"
+			if form == "nil" {
+				b.WriteString("\tform := url.Values{}\n")
+				form = "form"
+			}
+		default:
+			if query == "nil" {
+				b.WriteString("\tquery := url.Values{}\n")
+				query = "query"
+			}
+		}
+		g.imports["net/url"] = true
+		field := "req." + goName(p.name)
+		switch {
+		case strings.HasPrefix(p.goType, "[]"):
+			fmt.Fprintf(&b, "\tfor _, v := range %s {\n\t\t%s.Add(%q, formatValue(v))\n\t}\n", field, target, p.name)
+		case p.required:
+			fmt.Fprintf(&b, "\t%s.Set(%q, formatValue(%s))\n", target, p.name, field)
+		default:
+			fmt.Fprintf(&b, "\tif %s != nil {\n\t\t%s.Set(%q, formatValue(*%s))\n\t}\n", field, target, p.name, field)
+		}
+	}
+
+	pathExpr := fmt.Sprintf("%q", pathFmt)
+	if len(pathArgs) > 0 {
+		g.imports["fmt"] = true
+		pathExpr = fmt.Sprintf("fmt.Sprintf(%q, %s)", pathFmt, strings.Join(pathArgs, ", "))
+	}
+	fmt.Fprintf(&b, "\tvar resp %s\n", respType)
+	fmt.Fprintf(&b, "\tif err := c.do(ctx, %q, %s, %s, %s, %s, &resp); err != nil {\n\t\treturn nil, err\n\t}\n", method, pathExpr, query, form, files)
+	b.WriteString("\treturn &resp, nil\n}\n")
+	g.decls = append(g.decls, b.String())
+}
+
+// 路径参数名转为Go参数名, 如 id -> id, post_id -> postID
+func goParamName(s string) string {
+	n := goName(s)
+	if v, ok := goInitialisms[strings.ToLower(s)]; ok && v == n {
+		return s
+	}
+	return strings.ToLower(n[:1]) + n[1:]
+}
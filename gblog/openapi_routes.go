@@ -0,0 +1,390 @@
+package main
+
+import "fmt"
+
+// 文章id等路径参数由路径自动生成, 这里只描述查询参数、表单和响应
+// 新增或修改路由时需同步修改, NewRouter会校验两者一致
+var apiRoutes = []apiRoute{
+	// 认证
+	{
+		Method: "POST", Path: "/register", ID: "Register", Tag: "auth", Summary: "注册", Public: true,
+		// PasswordEncrypt只解析multipart表单
+		Form:      formParams(User{}),
+		Multipart: true,
+		Result:    tokenPairResult,
+	},
+	{
+		Method: "POST", Path: "/login", ID: "Login", Tag: "auth", Summary: "登录", Public: true,
+		Form: []apiParam{
+			{Name: "username", Schema: str(), Required: true},
+			{Name: "password", Schema: str(), Required: true},
+		},
+		Result: withProps(tokenPairResult, map[string]*Schema{
+			"user": object(map[string]*Schema{"id": integer(), "username": str()}),
+		}),
+	},
+	{
+		Method: "POST", Path: "/refresh", ID: "Refresh", Tag: "auth", Summary: "使用refresh token换取新的token对", Public: true,
+		Form:   formParams(RefreshReq{}),
+		Result: tokenPairResult,
+	},
+	{
+		Method: "GET", Path: "/siwe/nonce", ID: "SiweNonce", Tag: "auth", Summary: "获取SIWE登录nonce", Public: true,
+		Result: map[string]*Schema{"nonce": str()},
+	},
+	{
+		Method: "POST", Path: "/siwe/verify", ID: "SiweVerify", Tag: "auth", Summary: "校验SIWE签名并登录, 钱包地址未注册时自动创建用户", Public: true,
+		Form: formParams(SiweVerifyReq{}),
+		Result: withProps(tokenPairResult, map[string]*Schema{
+			"user": object(map[string]*Schema{"id": integer(), "username": str(), "wallet_address": nullable(str())}),
+		}),
+	},
+	{
+		Method: "POST", Path: "/email/verify", ID: "VerifyEmail", Tag: "account", Summary: "使用邮件中的token验证邮箱", Public: true,
+		Form:   formParams(VerifyEmailReq{}),
+		Result: map[string]*Schema{"email": str(), "email_verified_at": nullable(dateTime())},
+	},
+	{
+		Method: "POST", Path: "/password/forgot", ID: "ForgotPassword", Tag: "account", Summary: "忘记密码, 无论邮箱是否存在都返回成功", Public: true,
+		Form:   formParams(ForgotPasswordReq{}),
+		Result: map[string]*Schema{"message": str()},
+	},
+	{
+		Method: "POST", Path: "/password/reset", ID: "ResetPassword", Tag: "account", Summary: "使用邮件中的token重置密码, 成功后需要重新登录", Public: true,
+		Form: formParams(ResetPasswordReq{}),
+	},
+	{
+		Method: "GET", Path: filesPath + "*key", ID: "GetFile", Tag: "attachment", Summary: "下载附件文件; 未发布文章的文件需要作者的token", Public: true,
+		Query: []apiParam{accessTokenParam}, Raw: "application/octet-stream",
+	},
+	{
+		Method: "POST", Path: "/auth/logout", ID: "Logout", Tag: "auth", Summary: "退出登录: 吊销当前access token, 若带上refresh token则一并作废",
+		Form: formParams(LogoutReq{}),
+	},
+
+	// 账号
+	{
+		Method: "PUT", Path: "/auth/password", ID: "ChangePassword", Tag: "account", Summary: "修改密码, 其他登录失效, 返回新的token对",
+		Form:   formParams(ChangePasswordReq{}),
+		Result: tokenPairResult,
+	},
+	{
+		Method: "POST", Path: "/auth/email/verification", ID: "ResendVerification", Tag: "account", Summary: "重新发送验证邮件",
+	},
+	{
+		Method: "POST", Path: "/auth/siwe/link", ID: "SiweLink", Tag: "account", Summary: "已登录用户绑定钱包地址",
+		Form:   formParams(SiweVerifyReq{}),
+		Result: map[string]*Schema{"wallet_address": nullable(str())},
+	},
+	{
+		Method: "GET", Path: "/auth/me", ID: "GetMe", Tag: "account", Summary: "当前用户的资料",
+		Result: map[string]*Schema{"user": ref("Account")},
+	},
+	{
+		Method: "PUT", Path: "/auth/me", ID: "UpdateMe", Tag: "account", Summary: "修改昵称、头像、简介和邮箱, 未提交的字段不修改, 提交空字符串表示清空",
+		Form:   formParams(UpdateMeReq{}),
+		Result: map[string]*Schema{"user": ref("Account")},
+	},
+	{
+		Method: "POST", Path: "/auth/me/delete", ID: "DeleteMe", Tag: "account", Summary: "注销账号, 文章和评论一并删除",
+		Form: formParams(DeleteMeReq{}),
+	},
+
+	// 搜索
+	{
+		Method: "GET", Path: "/auth/search", ID: "Search", Tag: "search", Summary: "全文搜索文章和评论",
+		Query: append([]apiParam{
+			param("q", str(), "关键字"),
+			param("type", enum("all", SearchTypePost, SearchTypeComment), "搜索范围, 默认all"),
+		}, offsetPageParams...),
+		Result: map[string]*Schema{"hits": arrayOf(ref("SearchHit")), "meta": ref("PageMeta")},
+	},
+
+	// 标签和分类
+	{
+		Method: "GET", Path: "/auth/tags", ID: "ListTags", Tag: "taxonomy", Summary: "标签云",
+		Query:  []apiParam{param("limit", integer(), "返回数量, 不提供时返回全部标签")},
+		Result: map[string]*Schema{"tags": arrayOf(ref("TagCount"))},
+	},
+	{
+		Method: "POST", Path: "/auth/tags", ID: "CreateTag", Tag: "taxonomy", Summary: "创建标签, 需要taxonomy:manage权限",
+		Form:   formParams(CreateTagReq{}),
+		Result: map[string]*Schema{"tag": ref("Tag")},
+	},
+	{
+		Method: "DELETE", Path: "/auth/tags/:tid", ID: "DeleteTag", Tag: "taxonomy", Summary: "删除标签, 同时解除与文章的关联, 需要taxonomy:manage权限",
+		Result: map[string]*Schema{"tag_id": integer()},
+	},
+	{
+		Method: "GET", Path: "/auth/categories", ID: "ListCategories", Tag: "taxonomy", Summary: "分类树",
+		Result: map[string]*Schema{"categories": arrayOf(ref("CategoryNode"))},
+	},
+	{
+		Method: "POST", Path: "/auth/categories", ID: "CreateCategory", Tag: "taxonomy", Summary: "创建分类, 需要taxonomy:manage权限",
+		Form:   categoryForm,
+		Result: map[string]*Schema{"category": ref("Category")},
+	},
+	{
+		Method: "PUT", Path: "/auth/categories/:cid", ID: "UpdateCategory", Tag: "taxonomy", Summary: "修改分类, 需要taxonomy:manage权限",
+		Form:   categoryForm,
+		Result: map[string]*Schema{"category": ref("Category")},
+	},
+	{
+		Method: "DELETE", Path: "/auth/categories/:cid", ID: "DeleteCategory", Tag: "taxonomy", Summary: "删除分类, 有子分类时不允许删除, 需要taxonomy:manage权限",
+		Result: map[string]*Schema{"category_id": integer()},
+	},
+
+	// 文章列表
+	{
+		Method: "GET", Path: "/auth/posts", ID: "ListPosts", Tag: "post", Summary: "文章列表, 未发布的文章只返回自己的",
+		Query: append([]apiParam{
+			param("author_id", integer(), "作者id"),
+			param("from", str(), "创建时间下限, RFC3339或2006-01-02格式"),
+			param("to", str(), "创建时间上限, RFC3339或2006-01-02格式, 日期格式包含当天"),
+			param("q", str(), "标题或内容包含的关键字"),
+			param("tag", str(), "标签名, 多个用逗号分隔"),
+			param("tag_match", enum("any", "all"), "多个标签的匹配方式, 默认any"),
+			param("category_id", integer(), "分类id, 包含子分类"),
+			param("status", enumOf(PostStatus("")), "文章状态"),
+			param("sort", enum("created_at", "-created_at", "updated_at", "-updated_at", "title", "-title"), "排序字段, -表示倒序, 默认-created_at"),
+		}, pageParams...),
+		Result: postListResult,
+	},
+	{
+		Method: "GET", Path: "/auth/posts/most-liked", ID: "MostLikedPosts", Tag: "post", Summary: "点赞最多的文章",
+		Query:  []apiParam{rankLimitParam},
+		Result: map[string]*Schema{"posts": arrayOf(ref("RankedPost"))},
+	},
+	{
+		Method: "GET", Path: "/auth/posts/trending", ID: "TrendingPosts", Tag: "post", Summary: "最近一段时间内的热门文章",
+		Query:  []apiParam{rankLimitParam},
+		Result: map[string]*Schema{"posts": arrayOf(ref("RankedPost"))},
+	},
+	{
+		Method: "GET", Path: "/auth/bookmarks", ID: "ListBookmarks", Tag: "post", Summary: "当前用户的收藏, 按收藏时间倒序",
+		Query:  offsetPageParams,
+		Result: postListResult,
+	},
+	{
+		Method: "GET", Path: "/auth/feed", ID: "Feed", Tag: "post", Summary: "关注的用户发布的文章",
+		Query:  pageParams,
+		Result: postListResult,
+	},
+
+	// 用户和关注
+	{
+		Method: "GET", Path: "/auth/users/:id", ID: "GetProfile", Tag: "user", Summary: "用户的公开资料",
+		Result: map[string]*Schema{"user": ref("Profile")},
+	},
+	{
+		Method: "PUT", Path: "/auth/users/:id/follow", ID: "FollowUser", Tag: "user", Summary: "关注, 重复关注不报错",
+		Result: followResult,
+	},
+	{
+		Method: "DELETE", Path: "/auth/users/:id/follow", ID: "UnfollowUser", Tag: "user", Summary: "取消关注, 未关注时不报错",
+		Result: followResult,
+	},
+	{
+		Method: "GET", Path: "/auth/users/:id/followers", ID: "ListFollowers", Tag: "user", Summary: "关注该用户的人, 按关注时间倒序",
+		Query:  offsetPageParams,
+		Result: userListResult,
+	},
+	{
+		Method: "GET", Path: "/auth/users/:id/following", ID: "ListFollowing", Tag: "user", Summary: "该用户关注的人, 按关注时间倒序",
+		Query:  offsetPageParams,
+		Result: userListResult,
+	},
+
+	// 通知
+	{
+		Method: "GET", Path: "/auth/notifications", ID: "ListNotifications", Tag: "notification", Summary: "通知列表, 按时间倒序",
+		Query: append([]apiParam{param("unread", boolean(), "为true时只返回未读通知")}, pageParams...),
+		Result: map[string]*Schema{
+			"notifications": arrayOf(ref("Notification")),
+			"unread_count":  integer(),
+			"meta":          ref("PageMeta"),
+		},
+	},
+	{
+		Method: "GET", Path: "/auth/notifications/unread-count", ID: "UnreadNotificationCount", Tag: "notification", Summary: "未读通知数",
+		Result: map[string]*Schema{"unread_count": integer()},
+	},
+	{
+		Method: "POST", Path: "/auth/notifications/read-all", ID: "MarkAllNotificationsRead", Tag: "notification", Summary: "全部标记已读",
+		Result: map[string]*Schema{"updated": integer()},
+	},
+	{
+		Method: "POST", Path: "/auth/notifications/:nid/read", ID: "MarkNotificationRead", Tag: "notification", Summary: "标记单条通知已读, 已读的通知重复标记不报错",
+		Result: map[string]*Schema{"notification_id": integer()},
+	},
+
+	// 文章
+	{
+		Method: "POST", Path: "/auth/post", ID: "CreatePost", Tag: "post", Summary: "发表文章, 需要post:create权限",
+		Form:   append(formParams(CreatePostReq{}), postForm...),
+		Result: map[string]*Schema{"post": ref("Post")},
+	},
+	{
+		Method: "PUT", Path: "/auth/post/:id", ID: "UpdatePost", Tag: "post", Summary: "修改文章, 未提交的字段不修改",
+		Form:   append(formParams(UpdatePostReq{}), postForm...),
+		Result: map[string]*Schema{"post": ref("Post")},
+	},
+	{
+		Method: "GET", Path: "/auth/post/:id", ID: "GetPost", Tag: "post", Summary: "文章详情",
+		Query:  []apiParam{param("format", enum(postFormatMarkdown, postFormatHTML), "内容格式, 默认markdown; html时返回渲染后的内容和目录")},
+		Result: map[string]*Schema{"post": ref("PostDetail")},
+	},
+	{
+		Method: "DELETE", Path: "/auth/post/:id", ID: "DeletePost", Tag: "post", Summary: "删除文章",
+		Result: map[string]*Schema{"post_id": integer()},
+	},
+	{
+		Method: "GET", Path: "/auth/post/:id/revisions", ID: "ListPostRevisions", Tag: "revision", Summary: "文章的历史版本, 按版本号倒序",
+		Query:  offsetPageParams,
+		Result: map[string]*Schema{"revisions": arrayOf(ref("RevisionSummary")), "meta": ref("PageMeta")},
+	},
+	{
+		Method: "GET", Path: "/auth/post/:id/revisions/:rev", ID: "GetPostRevision", Tag: "revision", Summary: "指定版本的内容",
+		Result: map[string]*Schema{"revision": ref("PostRevision")},
+	},
+	{
+		Method: "POST", Path: "/auth/post/:id/revisions/:rev/rollback", ID: "RollbackPost", Tag: "revision", Summary: "回滚到指定版本, 回滚本身也会生成一个新版本",
+		Result: map[string]*Schema{"post": ref("Post")},
+	},
+	{
+		Method: "GET", Path: "/auth/post/:id/diff", ID: "DiffPostRevisions", Tag: "revision", Summary: "两个版本之间的unified diff",
+		Query: []apiParam{
+			param("from", integer(), "起始版本, 默认为to的上一版本"),
+			param("to", integer(), "目标版本, 默认为最新版本"),
+		},
+		Result: map[string]*Schema{"from": integer(), "to": integer(), "diff": str()},
+	},
+	{
+		Method: "POST", Path: "/auth/post/:id/attachments", ID: "UploadAttachment", Tag: "attachment", Summary: "上传文章附件",
+		Form:      []apiParam{{Name: "file", Schema: &Schema{Type: "string", Format: "binary"}, Required: true}},
+		Multipart: true,
+		Result:    map[string]*Schema{"attachment": ref("Attachment")},
+	},
+	{
+		Method: "GET", Path: "/auth/post/:id/attachments", ID: "ListAttachments", Tag: "attachment", Summary: "文章的附件",
+		Result: map[string]*Schema{"attachments": arrayOf(ref("Attachment"))},
+	},
+	{
+		Method: "DELETE", Path: "/auth/post/:id/attachments/:aid", ID: "DeleteAttachment", Tag: "attachment", Summary: "删除附件",
+		Result: map[string]*Schema{"attachment_id": integer()},
+	},
+	{
+		Method: "PUT", Path: "/auth/post/:id/like", ID: "LikePost", Tag: "post", Summary: "点赞, 重复点赞不报错",
+		Result: likeResult,
+	},
+	{
+		Method: "DELETE", Path: "/auth/post/:id/like", ID: "UnlikePost", Tag: "post", Summary: "取消点赞",
+		Result: likeResult,
+	},
+	{
+		Method: "PUT", Path: "/auth/post/:id/bookmark", ID: "BookmarkPost", Tag: "post", Summary: "收藏, 重复收藏不报错",
+		Result: bookmarkResult,
+	},
+	{
+		Method: "DELETE", Path: "/auth/post/:id/bookmark", ID: "UnbookmarkPost", Tag: "post", Summary: "取消收藏",
+		Result: bookmarkResult,
+	},
+
+	// 评论
+	{
+		Method: "POST", Path: "/auth/post/:id/comment", ID: "CreateComment", Tag: "comment", Summary: "发表评论, parent_id不为0时为回复, 需要comment:create权限",
+		Form:   formParams(CreateCommentReq{}),
+		Result: map[string]*Schema{"comment": ref("Comment")},
+	},
+	{
+		Method: "GET", Path: "/auth/post/:id/comments", ID: "ListComments", Tag: "comment", Summary: "文章的评论列表",
+		Query:  pageParams,
+		Result: map[string]*Schema{"comments": arrayOf(ref("Comment")), "meta": ref("PageMeta")},
+	},
+	{
+		Method: "GET", Path: "/auth/post/:id/comment/tree", ID: "GetCommentTree", Tag: "comment", Summary: "评论树, 按顶层评论分页",
+		Query: pageParams,
+		Result: map[string]*Schema{
+			"comments":      arrayOf(ref("CommentNode")),
+			"comment_count": integer(),
+			"meta":          ref("PageMeta"),
+		},
+	},
+	{
+		Method: "PUT", Path: "/auth/post/:id/comment/:cid", ID: "UpdateComment", Tag: "comment", Summary: "修改评论, 只有作者本人可以修改",
+		Form:   formParams(UpdateCommentReq{}),
+		Result: map[string]*Schema{"comment": ref("Comment")},
+	},
+	{
+		Method: "DELETE", Path: "/auth/post/:id/comment/:cid", ID: "DeleteComment", Tag: "comment", Summary: "删除评论",
+		Result: map[string]*Schema{"comment_id": integer()},
+	},
+
+	// 长连接, token也可以放在access_token参数中
+	{
+		Method: "GET", Path: "/auth/notifications/stream", ID: "NotificationStream", Tag: "notification", Summary: "通知的SSE推送: 连接后先发送unread事件, 之后每条新通知发送notification事件",
+		Query: []apiParam{accessTokenParam},
+		Raw:   "text/event-stream",
+	},
+	{
+		Method: "GET", Path: "/auth/post/:id/comments/ws", ID: "CommentStream", Tag: "comment", Summary: "文章新评论的WebSocket推送, 每条新评论发送一条json消息",
+		Query: []apiParam{accessTokenParam},
+		Raw:   rawWebSocket,
+	},
+
+	// 管理
+	{
+		Method: "GET", Path: "/auth/admin/users", ID: "ListUsers", Tag: "admin", Summary: "查询用户, 支持按关键字、角色和封禁状态过滤, 需要user:manage权限",
+		Query:  append(formParams(ListUsersReq{}), offsetPageParams...),
+		Result: map[string]*Schema{"users": arrayOf(ref("Account")), "meta": ref("PageMeta")},
+	},
+	{
+		Method: "PUT", Path: "/auth/admin/users/:id/role", ID: "SetUserRole", Tag: "admin", Summary: "修改用户角色, 需要user:manage权限",
+		Form: formParams(SetRoleReq{}),
+		Result: map[string]*Schema{
+			"user": object(map[string]*Schema{"id": integer(), "username": str(), "role": enumOf(Role(""))}),
+		},
+	},
+	{
+		Method: "PUT", Path: "/auth/admin/users/:id/suspension", ID: "SuspendUser", Tag: "admin", Summary: "封禁用户, 需要user:manage权限",
+		Result: map[string]*Schema{"user": ref("Account")},
+	},
+	{
+		Method: "DELETE", Path: "/auth/admin/users/:id/suspension", ID: "UnsuspendUser", Tag: "admin", Summary: "解除封禁, 需要user:manage权限",
+		Result: map[string]*Schema{"user": ref("Account")},
+	},
+}
+
+// 发表和修改文章时CreatePostReq/UpdatePostReq之外的表单字段
+var postForm = []apiParam{
+	param("category_id", integer(), "分类id, 0表示清空"),
+	param("publish_at", dateTime(), "定时发布时间, RFC3339格式, 只用于scheduled状态"),
+	param("tags", str(), "标签名, 多个用逗号分隔; 提交空字符串表示清空"),
+}
+
+var categoryForm = append(formParams(CategoryReq{}), param("parent_id", integer(), "上级分类id, 0表示顶级分类"))
+
+var rankLimitParam = param("limit", integer(), fmt.Sprintf("返回数量, 默认%d, 最大%d", defaultRankLimit, maxRankLimit))
+
+var accessTokenParam = param("access_token", str(), "access token, 无法设置Authorization头时使用")
+
+var postListResult = map[string]*Schema{"posts": arrayOf(ref("Post")), "meta": ref("PageMeta")}
+
+var userListResult = map[string]*Schema{"users": arrayOf(ref("UserSummary")), "meta": ref("PageMeta")}
+
+var followResult = map[string]*Schema{"user_id": integer(), "following": boolean()}
+
+var likeResult = map[string]*Schema{"post_id": integer(), "liked": boolean(), "like_count": integer()}
+
+var bookmarkResult = map[string]*Schema{"post_id": integer(), "bookmarked": boolean()}
+
+// 合并多组响应字段
+func withProps(props ...map[string]*Schema) map[string]*Schema {
+	merged := map[string]*Schema{}
+	for _, p := range props {
+		for k, v := range p {
+			merged[k] = v
+		}
+	}
+	return merged
+}
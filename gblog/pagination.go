@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var ErrInvalidCursor = newAppError(KindInvalid, "invalid_cursor", "cursor is not valid")
+
+// 分页参数: 提供After时使用游标分页, 否则使用Offset
+type Page struct {
+	Limit  int
+	Offset int
+	After  *Cursor
+}
+
+// 游标: 上一页最后一条记录的排序字段值和id
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// 分页响应元数据
+type PageMeta struct {
+	Total      int64  `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	PageSize   int    `json:"page_size"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// 解析 page、page_size、cursor 查询参数
+func parsePage(c *gin.Context) (Page, int, error) {
+	size := defaultPageSize
+	if v := c.Query("page_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return Page{}, 0, invalidParam("page_size", "page_size must be a positive integer")
+		}
+		size = min(n, maxPageSize)
+	}
+
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := DecodeCursor(v)
+		if err != nil {
+			return Page{}, 0, err
+		}
+		return Page{Limit: size, After: cursor}, 0, nil
+	}
+
+	page := 1
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return Page{}, 0, invalidParam("page", "page must be a positive integer")
+		}
+		page = n
+	}
+	return Page{Limit: size, Offset: (page - 1) * size}, page, nil
+}
+
+// 仓库多查询一条用于判断是否还有下一页, 这里截掉多余的一条
+func trimPage[T any](items []T, limit int) ([]T, bool) {
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
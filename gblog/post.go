@@ -1,7 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -14,94 +18,134 @@ type Post struct {
 	Content string
 	UserID  uint
 	User    User
+
+	CategoryID *uint `gorm:"index"`
+	Tags       []Tag `gorm:"many2many:post_tags"`
+
+	Status      PostStatus `gorm:"size:16;default:published;index"`
+	PublishAt   *time.Time `gorm:"index"` // 定时发布时间, 仅scheduled状态有效
+	PublishedAt *time.Time // 首次发布时间
+
+	// Content为Markdown源文, 保存时渲染为过滤后的html和目录; 与Content相同不限长度, MySQL中为longtext
+	ContentHTML string
+	TOC         []*TOCItem `gorm:"serializer:json"`
+
+	ViewCount    int64 `gorm:"not null;default:0"` // 浏览数, 由后台任务批量累加
+	CommentCount int64 `gorm:"-"`                  // 未删除的评论数, 由service填充
+	LikeCount    int64 `gorm:"-"`                  // 点赞数, 由service填充
+}
+
+// 文章详情返回的正文格式
+const (
+	postFormatMarkdown = "markdown" // Markdown源文
+	postFormatHTML     = "html"     // 渲染并过滤后的html, 附带目录
+)
+
+type PostStatus string
+
+const (
+	PostDraft     PostStatus = "draft"     // 草稿, 仅作者可见
+	PostScheduled PostStatus = "scheduled" // 到达PublishAt后自动发布
+	PostPublished PostStatus = "published"
+	PostArchived  PostStatus = "archived" // 归档, 仅作者可见
+)
+
+func (s PostStatus) Valid() bool {
+	switch s {
+	case PostDraft, PostScheduled, PostPublished, PostArchived:
+		return true
+	}
+	return false
+}
+
+// 已发布的文章所有人可见, 其他状态仅作者可见
+func (p *Post) VisibleTo(userID uint) bool {
+	return p.Status == PostPublished || p.UserID == userID
 }
 
 type CreatePostReq struct {
-	Title   string `form:"title" binding:"required,min=1,max=100"`
-	Content string `form:"content" binding:"required,min=1"`
+	Title   string     `form:"title" binding:"required,min=1,max=100"`
+	Content string     `form:"content" binding:"required,min=1"`
+	Status  PostStatus `form:"status"`
 }
 
 func getCurrentUserID(c *gin.Context) (uint, bool) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "can't get user"})
+		writeProblem(c, ErrUnauthenticated)
 		return 0, false
 	}
 	uid, ok := userID.(uint)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "userID error"})
+		respondError(c, "get user id", fmt.Errorf("unexpected user id type %T", userID))
 		return 0, false
 	}
 	return uid, true
 }
 
-func getPostAndCheckOwner(c *gin.Context, postID string, userID uint) (*Post, bool) {
-	var post Post
-	if err := db.Where("id = ?", postID).First(&post).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "can't get post"})
-		return nil, false
-	}
-	if post.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "post is not belongs to the user"})
-		return nil, false
-	}
-	return &post, true
-}
-
-func validatePostID(c *gin.Context) (string, bool) {
+// 解析路径中的文章id
+func validatePostID(c *gin.Context) (uint, bool) {
 	postID := c.Param("id")
 	if postID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "post id is null"})
-		return "", false
+		writeProblem(c, invalidParam("id", "post id is null"))
+		return 0, false
 	}
-	return postID, true
+	pid, err := strconv.ParseUint(postID, 10, 64)
+	if err != nil {
+		writeProblem(c, invalidParam("id", "post_id format is not correct"))
+		return 0, false
+	}
+	return uint(pid), true
 }
 
-func CreatePostHandler(c *gin.Context) {
+func (h *Handler) CreatePostHandler(c *gin.Context) {
 	var req CreatePostReq
 	if err := c.ShouldBind(&req); err != nil {
-		zap.L().Error("CreatePost failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, "CreatePost", bindError(err))
 		return
 	}
 
 	uid, ok := getCurrentUserID(c)
 	if !ok {
-		zap.L().Error("CreatePost failed", zap.String("error", "can't get user id"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
+		requestLogger(c).Error("CreatePost failed", zap.String("error", "can't get user id"))
 		return
 	}
 
-	post := Post{
-		Title:   req.Title,
-		Content: req.Content,
-		UserID:  uid,
+	categoryID, ok := optionalFormID(c, "category_id")
+	if !ok {
+		return
 	}
-
-	if err := db.Create(&post).Error; err != nil {
-		zap.L().Error("CreatePost failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "create post failed"})
+	publishAt, ok := optionalPublishAt(c)
+	if !ok {
+		return
+	}
+	post, err := h.posts.Create(c.Request.Context(), uid, PostInput{
+		Title:      req.Title,
+		Content:    req.Content,
+		CategoryID: categoryID,
+		Tags:       optionalTags(c),
+		Status:     req.Status,
+		PublishAt:  publishAt,
+	})
+	if err != nil {
+		respondError(c, "CreatePost", err)
 		return
 	}
 
-	zap.L().Info("CreatePost successfully", zap.Uint("post_id", post.ID), zap.Uint("user_id", uid))
+	requestLogger(c).Info("CreatePost successfully", zap.Uint("post_id", post.ID))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"post": gin.H{
-			"id":      post.ID,
-			"title":   post.Title,
-			"content": post.Content,
-			"user_id": post.UserID,
-			"created": post.CreatedAt,
-		},
+		"post":    postJSON(post),
 	})
 }
 
 type UpdatePostReq struct {
-	Title   string `form:"title"`
-	Content string `form:"content"`
+	Title   string     `form:"title"`
+	Content string     `form:"content"`
+	Status  PostStatus `form:"status"`
 }
 
-func UpdatePostHandler(c *gin.Context) {
+func (h *Handler) UpdatePostHandler(c *gin.Context) {
 	postID, ok := validatePostID(c)
 	if !ok {
 		return
@@ -109,99 +153,270 @@ func UpdatePostHandler(c *gin.Context) {
 
 	var req UpdatePostReq
 	if err := c.ShouldBind(&req); err != nil {
-		zap.L().Error("UpdatePost failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, "UpdatePost", bindError(err))
 		return
 	}
 
-	uid, ok := getCurrentUserID(c)
+	actor, ok := getCurrentActor(c)
 	if !ok {
-		zap.L().Error("UpdatePost failed", zap.String("error", "can't get user id"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
+		requestLogger(c).Error("UpdatePost failed", zap.String("error", "can't get user id"))
 		return
 	}
-	post, ok := getPostAndCheckOwner(c, postID, uid)
+	categoryID, ok := optionalFormID(c, "category_id")
 	if !ok {
 		return
 	}
-
-	updateData := make(map[string]interface{})
-	if req.Title != "" {
-		updateData["Title"] = req.Title
-	}
-	if req.Content != "" {
-		updateData["Content"] = req.Title
+	publishAt, ok := optionalPublishAt(c)
+	if !ok {
+		return
 	}
-	if err := db.Model(&post).Updates(updateData).Error; err != nil {
-		zap.L().Error("UpdatePost failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	post, err := h.posts.Update(c.Request.Context(), actor, postID, PostInput{
+		Title:      req.Title,
+		Content:    req.Content,
+		CategoryID: categoryID,
+		Tags:       optionalTags(c),
+		Status:     req.Status,
+		PublishAt:  publishAt,
+	})
+	if err != nil {
+		respondError(c, "UpdatePost", err)
 		return
 	}
 
-	zap.L().Info("UpdatePost successfully", zap.Uint("post_id", post.ID), zap.Uint("user_id", uid))
+	requestLogger(c).Info("UpdatePost successfully", zap.Uint("post_id", post.ID))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"post": gin.H{
-			"id":      post.ID,
-			"title":   post.Title,
-			"content": post.Content,
-			"updated": post.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
+		"post":    postJSON(post),
 	})
 }
 
-func GetPostHandler(c *gin.Context) {
-	postID, ok := validatePostID(c)
+// 解析时间参数, 支持RFC3339和日期格式; endOfDay为true时日期取当天最后时刻
+func parseTimeParam(v string, endOfDay bool) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// 解析定时发布时间, 只接受RFC3339格式
+func optionalPublishAt(c *gin.Context) (*time.Time, bool) {
+	v := c.PostForm("publish_at")
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		writeProblem(c, invalidParam("publish_at", "publish_at must be RFC3339 time"))
+		return nil, false
+	}
+	return &t, true
+}
+
+// 解析文章列表的过滤和排序参数
+func parsePostQuery(c *gin.Context) (PostQuery, int, error) {
+	var q PostQuery
+	if v := c.Query("author_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return q, 0, invalidParam("author_id", "author_id format is not correct")
+		}
+		q.AuthorID = uint(id)
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := parseTimeParam(v, false)
+		if err != nil {
+			return q, 0, invalidParam("from", "from format is not correct")
+		}
+		q.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parseTimeParam(v, true)
+		if err != nil {
+			return q, 0, invalidParam("to", "to format is not correct")
+		}
+		q.To = t
+	}
+	q.Keyword = strings.TrimSpace(c.Query("q"))
+	if v := c.Query("tag"); v != "" {
+		for _, name := range splitList(v) {
+			q.Tags = append(q.Tags, slugify(name))
+		}
+	}
+	switch c.DefaultQuery("tag_match", "any") {
+	case "any":
+	case "all":
+		q.AllTags = true
+	default:
+		return q, 0, invalidParam("tag_match", "tag_match must be any or all")
+	}
+	if v := c.Query("category_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return q, 0, invalidParam("category_id", "category_id format is not correct")
+		}
+		q.CategoryID = uint(id)
+	}
+
+	if v := c.Query("status"); v != "" {
+		q.Status = PostStatus(v)
+		if !q.Status.Valid() {
+			return q, 0, ErrInvalidPostStatus
+		}
+	}
+
+	sort, ok := ParsePostSort(c.Query("sort"))
+	if !ok {
+		return q, 0, invalidParam("sort", "sort must be one of created_at, updated_at, title, optionally prefixed with -")
+	}
+	q.Sort = sort
+
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		return q, 0, err
+	}
+	q.Page = page
+	return q, pageNum, nil
+}
+
+// 文章列表, 支持按作者、时间范围、关键字过滤, 支持页码和游标分页; 未发布的文章只返回自己的
+func (h *Handler) ListPostsHandler(c *gin.Context) {
+	q, pageNum, err := parsePostQuery(c)
+	if err != nil {
+		respondError(c, "ListPosts", err)
+		return
+	}
+	uid, ok := getCurrentUserID(c)
 	if !ok {
-		zap.L().Error("GetPost failed", zap.String("error", "valid postID failed"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
 		return
 	}
+	q.ViewerID = uid
 
-	var post Post
-	if err := db.Where("id = ?", postID).First(&post).Error; err != nil {
-		zap.L().Error("GetPost failed", zap.String("error", "can't get post"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusNotFound, gin.H{"error": "can't get post"})
+	posts, meta, err := h.posts.List(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, "ListPosts", err)
 		return
 	}
+	meta.Page = pageNum
 
-	zap.L().Info("GetPost successfully", zap.Uint("post_id", post.ID))
+	items := make([]gin.H, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, postJSON(&post))
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"post": gin.H{
-			"id":      post.ID,
-			"title":   post.Title,
-			"content": post.Content,
-			"created": post.CreatedAt.Format("2006-01-02 15:04:05"),
-			"updated": post.UpdatedAt.Format("2006-01-02 15:04:05"),
-		},
+		"posts":   items,
+		"meta":    meta,
 	})
 }
 
-func DeletePostHandler(c *gin.Context) {
+// 提交了tags字段时返回标签名列表, 否则返回nil表示不修改
+func optionalTags(c *gin.Context) []string {
+	v, ok := c.GetPostForm("tags")
+	if !ok {
+		return nil
+	}
+	return parseTagsParam(v)
+}
+
+func postTags(post *Post) []Tag {
+	if post.Tags == nil {
+		return []Tag{}
+	}
+	return post.Tags
+}
+
+func postJSON(post *Post) gin.H {
+	return gin.H{
+		"id":            post.ID,
+		"title":         post.Title,
+		"content":       post.Content,
+		"user_id":       post.UserID,
+		"category_id":   post.CategoryID,
+		"tags":          postTags(post),
+		"status":        post.Status,
+		"publish_at":    post.PublishAt,
+		"published_at":  post.PublishedAt,
+		"comment_count": post.CommentCount,
+		"like_count":    post.LikeCount,
+		"view_count":    post.ViewCount,
+		"created":       post.CreatedAt.Format("2006-01-02 15:04:05"),
+		"updated":       post.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+func (h *Handler) GetPostHandler(c *gin.Context) {
 	postID, ok := validatePostID(c)
 	if !ok {
-		zap.L().Error("DelPost failed", zap.String("error", "validatePostID failed"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
+		requestLogger(c).Error("GetPost failed", zap.String("error", "valid postID failed"))
 		return
 	}
 
 	uid, ok := getCurrentUserID(c)
 	if !ok {
-		zap.L().Error("DelPost failed", zap.String("error", "getCurrentUserID failed"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
 		return
 	}
 
-	post, ok := getPostAndCheckOwner(c, postID, uid)
+	format := c.DefaultQuery("format", postFormatMarkdown)
+	if format != postFormatMarkdown && format != postFormatHTML {
+		writeProblem(c, invalidParam("format", "format must be markdown or html"))
+		return
+	}
+
+	post, err := h.posts.Get(c.Request.Context(), uid, postID)
+	if err != nil {
+		respondError(c, "GetPost", err)
+		return
+	}
+
+	h.engagement.RecordView(post)
+	liked, bookmarked, err := h.engagement.Status(c.Request.Context(), uid, post.ID)
+	if err != nil {
+		respondError(c, "GetPost", err)
+		return
+	}
+
+	resp := postJSON(post)
+	resp["format"] = format
+	resp["liked"] = liked
+	resp["bookmarked"] = bookmarked
+	if format == postFormatHTML {
+		resp["content"] = post.ContentHTML
+		resp["toc"] = post.TOC
+	}
+	requestLogger(c).Info("GetPost successfully", zap.Uint("post_id", post.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"post":    resp,
+	})
+}
+
+func (h *Handler) DeletePostHandler(c *gin.Context) {
+	postID, ok := validatePostID(c)
+	if !ok {
+		requestLogger(c).Error("DelPost failed", zap.String("error", "validatePostID failed"))
+		return
+	}
+
+	actor, ok := getCurrentActor(c)
 	if !ok {
-		zap.L().Error("DelPost failed", zap.String("error", "getPostAndCheckOwner failed"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
+		requestLogger(c).Error("DelPost failed", zap.String("error", "getCurrentActor failed"))
 		return
 	}
 
-	if err := db.Delete(&post).Error; err != nil {
-		zap.L().Error("DelPost failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	post, err := h.posts.Delete(c.Request.Context(), actor, postID)
+	if err != nil {
+		respondError(c, "DelPost", err)
 		return
 	}
 
-	zap.L().Info("DelPost successfully", zap.Uint("post_id", post.ID))
+	requestLogger(c).Info("DelPost successfully", zap.Uint("post_id", post.ID))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"post_id": post.ID,
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// 错误响应的Content-Type, 见RFC 7807
+const problemContentType = "application/problem+json"
+
+// problem的type前缀, 后接错误码
+const problemTypePrefix = "urn:gblog:problem:"
+
+// RFC 7807错误响应, code、request_id、errors为扩展字段
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// 错误类别对应的http状态码
+var kindStatus = map[ErrorKind]int{
+	KindInternal:         http.StatusInternalServerError,
+	KindInvalid:          http.StatusBadRequest,
+	KindUnauthorized:     http.StatusUnauthorized,
+	KindForbidden:        http.StatusForbidden,
+	KindNotFound:         http.StatusNotFound,
+	KindConflict:         http.StatusConflict,
+	KindTooLarge:         http.StatusRequestEntityTooLarge,
+	KindUnsupportedMedia: http.StatusUnsupportedMediaType,
+	KindTooManyRequests:  http.StatusTooManyRequests,
+}
+
+// 转为返回给客户端的应用错误, 非AppError一律视为服务端错误
+func asAppError(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal
+}
+
+// 返回problem+json错误响应并中止后续处理, 服务端错误只返回通用说明
+func writeProblem(c *gin.Context, err error) {
+	appErr := asAppError(err)
+	status := kindStatus[appErr.Kind]
+	c.Header("Content-Type", problemContentType)
+	c.AbortWithStatusJSON(status, Problem{
+		Type:      problemTypePrefix + appErr.Code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    appErr.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      appErr.Code,
+		RequestID: c.Writer.Header().Get(requestIDHeader),
+		Errors:    appErr.Fields,
+	})
+}
+
+// 记录错误日志并返回错误响应; 客户端错误记为warn, 服务端错误记为error
+func respondError(c *gin.Context, op string, err error) {
+	if appErr := asAppError(err); appErr.Kind != KindInternal {
+		requestLogger(c).Warn(op+" failed", zap.String("code", appErr.Code), zap.Error(err))
+	} else {
+		requestLogger(c).Error(op+" failed", zap.Error(err))
+	}
+	writeProblem(c, err)
+}
+
+// 把ShouldBind返回的错误转为参数错误, 校验失败时列出每个字段
+func bindError(err error) *AppError {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		fields := make([]FieldError, 0, len(verrs))
+		for _, fe := range verrs {
+			fields = append(fields, FieldError{Field: fe.Field(), Rule: fe.Tag(), Message: fieldErrorMessage(fe)})
+		}
+		appErr := ErrValidation.Wrap(err)
+		appErr.Fields = fields
+		return appErr
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		appErr := ErrValidation.Wrap(err)
+		appErr.Fields = []FieldError{{Field: typeErr.Field, Rule: "type", Message: typeErr.Field + " must be " + typeErr.Type.Kind().String()}}
+		return appErr
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return ErrInvalidRequest.Withf("body is not valid json").Wrap(err)
+	}
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) {
+		return ErrInvalidRequest.Withf("%q is not a valid number", numErr.Num).Wrap(err)
+	}
+	return ErrInvalidRequest.Wrap(err)
+}
+
+// 校验规则对应的说明; 或规则(如 eq=|http_url)按最后一个规则说明
+func fieldErrorMessage(fe validator.FieldError) string {
+	tag := fe.Tag()
+	if i := strings.LastIndex(tag, "|"); i >= 0 {
+		tag, _, _ = strings.Cut(tag[i+1:], "=")
+	}
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch tag {
+	case "required":
+		return fe.Field() + " is required"
+	case "email":
+		return fe.Field() + " must be a valid email address"
+	case "url", "http_url":
+		return fe.Field() + " must be a valid url"
+	case "min":
+		return fmt.Sprintf("%s must be at least %s%s", fe.Field(), fe.Param(), unit)
+	case "max":
+		return fmt.Sprintf("%s must be at most %s%s", fe.Field(), fe.Param(), unit)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s", fe.Field(), fe.Param())
+	default:
+		return fe.Field() + " is not valid"
+	}
+}
+
+// 校验错误中的字段名使用json或form标签中的名称, 与请求参数一致
+func registerValidation() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, key := range []string{"json", "form"} {
+			if name, _, _ := strings.Cut(f.Tag.Get(key), ","); name != "" && name != "-" {
+				return name
+			}
+		}
+		return f.Name
+	})
+}
+
+// panic恢复中间件: 记录堆栈并返回500, 不把panic信息返回给客户端
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// 由net/http处理, 用于中止响应
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			// 客户端已断开, 无法再写入响应
+			if err, ok := rec.(error); ok && (errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)) {
+				requestLogger(c).Warn("connection closed", zap.Error(err))
+				c.Abort()
+				return
+			}
+			requestLogger(c).Error("panic recovered", zap.Any("panic", rec), zap.Stack("stack"))
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			writeProblem(c, ErrInternal)
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 推送方式, 见RealtimeConfig.Broker
+const (
+	brokerMemory = "memory"
+	brokerRedis  = "redis"
+)
+
+// 订阅者的缓冲消息数, 缓冲满时丢弃新消息, 客户端可通过查询接口补齐
+const brokerBufferSize = 16
+
+// 消息发布订阅, 用于向SSE、WebSocket等长连接推送事件
+type Broker interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// 订阅主题, ctx取消后退订并关闭channel
+	Subscribe(ctx context.Context, topic string) (<-chan []byte, error)
+}
+
+// 根据配置创建发布订阅, redis方式需传入已连接的client
+func NewBroker(ctx context.Context, cfg RealtimeConfig, client *redis.Client) (Broker, error) {
+	if cfg.Broker == brokerRedis {
+		return NewRedisBroker(ctx, client)
+	}
+	return NewMemoryBroker(), nil
+}
+
+// 进程内的发布订阅, 只能推送给同一进程的订阅者
+type MemoryBroker struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan []byte]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subs: make(map[string]map[chan []byte]struct{})}
+}
+
+// 不阻塞发布者, 处理不过来的订阅者丢弃该消息
+func (b *MemoryBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	ch := make(chan []byte, brokerBufferSize)
+	b.mu.Lock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan []byte]struct{})
+	}
+	b.subs[topic][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs[topic], ch)
+		if len(b.subs[topic]) == 0 {
+			delete(b.subs, topic)
+		}
+		b.mu.Unlock()
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Redis键和频道的前缀, 避免与共用Redis的其他应用冲突
+const redisKeyPrefix = "gblog:"
+
+// 基于Redis pub/sub的发布订阅, 消息发往所有实例;
+// 每个实例只用一个连接订阅全部频道, 再分发给本进程的订阅者
+type RedisBroker struct {
+	client *redis.Client
+	local  *MemoryBroker
+}
+
+// 创建并开始订阅, ctx取消后停止
+func NewRedisBroker(ctx context.Context, client *redis.Client) (*RedisBroker, error) {
+	sub := client.PSubscribe(ctx, redisKeyPrefix+"*")
+	// 等待订阅确认, 之后发布的消息不会漏掉
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	b := &RedisBroker{client: client, local: NewMemoryBroker()}
+	go b.dispatch(ctx, sub)
+	return b, nil
+}
+
+// 转发Redis消息给本进程的订阅者, 连接断开时由go-redis自动重连并重新订阅
+func (b *RedisBroker) dispatch(ctx context.Context, sub *redis.PubSub) {
+	defer sub.Close()
+	msgs := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				zap.L().Error("redis broker subscription closed")
+				return
+			}
+			topic := strings.TrimPrefix(msg.Channel, redisKeyPrefix)
+			b.local.Publish(ctx, topic, []byte(msg.Payload))
+		}
+	}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	return b.client.Publish(ctx, redisKeyPrefix+topic, payload).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	return b.local.Subscribe(ctx, topic)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 限流状态的存储方式, 见RateLimitConfig.Store
+const (
+	rateLimitStoreMemory = "memory"
+	rateLimitStoreRedis  = "redis"
+)
+
+// 限流和登录失败计数的存储, 多实例部署时使用Redis共享
+type RateLimitStore interface {
+	// 从key的令牌桶中取一个令牌, 令牌不足时返回需要等待的时间, 否则返回0
+	Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error)
+	// 计数加一并返回新值, 计数在最后一次增加ttl后清零
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// 锁定key一段时间
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// 剩余的锁定时间, 未锁定时返回0
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	// 删除计数和锁定
+	Reset(ctx context.Context, key string) error
+}
+
+// 根据配置创建限流存储, redis方式需传入已连接的client
+func NewRateLimitStore(cfg RateLimitConfig, client *redis.Client) RateLimitStore {
+	if cfg.Store == rateLimitStoreRedis {
+		return NewRedisRateLimitStore(client)
+	}
+	return NewMemoryRateLimitStore()
+}
+
+// 每毫秒补充的令牌数
+func (l RateLimit) perMilli() float64 {
+	return float64(l.Rate) / float64(time.Minute/time.Millisecond)
+}
+
+// 按key限流的中间件, key为空时不限流; 存储出错时放行, 避免限流故障导致服务不可用
+func RateLimitMiddleware(store RateLimitStore, scope string, limit RateLimit, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+		wait, err := store.Take(c.Request.Context(), "ratelimit:"+scope+":"+k, limit)
+		if err != nil {
+			requestLogger(c).Error("rate limit failed", zap.String("scope", scope), zap.Error(err))
+			c.Next()
+			return
+		}
+		if wait > 0 {
+			requestLogger(c).Warn("rate limited", zap.String("scope", scope), zap.String("key", k))
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeProblem(c, ErrRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
+
+// 按客户端IP限流, 代理后部署时需配置server.trusted_proxies
+func clientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// 按表单中的用户名限流
+func formUsernameKey(c *gin.Context) string {
+	return c.PostForm("username")
+}
+
+// 进程内的限流存储
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	counters  map[string]*expiringCount
+	locks     map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // 桶满的时间, 之后可以删除
+}
+
+type expiringCount struct {
+	n       int64
+	expires time.Time
+}
+
+// 清理过期状态的间隔
+const rateLimitSweepInterval = time.Minute
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:  make(map[string]*tokenBucket),
+		counters: make(map[string]*expiringCount),
+		locks:    make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	elapsed := float64(now.Sub(b.last)) / float64(time.Millisecond)
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.perMilli())
+	b.last = now
+	var wait time.Duration
+	if b.tokens >= 1 {
+		b.tokens--
+	} else {
+		wait = time.Duration(math.Ceil((1-b.tokens)/limit.perMilli())) * time.Millisecond
+	}
+	b.full = now.Add(time.Duration((float64(limit.Burst) - b.tokens) / limit.perMilli() * float64(time.Millisecond)))
+	return wait, nil
+}
+
+func (s *MemoryRateLimitStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expires) {
+		c = &expiringCount{}
+		s.counters[key] = c
+	}
+	c.n++
+	c.expires = now.Add(ttl)
+	return c.n, nil
+}
+
+func (s *MemoryRateLimitStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	s.mu.Lock()
+	s.locks[key] = s.now().Add(d)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryRateLimitStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until, ok := s.locks[key]; ok {
+		if d := until.Sub(s.now()); d > 0 {
+			return d, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *MemoryRateLimitStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.counters, key)
+	delete(s.locks, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// 定期删除已满的令牌桶和过期的计数、锁定, 调用方需持有锁
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for k, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, k)
+		}
+	}
+	for k, c := range s.counters {
+		if !now.Before(c.expires) {
+			delete(s.counters, k)
+		}
+	}
+	for k, until := range s.locks {
+		if !now.Before(until) {
+			delete(s.locks, k)
+		}
+	}
+}
+
+// 令牌桶脚本, 保证多实例并发取令牌时的原子性; 返回需等待的毫秒数
+var takeTokenScript = redis.NewScript(`
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1]) or burst
+local ts = tonumber(b[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1)
+return wait
+`)
+
+// 计数脚本, 每次增加都重新设置过期时间
+var incrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return n
+`)
+
+// 基于Redis的限流存储, 多个实例共享限流状态
+type RedisRateLimitStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+func NewRedisRateLimitStore(client *redis.Client) *RedisRateLimitStore {
+	return &RedisRateLimitStore{client: client, now: time.Now}
+}
+
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error) {
+	ms, err := takeTokenScript.Run(ctx, s.client, []string{redisKeyPrefix + key}, limit.Burst, limit.perMilli(), s.now().UnixMilli()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func (s *RedisRateLimitStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(ctx, s.client, []string{redisKeyPrefix + key}, ttl.Milliseconds()).Int64()
+}
+
+func (s *RedisRateLimitStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	return s.client.Set(ctx, redisKeyPrefix+key+":lock", 1, d).Err()
+}
+
+func (s *RedisRateLimitStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	d, err := s.client.PTTL(ctx, redisKeyPrefix+key+":lock").Result()
+	if err != nil {
+		return 0, err
+	}
+	// 不存在时为负数
+	return max(d, 0), nil
+}
+
+func (s *RedisRateLimitStore) Reset(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKeyPrefix+key, redisKeyPrefix+key+":lock").Err()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 当前时间由now控制的内存限流存储
+func newTestRateLimitStore(now *time.Time) *MemoryRateLimitStore {
+	s := NewMemoryRateLimitStore()
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+// 所有操作都返回错误的存储, 模拟Redis不可用
+type failingRateLimitStore struct{}
+
+var errStoreDown = errors.New("store is down")
+
+func (failingRateLimitStore) Take(context.Context, string, RateLimit) (time.Duration, error) {
+	return 0, errStoreDown
+}
+func (failingRateLimitStore) Incr(context.Context, string, time.Duration) (int64, error) {
+	return 0, errStoreDown
+}
+func (failingRateLimitStore) Lock(context.Context, string, time.Duration) error { return errStoreDown }
+func (failingRateLimitStore) LockedFor(context.Context, string) (time.Duration, error) {
+	return 0, errStoreDown
+}
+func (failingRateLimitStore) Reset(context.Context, string) error { return errStoreDown }
+
+func TestMemoryRateLimitStoreTake(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestRateLimitStore(&now)
+	// 每秒补充一个令牌
+	limit := RateLimit{Rate: 60, Burst: 3}
+	take := func(key string, want time.Duration) {
+		t.Helper()
+		wait, err := s.Take(ctx, key, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wait != want {
+			t.Fatalf("Take(%s) at %s = %s, want %s", key, now.Format(time.TimeOnly), wait, want)
+		}
+	}
+
+	for range limit.Burst {
+		take("a", 0)
+	}
+	take("a", time.Second)
+	// 其他key不受影响
+	take("b", 0)
+
+	now = now.Add(500 * time.Millisecond)
+	take("a", 500*time.Millisecond)
+	now = now.Add(500 * time.Millisecond)
+	take("a", 0)
+	take("a", time.Second)
+
+	// 长时间不使用, 令牌数不超过burst
+	now = now.Add(time.Hour)
+	for range limit.Burst {
+		take("a", 0)
+	}
+	take("a", time.Second)
+}
+
+func TestMemoryRateLimitStoreCounterAndLock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestRateLimitStore(&now)
+
+	for want := int64(1); want <= 3; want++ {
+		// 每次增加都重新计算过期时间
+		now = now.Add(50 * time.Second)
+		if n, _ := s.Incr(ctx, "k", time.Minute); n != want {
+			t.Fatalf("Incr = %d, want %d", n, want)
+		}
+	}
+	now = now.Add(time.Minute)
+	if n, _ := s.Incr(ctx, "k", time.Minute); n != 1 {
+		t.Fatalf("Incr after ttl = %d, want 1", n)
+	}
+
+	if err := s.Lock(ctx, "k", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(20 * time.Second)
+	if d, _ := s.LockedFor(ctx, "k"); d != 40*time.Second {
+		t.Fatalf("LockedFor = %s, want 40s", d)
+	}
+	now = now.Add(40 * time.Second)
+	if d, _ := s.LockedFor(ctx, "k"); d != 0 {
+		t.Fatalf("LockedFor after expiry = %s, want 0", d)
+	}
+
+	if err := s.Lock(ctx, "k", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Reset(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := s.LockedFor(ctx, "k"); d != 0 {
+		t.Fatalf("LockedFor after reset = %s, want 0", d)
+	}
+	if n, _ := s.Incr(ctx, "k", time.Minute); n != 1 {
+		t.Fatalf("Incr after reset = %d, want 1", n)
+	}
+}
+
+func newRateLimitedEngine(store RateLimitStore, limit RateLimit) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", RateLimitMiddleware(store, "test", limit, clientIPKey), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	r := newRateLimitedEngine(NewMemoryRateLimitStore(), RateLimit{Rate: 1, Burst: 1})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("first request = %d, want 204", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("second request = %d, Retry-After %q, want 429 and 60", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+// 存储出错时放行, 限流故障不影响服务
+func TestRateLimitMiddlewareFailsOpen(t *testing.T) {
+	r := newRateLimitedEngine(failingRateLimitStore{}, RateLimit{Rate: 1, Burst: 1})
+	for range 3 {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("request with failing store = %d, want 204", w.Code)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type Role string
+
+const (
+	RoleAdmin     Role = "admin"     // 管理用户, 拥有全部权限
+	RoleModerator Role = "moderator" // 可删除任意文章和评论, 管理标签和分类
+	RoleAuthor    Role = "author"    // 可发表文章, 管理自己的文章和评论
+	RoleReader    Role = "reader"    // 只能阅读和评论
+)
+
+type Permission string
+
+const (
+	PermPostCreate       Permission = "post:create"
+	PermPostUpdateOwn    Permission = "post:update:own"
+	PermPostUpdateAny    Permission = "post:update:any"
+	PermPostDeleteOwn    Permission = "post:delete:own"
+	PermPostDeleteAny    Permission = "post:delete:any"
+	PermCommentCreate    Permission = "comment:create"
+	PermCommentUpdateOwn Permission = "comment:update:own"
+	PermCommentDeleteOwn Permission = "comment:delete:own"
+	PermCommentDeleteAny Permission = "comment:delete:any"
+	PermTaxonomyManage   Permission = "taxonomy:manage"
+	PermUserManage       Permission = "user:manage"
+)
+
+var ErrPermissionDenied = newAppError(KindForbidden, "permission_denied", "permission denied")
+
+// 角色拥有的权限
+var rolePermissions = map[Role][]Permission{
+	RoleReader: {
+		PermCommentCreate, PermCommentUpdateOwn, PermCommentDeleteOwn,
+	},
+	RoleAuthor: {
+		PermCommentCreate, PermCommentUpdateOwn, PermCommentDeleteOwn,
+		PermPostCreate, PermPostUpdateOwn, PermPostDeleteOwn,
+	},
+	RoleModerator: {
+		PermCommentCreate, PermCommentUpdateOwn, PermCommentDeleteOwn, PermCommentDeleteAny,
+		PermPostCreate, PermPostUpdateOwn, PermPostDeleteOwn, PermPostDeleteAny,
+		PermTaxonomyManage,
+	},
+	RoleAdmin: {
+		PermCommentCreate, PermCommentUpdateOwn, PermCommentDeleteOwn, PermCommentDeleteAny,
+		PermPostCreate, PermPostUpdateOwn, PermPostUpdateAny, PermPostDeleteOwn, PermPostDeleteAny,
+		PermTaxonomyManage, PermUserManage,
+	},
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, perm := range rolePermissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
+
+// 发起请求的用户
+type Actor struct {
+	UserID uint
+	Role   Role
+}
+
+func (a Actor) Can(p Permission) bool {
+	return a.Role.Can(p)
+}
+
+// 操作资源的权限: 作者本人需要own权限, 其他人需要any权限
+func (a Actor) CanOn(ownerID uint, own, any Permission) bool {
+	if a.UserID == ownerID && a.Can(own) {
+		return true
+	}
+	return a.Can(any)
+}
+
+func getCurrentActor(c *gin.Context) (Actor, bool) {
+	claims, ok := getCurrentClaims(c)
+	if !ok {
+		return Actor{}, false
+	}
+	return Actor{UserID: claims.UserID, Role: claims.Role}, true
+}
+
+// 校验当前用户是否拥有权限, 需在JwtAuthMiddleware之后使用
+func RequirePermission(p Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor, ok := getCurrentActor(c)
+		if !ok {
+			c.Abort()
+			return
+		}
+		if !actor.Can(p) {
+			requestLogger(c).Warn("permission denied", zap.String("role", string(actor.Role)), zap.String("permission", string(p)))
+			writeProblem(c, ErrPermissionDenied)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("record already exists")
+)
+
+// 用户存储
+type UserRepository interface {
+	Create(ctx context.Context, user *User) error
+	GetByID(ctx context.Context, id uint) (*User, error)
+	GetByUsername(ctx context.Context, username string) (*User, error)
+	GetByWalletAddress(ctx context.Context, address string) (*User, error)
+	// 多个用户使用同一邮箱时返回最早注册的
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	SetWalletAddress(ctx context.Context, id uint, address string) error
+	SetRole(ctx context.Context, id uint, role Role) error
+	SetPassword(ctx context.Context, id uint, hash string) error
+	// 邮箱仍为email时标记为已验证, 否则返回ErrNotFound
+	SetEmailVerified(ctx context.Context, id uint, email string, at time.Time) error
+	// 更新昵称、头像、简介和邮箱
+	UpdateProfile(ctx context.Context, user *User) error
+	// at为nil时解除封禁
+	SetSuspended(ctx context.Context, id uint, at *time.Time) error
+	// 按条件分页查询, 按id升序
+	List(ctx context.Context, q UserQuery) (users []User, total int64, err error)
+	// 注销: 清空个人信息并软删除用户及其文章、评论, 删除关注关系; 返回被删除的文章和评论id
+	Delete(ctx context.Context, id uint) (postIDs, commentIDs []uint, err error)
+}
+
+// 用户列表查询条件, 零值表示不过滤
+type UserQuery struct {
+	Keyword   string // 用户名、昵称或邮箱包含关键字
+	Role      Role
+	Suspended *bool
+	Page      Page
+}
+
+// 文章存储
+type PostRepository interface {
+	Create(ctx context.Context, post *Post) error
+	GetByID(ctx context.Context, id uint) (*Post, error)
+	Update(ctx context.Context, post *Post) error
+	Delete(ctx context.Context, id uint) error
+	// 按条件分页查询, total为不含分页条件的总数
+	List(ctx context.Context, q PostQuery) (posts []Post, total int64, err error)
+	// 按id升序返回id大于afterID的文章, 用于批量遍历
+	ListAfterID(ctx context.Context, afterID uint, limit int) ([]Post, error)
+	// 发布所有PublishAt不晚于now的定时文章, 返回被发布的文章
+	PublishDue(ctx context.Context, now time.Time) ([]Post, error)
+}
+
+// 文章排序字段
+var postSortFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"title":      true,
+}
+
+// 文章排序方式, 相同值按id排序保证顺序稳定
+type PostSort struct {
+	Field string
+	Desc  bool
+}
+
+// 解析 "created_at"、"-created_at" 形式的排序参数, "-" 表示倒序
+func ParsePostSort(s string) (PostSort, bool) {
+	if s == "" {
+		return PostSort{Field: "created_at", Desc: true}, true
+	}
+	sort := PostSort{Field: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+	return sort, postSortFields[sort.Field]
+}
+
+func (s PostSort) String() string {
+	if s.Desc {
+		return "-" + s.Field
+	}
+	return s.Field
+}
+
+// 文章排序字段的值, 写入游标
+func postSortValue(p Post, field string) string {
+	switch field {
+	case "updated_at":
+		return p.UpdatedAt.Format(time.RFC3339Nano)
+	case "title":
+		return p.Title
+	default:
+		return p.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// 解析游标中的排序字段值
+func postCursorValue(field, value string) (any, error) {
+	if field == "title" {
+		return value, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// 文章列表查询条件, 零值表示不过滤
+type PostQuery struct {
+	AuthorID    uint
+	From        *time.Time // 创建时间 >= From
+	To          *time.Time // 创建时间 <= To
+	Keyword     string     // 标题或正文包含关键字
+	Tags        []string   // 标签slug, 默认包含任一标签即可
+	AllTags     bool       // 为true时需包含全部标签
+	CategoryID  uint       // 分类id, 包含子分类
+	CategoryIDs []uint     // 由service根据CategoryID展开, 仓库只使用该字段
+	Status      PostStatus // 按状态过滤
+	ViewerID    uint       // 只返回已发布或该用户自己的文章
+	FollowedBy  uint       // 只返回该用户关注的作者的文章
+	Sort        PostSort
+	Page        Page
+}
+
+// 评论存储
+type CommentRepository interface {
+	Create(ctx context.Context, comment *Comment) error
+	GetByID(ctx context.Context, id uint) (*Comment, error)
+	// 更新内容和编辑时间
+	Update(ctx context.Context, comment *Comment) error
+	// 软删除, 回复仍然保留
+	Delete(ctx context.Context, id uint) error
+	// 按id升序分页查询, 游标只使用id
+	ListByPostID(ctx context.Context, postID uint, page Page) (comments []Comment, total int64, err error)
+	// 按id升序返回文章下的全部评论, 包含已删除的评论, 用于建立搜索索引
+	ListThread(ctx context.Context, postID uint) ([]Comment, error)
+	// 按id升序分页查询顶层评论, 用于评论树; 已删除的顶层评论有回复时保留
+	ListRoots(ctx context.Context, postID uint, page Page) (comments []Comment, total int64, err error)
+	// 按id升序返回这些评论的直接回复, 包含已删除的评论
+	ListReplies(ctx context.Context, parentIDs []uint) ([]Comment, error)
+	// 统计每篇文章未删除的评论数
+	CountByPostIDs(ctx context.Context, postIDs []uint) (map[uint]int64, error)
+	// 按id升序返回id大于afterID的评论, 用于批量遍历
+	ListAfterID(ctx context.Context, afterID uint, limit int) ([]Comment, error)
+}
+
+// 文章修订存储
+type PostRevisionRepository interface {
+	// 保存修订, 版本号为文章当前最大版本号加1
+	Create(ctx context.Context, rev *PostRevision) error
+	GetByVersion(ctx context.Context, postID uint, version int) (*PostRevision, error)
+	// 最新版本, 没有修订时返回ErrNotFound
+	Latest(ctx context.Context, postID uint) (*PostRevision, error)
+	// 按版本倒序分页查询
+	ListByPostID(ctx context.Context, postID uint, page Page) (revisions []PostRevision, total int64, err error)
+}
+
+// 附件存储
+type AttachmentRepository interface {
+	Create(ctx context.Context, a *Attachment) error
+	GetByID(ctx context.Context, id uint) (*Attachment, error)
+	// 按文件或缩略图的key查找
+	GetByKey(ctx context.Context, key string) (*Attachment, error)
+	// 按id升序返回文章的全部附件
+	ListByPostID(ctx context.Context, postID uint) ([]Attachment, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+// 点赞、收藏和浏览数存储, 重复点赞或取消时返回false
+type EngagementRepository interface {
+	Like(ctx context.Context, userID, postID uint) (bool, error)
+	Unlike(ctx context.Context, userID, postID uint) (bool, error)
+	Bookmark(ctx context.Context, userID, postID uint) (bool, error)
+	Unbookmark(ctx context.Context, userID, postID uint) (bool, error)
+	// 用户是否点赞、收藏了文章
+	Status(ctx context.Context, userID, postID uint) (liked, bookmarked bool, err error)
+	CountLikes(ctx context.Context, postIDs []uint) (map[uint]int64, error)
+	// 按收藏时间倒序分页查询, 只返回已发布或该用户自己的文章
+	ListBookmarks(ctx context.Context, userID uint, page Page) (posts []Post, total int64, err error)
+	// 累加文章浏览数, 不修改更新时间
+	AddViews(ctx context.Context, views map[uint]int64) error
+	// 已发布文章按点赞数倒序
+	MostLiked(ctx context.Context, limit int) ([]PostScore, error)
+	// 已发布文章按since之后的点赞数加commentWeight倍评论数倒序, 相同时按浏览数
+	Trending(ctx context.Context, since time.Time, commentWeight int64, limit int) ([]PostScore, error)
+}
+
+// 关注关系存储, 重复关注或取消时返回false
+type FollowRepository interface {
+	Follow(ctx context.Context, followerID, followeeID uint) (bool, error)
+	Unfollow(ctx context.Context, followerID, followeeID uint) (bool, error)
+	// 关注该用户的人, 按关注时间倒序分页
+	ListFollowers(ctx context.Context, userID uint, page Page) (users []User, total int64, err error)
+	// 该用户关注的人, 按关注时间倒序分页
+	ListFollowing(ctx context.Context, userID uint, page Page) (users []User, total int64, err error)
+	// 全部关注者的id
+	FollowerIDs(ctx context.Context, userID uint) ([]uint, error)
+}
+
+// 通知存储
+type NotificationRepository interface {
+	Create(ctx context.Context, n *Notification) error
+	// 按id倒序分页查询, 游标只使用id; unreadOnly为true时只返回未读通知
+	ListByUserID(ctx context.Context, userID uint, unreadOnly bool, page Page) (notifications []Notification, total int64, err error)
+	CountUnread(ctx context.Context, userID uint) (int64, error)
+	// 标记已读, 不存在或不属于该用户时返回ErrNotFound
+	MarkRead(ctx context.Context, userID, id uint, at time.Time) error
+	// 标记全部未读通知, 返回标记的数量
+	MarkAllRead(ctx context.Context, userID uint, at time.Time) (int64, error)
+}
+
+// 文章id及其排名分数
+type PostScore struct {
+	PostID uint
+	Score  int64
+}
+
+// 标签存储
+type TagRepository interface {
+	Create(ctx context.Context, tag *Tag) error
+	GetBySlugs(ctx context.Context, slugs []string) ([]Tag, error)
+	// 删除标签及其与文章的关联
+	Delete(ctx context.Context, id uint) error
+	// 按文章数倒序返回标签, 只统计已发布的文章; limit为0时不限制
+	Cloud(ctx context.Context, limit int) ([]TagCount, error)
+	// 替换文章的全部标签
+	SetPostTags(ctx context.Context, postID uint, tagIDs []uint) error
+	ListByPostIDs(ctx context.Context, postIDs []uint) (map[uint][]Tag, error)
+}
+
+// 标签及其文章数
+type TagCount struct {
+	Tag
+	Count  int64 `json:"count"`
+	Weight int   `json:"weight" gorm:"-"` // 标签云字号等级1-5
+}
+
+// 分类存储
+type CategoryRepository interface {
+	Create(ctx context.Context, category *Category) error
+	GetByID(ctx context.Context, id uint) (*Category, error)
+	Update(ctx context.Context, category *Category) error
+	// 删除分类, 并清空使用该分类的文章的分类
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context) ([]Category, error)
+}
+
+// token存储: refresh token与access token黑名单
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	// 作废单个refresh token, 已作废时返回false
+	RevokeRefreshToken(ctx context.Context, id uint, at time.Time) (bool, error)
+	// 作废整个家族, 返回家族内所有token
+	RevokeRefreshFamily(ctx context.Context, familyID string, at time.Time) ([]RefreshToken, error)
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	// 作废用户所有的refresh token, 返回被作废的token
+	RevokeUserRefreshTokens(ctx context.Context, userID uint, at time.Time) ([]RefreshToken, error)
+	CreateUserToken(ctx context.Context, token *UserToken) error
+	// 使用一次性token, 不存在、已使用或已过期时返回ErrNotFound
+	ConsumeUserToken(ctx context.Context, purpose UserTokenPurpose, tokenHash string, now time.Time) (*UserToken, error)
+	// 作废用户该用途未使用的token
+	RevokeUserTokens(ctx context.Context, userID uint, purpose UserTokenPurpose, at time.Time) error
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+// SIWE nonce存储
+type NonceRepository interface {
+	CreateNonce(ctx context.Context, nonce *SiweNonce) error
+	// 消耗nonce, 不存在或已过期返回false
+	ConsumeNonce(ctx context.Context, nonce string, now time.Time) (bool, error)
+	DeleteExpiredNonces(ctx context.Context, now time.Time) error
+}
+
+// 所有存储的集合, 由gorm或内存实现提供
+type Repositories struct {
+	Users         UserRepository
+	Posts         PostRepository
+	Comments      CommentRepository
+	Revisions     PostRevisionRepository
+	Attachments   AttachmentRepository
+	Engagement    EngagementRepository
+	Follows       FollowRepository
+	Notifications NotificationRepository
+	Tags          TagRepository
+	Categories    CategoryRepository
+	Tokens        TokenRepository
+	Nonces        NonceRepository
+	Search        Searcher
+	Blobs         BlobStore      // 上传文件存储, 由main根据配置创建
+	Broker        Broker         // 实时推送的发布订阅, 由main创建
+	RateLimits    RateLimitStore // 限流和登录失败计数, 由main创建
+	Mailer        Mailer         // 邮件发送, 由main根据配置创建
+}
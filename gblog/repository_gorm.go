@@ -0,0 +1,1133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func NewGormRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		Users:         &gormUserRepository{db: db},
+		Posts:         &gormPostRepository{db: db},
+		Comments:      &gormCommentRepository{db: db},
+		Revisions:     &gormRevisionRepository{db: db},
+		Attachments:   &gormAttachmentRepository{db: db},
+		Engagement:    &gormEngagementRepository{db: db},
+		Follows:       &gormFollowRepository{db: db},
+		Notifications: &gormNotificationRepository{db: db},
+		Tags:          &gormTagRepository{db: db},
+		Categories:    &gormCategoryRepository{db: db},
+		Tokens:        &gormTokenRepository{db: db},
+		Nonces:        &gormNonceRepository{db: db},
+		Search:        NewIndexSearcher(),
+	}
+}
+
+// 将gorm错误转换为存储层错误
+func translateGormError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	default:
+		return err
+	}
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *User) error {
+	return translateGormError(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *gormUserRepository) GetByID(ctx context.Context, id uint) (*User, error) {
+	var user User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	var user User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) GetByWalletAddress(ctx context.Context, address string) (*User, error) {
+	var user User
+	if err := r.db.WithContext(ctx).Where("wallet_address = ?", address).First(&user).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	var user User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).Order("id").First(&user).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepository) SetWalletAddress(ctx context.Context, id uint, address string) error {
+	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("wallet_address", address)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) SetRole(ctx context.Context, id uint, role Role) error {
+	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("role", role)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) SetPassword(ctx context.Context, id uint, hash string) error {
+	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("password", hash)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) SetEmailVerified(ctx context.Context, id uint, email string, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ? AND email = ?", id, email).Update("email_verified_at", at)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) UpdateProfile(ctx context.Context, user *User) error {
+	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", user.ID).
+		Select("display_name", "avatar_url", "bio", "email", "email_verified_at").
+		Updates(user)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) SetSuspended(ctx context.Context, id uint, at *time.Time) error {
+	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("suspended_at", at)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormUserRepository) List(ctx context.Context, q UserQuery) ([]User, int64, error) {
+	db := r.db.WithContext(ctx).Model(&User{})
+	if q.Keyword != "" {
+		pattern := likePattern(q.Keyword)
+		db = db.Where("(LOWER(username) LIKE ? ESCAPE '"+likeEscape+"' OR LOWER(display_name) LIKE ? ESCAPE '"+likeEscape+"' OR LOWER(email) LIKE ? ESCAPE '"+likeEscape+"')", pattern, pattern, pattern)
+	}
+	if q.Role != "" {
+		db = db.Where("role = ?", q.Role)
+	}
+	if q.Suspended != nil {
+		if *q.Suspended {
+			db = db.Where("suspended_at IS NOT NULL")
+		} else {
+			db = db.Where("suspended_at IS NULL")
+		}
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	var users []User
+	if err := db.Order("id").Offset(q.Page.Offset).Limit(q.Page.Limit).Find(&users).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	return users, total, nil
+}
+
+func (r *gormUserRepository) Delete(ctx context.Context, id uint) ([]uint, []uint, error) {
+	var postIDs, commentIDs []uint
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 保留用户名防止被他人冒用, 释放钱包地址
+		result := tx.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+			"password":       "",
+			"email":          "",
+			"display_name":   "",
+			"avatar_url":     "",
+			"bio":            "",
+			"wallet_address": nil,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Model(&Post{}).Where("user_id = ?", id).Pluck("id", &postIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Comment{}).Where("user_id = ?", id).Pluck("id", &commentIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&Post{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("follower_id = ? OR followee_id = ?", id, id).Delete(&Follow{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&User{}, id).Error
+	})
+	if err != nil {
+		return nil, nil, translateGormError(err)
+	}
+	return postIDs, commentIDs, nil
+}
+
+type gormPostRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormPostRepository) Create(ctx context.Context, post *Post) error {
+	return translateGormError(r.db.WithContext(ctx).Create(post).Error)
+}
+
+func (r *gormPostRepository) GetByID(ctx context.Context, id uint) (*Post, error) {
+	var post Post
+	if err := r.db.WithContext(ctx).First(&post, id).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &post, nil
+}
+
+func (r *gormPostRepository) Update(ctx context.Context, post *Post) error {
+	return translateGormError(r.db.WithContext(ctx).Model(post).Select("Title", "Content", "ContentHTML", "TOC", "CategoryID", "Status", "PublishAt", "PublishedAt").Updates(post).Error)
+}
+
+func (r *gormPostRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&Post{}, id)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// LIKE匹配的转义字符, 各数据库默认转义字符不同, 统一显式指定
+const likeEscape = "!"
+
+func likePattern(keyword string) string {
+	r := strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_")
+	return "%" + strings.ToLower(r.Replace(keyword)) + "%"
+}
+
+// 游标条件: 排序字段越过游标值, 或相等时id越过游标id
+func applyCursor(q *gorm.DB, column string, desc bool, value any, id uint) *gorm.DB {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return q.Where("("+column+" "+op+" ?) OR ("+column+" = ? AND id "+op+" ?)", value, value, id)
+}
+
+func (r *gormPostRepository) List(ctx context.Context, pq PostQuery) ([]Post, int64, error) {
+	q := r.db.WithContext(ctx).Model(&Post{})
+	if pq.AuthorID != 0 {
+		q = q.Where("user_id = ?", pq.AuthorID)
+	}
+	if pq.From != nil {
+		q = q.Where("created_at >= ?", *pq.From)
+	}
+	if pq.To != nil {
+		q = q.Where("created_at <= ?", *pq.To)
+	}
+	if pq.Keyword != "" {
+		pattern := likePattern(pq.Keyword)
+		q = q.Where("(LOWER(title) LIKE ? ESCAPE '"+likeEscape+"' OR LOWER(content) LIKE ? ESCAPE '"+likeEscape+"')", pattern, pattern)
+	}
+	if len(pq.Tags) > 0 {
+		sub := r.db.Table("post_tags").Select("post_tags.post_id").
+			Joins("JOIN tags ON tags.id = post_tags.tag_id").
+			Where("tags.slug IN ?", pq.Tags)
+		if pq.AllTags {
+			sub = sub.Group("post_tags.post_id").Having("COUNT(DISTINCT tags.id) = ?", len(pq.Tags))
+		}
+		q = q.Where("id IN (?)", sub)
+	}
+	if pq.CategoryIDs != nil {
+		q = q.Where("category_id IN ?", pq.CategoryIDs)
+	}
+	if pq.Status != "" {
+		q = q.Where("status = ?", pq.Status)
+	}
+	if pq.FollowedBy != 0 {
+		q = q.Where("user_id IN (?)", r.db.Model(&Follow{}).Select("followee_id").Where("follower_id = ?", pq.FollowedBy))
+	}
+	q = q.Where("(status = ? OR user_id = ?)", PostPublished, pq.ViewerID)
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+
+	// 排序字段已在ParsePostSort中校验
+	column, dir := pq.Sort.Field, " ASC"
+	if pq.Sort.Desc {
+		dir = " DESC"
+	}
+	if c := pq.Page.After; c != nil {
+		value, err := postCursorValue(pq.Sort.Field, c.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		q = applyCursor(q, column, pq.Sort.Desc, value, c.ID)
+	} else if pq.Page.Offset > 0 {
+		q = q.Offset(pq.Page.Offset)
+	}
+
+	var posts []Post
+	if err := q.Order(column + dir).Order("id" + dir).Limit(pq.Page.Limit).Find(&posts).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	return posts, total, nil
+}
+
+func (r *gormPostRepository) ListAfterID(ctx context.Context, afterID uint, limit int) ([]Post, error) {
+	var posts []Post
+	if err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id").Limit(limit).Find(&posts).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return posts, nil
+}
+
+func (r *gormPostRepository) PublishDue(ctx context.Context, now time.Time) ([]Post, error) {
+	var posts []Post
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("status = ? AND publish_at <= ?", PostScheduled, now).Find(&posts).Error; err != nil {
+			return err
+		}
+		for i := range posts {
+			p := &posts[i]
+			// 条件中带上状态, 避免覆盖期间被作者修改的文章
+			result := tx.Model(&Post{}).Where("id = ? AND status = ?", p.ID, PostScheduled).
+				Updates(map[string]any{"status": PostPublished, "published_at": p.PublishAt, "publish_at": nil})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			p.Status, p.PublishedAt, p.PublishAt = PostPublished, p.PublishAt, nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	published := posts[:0]
+	for _, p := range posts {
+		if p.Status == PostPublished {
+			published = append(published, p)
+		}
+	}
+	return published, nil
+}
+
+type gormRevisionRepository struct {
+	db *gorm.DB
+}
+
+// 版本号在事务中分配, 并发写入时由唯一索引保证不重复
+func (r *gormRevisionRepository) Create(ctx context.Context, rev *PostRevision) error {
+	return translateGormError(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&PostRevision{}).Where("post_id = ?", rev.PostID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		rev.Version = latest + 1
+		return tx.Create(rev).Error
+	}))
+}
+
+func (r *gormRevisionRepository) GetByVersion(ctx context.Context, postID uint, version int) (*PostRevision, error) {
+	var rev PostRevision
+	if err := r.db.WithContext(ctx).Where("post_id = ? AND version = ?", postID, version).First(&rev).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &rev, nil
+}
+
+func (r *gormRevisionRepository) Latest(ctx context.Context, postID uint) (*PostRevision, error) {
+	var rev PostRevision
+	if err := r.db.WithContext(ctx).Where("post_id = ?", postID).Order("version DESC").First(&rev).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &rev, nil
+}
+
+func (r *gormRevisionRepository) ListByPostID(ctx context.Context, postID uint, page Page) ([]PostRevision, int64, error) {
+	q := r.db.WithContext(ctx).Model(&PostRevision{}).Where("post_id = ?", postID)
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	var revisions []PostRevision
+	if err := q.Order("version DESC").Offset(page.Offset).Limit(page.Limit).Find(&revisions).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	return revisions, total, nil
+}
+
+type gormAttachmentRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAttachmentRepository) Create(ctx context.Context, a *Attachment) error {
+	return translateGormError(r.db.WithContext(ctx).Create(a).Error)
+}
+
+func (r *gormAttachmentRepository) GetByID(ctx context.Context, id uint) (*Attachment, error) {
+	var a Attachment
+	if err := r.db.WithContext(ctx).First(&a, id).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &a, nil
+}
+
+func (r *gormAttachmentRepository) GetByKey(ctx context.Context, key string) (*Attachment, error) {
+	var a Attachment
+	if err := r.db.WithContext(ctx).Where("file_key = ? OR thumb_key = ?", key, key).First(&a).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &a, nil
+}
+
+func (r *gormAttachmentRepository) ListByPostID(ctx context.Context, postID uint) ([]Attachment, error) {
+	var attachments []Attachment
+	if err := r.db.WithContext(ctx).Where("post_id = ?", postID).Order("id").Find(&attachments).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return attachments, nil
+}
+
+func (r *gormAttachmentRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&Attachment{}, id)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type gormEngagementRepository struct {
+	db *gorm.DB
+}
+
+// 主键冲突时不插入, 由影响行数判断是否新增
+func (r *gormEngagementRepository) insert(ctx context.Context, value any) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(value)
+	if result.Error != nil {
+		return false, translateGormError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *gormEngagementRepository) remove(ctx context.Context, model any, userID, postID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND post_id = ?", userID, postID).Delete(model)
+	if result.Error != nil {
+		return false, translateGormError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *gormEngagementRepository) Like(ctx context.Context, userID, postID uint) (bool, error) {
+	return r.insert(ctx, &PostLike{UserID: userID, PostID: postID})
+}
+
+func (r *gormEngagementRepository) Unlike(ctx context.Context, userID, postID uint) (bool, error) {
+	return r.remove(ctx, &PostLike{}, userID, postID)
+}
+
+func (r *gormEngagementRepository) Bookmark(ctx context.Context, userID, postID uint) (bool, error) {
+	return r.insert(ctx, &Bookmark{UserID: userID, PostID: postID})
+}
+
+func (r *gormEngagementRepository) Unbookmark(ctx context.Context, userID, postID uint) (bool, error) {
+	return r.remove(ctx, &Bookmark{}, userID, postID)
+}
+
+func (r *gormEngagementRepository) Status(ctx context.Context, userID, postID uint) (bool, bool, error) {
+	var likes, bookmarks int64
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&PostLike{}).Where("user_id = ? AND post_id = ?", userID, postID).Count(&likes).Error; err != nil {
+		return false, false, translateGormError(err)
+	}
+	if err := db.Model(&Bookmark{}).Where("user_id = ? AND post_id = ?", userID, postID).Count(&bookmarks).Error; err != nil {
+		return false, false, translateGormError(err)
+	}
+	return likes > 0, bookmarks > 0, nil
+}
+
+func (r *gormEngagementRepository) CountLikes(ctx context.Context, postIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		PostID uint
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&PostLike{}).
+		Select("post_id, COUNT(*) AS count").
+		Where("post_id IN ?", postIDs).
+		Group("post_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	for _, id := range postIDs {
+		counts[id] = 0
+	}
+	for _, row := range rows {
+		counts[row.PostID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *gormEngagementRepository) ListBookmarks(ctx context.Context, userID uint, page Page) ([]Post, int64, error) {
+	q := r.db.WithContext(ctx).Model(&Post{}).
+		Joins("JOIN bookmarks ON bookmarks.post_id = posts.id AND bookmarks.user_id = ?", userID).
+		Where("posts.status = ? OR posts.user_id = ?", PostPublished, userID)
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	var posts []Post
+	err := q.Select("posts.*").
+		Order("bookmarks.created_at DESC").Order("posts.id DESC").
+		Offset(page.Offset).Limit(page.Limit).
+		Find(&posts).Error
+	if err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	return posts, total, nil
+}
+
+func (r *gormEngagementRepository) AddViews(ctx context.Context, views map[uint]int64) error {
+	return translateGormError(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, n := range views {
+			err := tx.Model(&Post{}).Where("id = ?", id).
+				UpdateColumn("view_count", gorm.Expr("view_count + ?", n)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// 与task3中查询评论最多的文章相同, 先按文章分组计数再关联文章表
+func (r *gormEngagementRepository) MostLiked(ctx context.Context, limit int) ([]PostScore, error) {
+	db := r.db.WithContext(ctx)
+	likes := db.Model(&PostLike{}).Select("post_id, COUNT(*) AS count").Group("post_id")
+
+	var scores []PostScore
+	err := db.Table("posts").
+		Select("posts.id AS post_id, likes.count AS score").
+		Joins("JOIN (?) AS likes ON likes.post_id = posts.id", likes).
+		Where("posts.deleted_at IS NULL AND posts.status = ?", PostPublished).
+		Order("score DESC").Order("posts.id DESC").
+		Limit(limit).
+		Scan(&scores).Error
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	return scores, nil
+}
+
+func (r *gormEngagementRepository) Trending(ctx context.Context, since time.Time, commentWeight int64, limit int) ([]PostScore, error) {
+	db := r.db.WithContext(ctx)
+	likes := db.Model(&PostLike{}).Select("post_id, COUNT(*) AS count").
+		Where("created_at >= ?", since).Group("post_id")
+	comments := db.Model(&Comment{}).Select("post_id, COUNT(*) AS count").
+		Where("created_at >= ?", since).Group("post_id")
+
+	var scores []PostScore
+	err := db.Table("posts").
+		Select("posts.id AS post_id, COALESCE(likes.count, 0) + ? * COALESCE(comments.count, 0) AS score", commentWeight).
+		Joins("LEFT JOIN (?) AS likes ON likes.post_id = posts.id", likes).
+		Joins("LEFT JOIN (?) AS comments ON comments.post_id = posts.id", comments).
+		Where("posts.deleted_at IS NULL AND posts.status = ?", PostPublished).
+		Where("likes.count IS NOT NULL OR comments.count IS NOT NULL").
+		Order("score DESC").Order("posts.view_count DESC").Order("posts.id DESC").
+		Limit(limit).
+		Scan(&scores).Error
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	return scores, nil
+}
+
+type gormFollowRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormFollowRepository) Follow(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Follow{FollowerID: followerID, FolloweeID: followeeID})
+	if result.Error != nil {
+		return false, translateGormError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *gormFollowRepository) Unfollow(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&Follow{})
+	if result.Error != nil {
+		return false, translateGormError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *gormFollowRepository) ListFollowers(ctx context.Context, userID uint, page Page) ([]User, int64, error) {
+	return r.listUsers(ctx, "follows.follower_id = users.id AND follows.followee_id = ?", userID, page)
+}
+
+func (r *gormFollowRepository) ListFollowing(ctx context.Context, userID uint, page Page) ([]User, int64, error) {
+	return r.listUsers(ctx, "follows.followee_id = users.id AND follows.follower_id = ?", userID, page)
+}
+
+func (r *gormFollowRepository) listUsers(ctx context.Context, on string, userID uint, page Page) ([]User, int64, error) {
+	q := r.db.WithContext(ctx).Model(&User{}).Joins("JOIN follows ON "+on, userID)
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	var users []User
+	err := q.Select("users.*").
+		Order("follows.created_at DESC").Order("users.id DESC").
+		Offset(page.Offset).Limit(page.Limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	return users, total, nil
+}
+
+func (r *gormFollowRepository) FollowerIDs(ctx context.Context, userID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.WithContext(ctx).Model(&Follow{}).Where("followee_id = ?", userID).Pluck("follower_id", &ids).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return ids, nil
+}
+
+type gormNotificationRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormNotificationRepository) Create(ctx context.Context, n *Notification) error {
+	return translateGormError(r.db.WithContext(ctx).Create(n).Error)
+}
+
+func (r *gormNotificationRepository) ListByUserID(ctx context.Context, userID uint, unreadOnly bool, page Page) ([]Notification, int64, error) {
+	q := r.db.WithContext(ctx).Model(&Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		q = q.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+
+	if page.After != nil {
+		q = q.Where("id < ?", page.After.ID)
+	} else if page.Offset > 0 {
+		q = q.Offset(page.Offset)
+	}
+	var notifications []Notification
+	if err := q.Order("id DESC").Limit(page.Limit).Find(&notifications).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	return notifications, total, nil
+}
+
+func (r *gormNotificationRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, translateGormError(err)
+}
+
+func (r *gormNotificationRepository) MarkRead(ctx context.Context, userID, id uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", at)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	// 未更新时区分已读和不存在
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&Notification{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+		return translateGormError(err)
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormNotificationRepository) MarkAllRead(ctx context.Context, userID uint, at time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", at)
+	return result.RowsAffected, translateGormError(result.Error)
+}
+
+type gormCommentRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormCommentRepository) Create(ctx context.Context, comment *Comment) error {
+	return translateGormError(r.db.WithContext(ctx).Create(comment).Error)
+}
+
+func (r *gormCommentRepository) GetByID(ctx context.Context, id uint) (*Comment, error) {
+	var comment Comment
+	if err := r.db.WithContext(ctx).First(&comment, id).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &comment, nil
+}
+
+func (r *gormCommentRepository) Update(ctx context.Context, comment *Comment) error {
+	result := r.db.WithContext(ctx).Model(comment).Select("Content", "EditedAt").Updates(comment)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormCommentRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&Comment{}, id)
+	if result.Error != nil {
+		return translateGormError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *gormCommentRepository) ListByPostID(ctx context.Context, postID uint, page Page) ([]Comment, int64, error) {
+	q := r.db.WithContext(ctx).Model(&Comment{}).Where("post_id = ?", postID)
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+
+	if page.After != nil {
+		q = q.Where("id > ?", page.After.ID)
+	} else if page.Offset > 0 {
+		q = q.Offset(page.Offset)
+	}
+	var comments []Comment
+	if err := q.Order("id").Limit(page.Limit).Find(&comments).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	return comments, total, nil
+}
+
+func (r *gormCommentRepository) ListThread(ctx context.Context, postID uint) ([]Comment, error) {
+	var comments []Comment
+	if err := r.db.WithContext(ctx).Unscoped().Where("post_id = ?", postID).Order("id").Find(&comments).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return comments, nil
+}
+
+func (r *gormCommentRepository) ListRoots(ctx context.Context, postID uint, page Page) ([]Comment, int64, error) {
+	q := r.db.WithContext(ctx).Unscoped().Model(&Comment{}).
+		Where("post_id = ? AND parent_id IS NULL", postID).
+		Where("deleted_at IS NULL OR EXISTS (SELECT 1 FROM comments AS replies WHERE replies.parent_id = comments.id)")
+
+	var total int64
+	if err := q.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+
+	if page.After != nil {
+		q = q.Where("id > ?", page.After.ID)
+	} else if page.Offset > 0 {
+		q = q.Offset(page.Offset)
+	}
+	var comments []Comment
+	if err := q.Order("id").Limit(page.Limit).Find(&comments).Error; err != nil {
+		return nil, 0, translateGormError(err)
+	}
+	return comments, total, nil
+}
+
+func (r *gormCommentRepository) ListReplies(ctx context.Context, parentIDs []uint) ([]Comment, error) {
+	var comments []Comment
+	if len(parentIDs) == 0 {
+		return comments, nil
+	}
+	if err := r.db.WithContext(ctx).Unscoped().Where("parent_id IN ?", parentIDs).Order("id").Find(&comments).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return comments, nil
+}
+
+func (r *gormCommentRepository) CountByPostIDs(ctx context.Context, postIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(postIDs))
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		PostID uint
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).Model(&Comment{}).
+		Select("post_id, COUNT(*) AS count").
+		Where("post_id IN ?", postIDs).
+		Group("post_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	for _, id := range postIDs {
+		counts[id] = 0
+	}
+	for _, row := range rows {
+		counts[row.PostID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *gormCommentRepository) ListAfterID(ctx context.Context, afterID uint, limit int) ([]Comment, error) {
+	var comments []Comment
+	if err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id").Limit(limit).Find(&comments).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return comments, nil
+}
+
+type gormTagRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTagRepository) Create(ctx context.Context, tag *Tag) error {
+	return translateGormError(r.db.WithContext(ctx).Create(tag).Error)
+}
+
+func (r *gormTagRepository) GetBySlugs(ctx context.Context, slugs []string) ([]Tag, error) {
+	var tags []Tag
+	if err := r.db.WithContext(ctx).Where("slug IN ?", slugs).Find(&tags).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return tags, nil
+}
+
+func (r *gormTagRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM post_tags WHERE tag_id = ?", id).Error; err != nil {
+			return translateGormError(err)
+		}
+		result := tx.Delete(&Tag{}, id)
+		if result.Error != nil {
+			return translateGormError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *gormTagRepository) Cloud(ctx context.Context, limit int) ([]TagCount, error) {
+	q := r.db.WithContext(ctx).Table("tags").
+		Select("tags.id, tags.name, tags.slug, COUNT(posts.id) AS count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.deleted_at IS NULL AND posts.status = ?", PostPublished).
+		Group("tags.id, tags.name, tags.slug").
+		Order("count DESC").Order("tags.slug")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	var tags []TagCount
+	if err := q.Scan(&tags).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return tags, nil
+}
+
+func (r *gormTagRepository) SetPostTags(ctx context.Context, postID uint, tagIDs []uint) error {
+	tags := make([]Tag, 0, len(tagIDs))
+	for _, id := range tagIDs {
+		tags = append(tags, Tag{ID: id})
+	}
+	post := &Post{}
+	post.ID = postID
+	return translateGormError(r.db.WithContext(ctx).Model(post).Association("Tags").Replace(tags))
+}
+
+func (r *gormTagRepository) ListByPostIDs(ctx context.Context, postIDs []uint) (map[uint][]Tag, error) {
+	result := make(map[uint][]Tag, len(postIDs))
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+	var rows []struct {
+		PostID uint
+		Tag
+	}
+	err := r.db.WithContext(ctx).Table("post_tags").
+		Select("post_tags.post_id, tags.id, tags.name, tags.slug").
+		Joins("JOIN tags ON tags.id = post_tags.tag_id").
+		Where("post_tags.post_id IN ?", postIDs).
+		Order("tags.slug").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	for _, row := range rows {
+		result[row.PostID] = append(result[row.PostID], row.Tag)
+	}
+	return result, nil
+}
+
+type gormCategoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormCategoryRepository) Create(ctx context.Context, category *Category) error {
+	return translateGormError(r.db.WithContext(ctx).Create(category).Error)
+}
+
+func (r *gormCategoryRepository) GetByID(ctx context.Context, id uint) (*Category, error) {
+	var category Category
+	if err := r.db.WithContext(ctx).First(&category, id).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &category, nil
+}
+
+func (r *gormCategoryRepository) Update(ctx context.Context, category *Category) error {
+	return translateGormError(r.db.WithContext(ctx).Model(category).Select("Name", "Slug", "Description", "ParentID").Updates(category).Error)
+}
+
+func (r *gormCategoryRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Post{}).Where("category_id = ?", id).Update("category_id", nil).Error; err != nil {
+			return translateGormError(err)
+		}
+		result := tx.Delete(&Category{}, id)
+		if result.Error != nil {
+			return translateGormError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *gormCategoryRepository) List(ctx context.Context) ([]Category, error) {
+	var categories []Category
+	if err := r.db.WithContext(ctx).Order("id").Find(&categories).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return categories, nil
+}
+
+type gormTokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormTokenRepository) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	return translateGormError(r.db.WithContext(ctx).Create(token).Error)
+}
+
+func (r *gormTokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	var token RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, translateGormError(err)
+	}
+	return &token, nil
+}
+
+func (r *gormTokenRepository) RevokeRefreshToken(ctx context.Context, id uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at)
+	if result.Error != nil {
+		return false, translateGormError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormTokenRepository) RevokeRefreshFamily(ctx context.Context, familyID string, at time.Time) ([]RefreshToken, error) {
+	var tokens []RefreshToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+		return tx.Where("family_id = ?", familyID).Find(&tokens).Error
+	})
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	return tokens, nil
+}
+
+func (r *gormTokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+	token := RevokedToken{JTI: jti, ExpiresAt: expiresAt}
+	return translateGormError(r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&token).Error)
+}
+
+func (r *gormTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, translateGormError(err)
+	}
+	return count > 0, nil
+}
+
+func (r *gormTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uint, at time.Time) ([]RefreshToken, error) {
+	var tokens []RefreshToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND revoked_at IS NULL", userID).Find(&tokens).Error; err != nil {
+			return err
+		}
+		return tx.Model(&RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error
+	})
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	return tokens, nil
+}
+
+func (r *gormTokenRepository) CreateUserToken(ctx context.Context, token *UserToken) error {
+	return translateGormError(r.db.WithContext(ctx).Create(token).Error)
+}
+
+func (r *gormTokenRepository) ConsumeUserToken(ctx context.Context, purpose UserTokenPurpose, tokenHash string, now time.Time) (*UserToken, error) {
+	var token UserToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND purpose = ?", tokenHash, purpose).First(&token).Error; err != nil {
+			return err
+		}
+		if token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+			return gorm.ErrRecordNotFound
+		}
+		// 条件更新保证并发使用时只有一个成功
+		result := tx.Model(&UserToken{}).Where("id = ? AND used_at IS NULL", token.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		token.UsedAt = &now
+		return nil
+	})
+	if err != nil {
+		return nil, translateGormError(err)
+	}
+	return &token, nil
+}
+
+func (r *gormTokenRepository) RevokeUserTokens(ctx context.Context, userID uint, purpose UserTokenPurpose, at time.Time) error {
+	return translateGormError(r.db.WithContext(ctx).Model(&UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error)
+}
+
+func (r *gormTokenRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", now).Delete(&RevokedToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("expires_at < ?", now).Delete(&UserToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("expires_at < ?", now).Delete(&RefreshToken{}).Error
+	})
+}
+
+type gormNonceRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormNonceRepository) CreateNonce(ctx context.Context, nonce *SiweNonce) error {
+	return translateGormError(r.db.WithContext(ctx).Create(nonce).Error)
+}
+
+func (r *gormNonceRepository) ConsumeNonce(ctx context.Context, nonce string, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Where("nonce = ? AND expires_at > ?", nonce, now).Delete(&SiweNonce{})
+	if result.Error != nil {
+		return false, translateGormError(result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *gormNonceRepository) DeleteExpiredNonces(ctx context.Context, now time.Time) error {
+	return translateGormError(r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&SiweNonce{}).Error)
+}
@@ -0,0 +1,1407 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 内存存储, 用于单元测试和本地调试, 不做持久化
+type memoryStore struct {
+	mu       sync.RWMutex
+	seq      map[string]uint // 每张表独立的自增id
+	users    map[uint]User
+	deleted  map[string]struct{} // 已注销用户的用户名, 不能再注册
+	posts    map[uint]Post
+	comments map[uint]Comment
+	revs     map[uint][]PostRevision // 文章id -> 按版本升序的修订
+	files    map[uint]Attachment
+	likes    map[engagementKey]time.Time // 点赞时间
+	marks    map[engagementKey]time.Time // 收藏时间
+	follows  map[followKey]time.Time     // 关注时间
+	notices  map[uint]Notification
+	tags     map[uint]Tag
+	postTags map[uint][]uint // 文章id -> 标签id
+	cats     map[uint]Category
+	refresh  map[uint]RefreshToken
+	userToks map[uint]UserToken
+	revoked  map[string]time.Time // jti -> 过期时间
+	nonces   map[string]time.Time // nonce -> 过期时间
+}
+
+func NewMemoryRepositories() *Repositories {
+	s := &memoryStore{
+		seq:      make(map[string]uint),
+		users:    make(map[uint]User),
+		deleted:  make(map[string]struct{}),
+		posts:    make(map[uint]Post),
+		comments: make(map[uint]Comment),
+		revs:     make(map[uint][]PostRevision),
+		files:    make(map[uint]Attachment),
+		likes:    make(map[engagementKey]time.Time),
+		marks:    make(map[engagementKey]time.Time),
+		follows:  make(map[followKey]time.Time),
+		notices:  make(map[uint]Notification),
+		tags:     make(map[uint]Tag),
+		postTags: make(map[uint][]uint),
+		cats:     make(map[uint]Category),
+		refresh:  make(map[uint]RefreshToken),
+		userToks: make(map[uint]UserToken),
+		revoked:  make(map[string]time.Time),
+		nonces:   make(map[string]time.Time),
+	}
+	return &Repositories{
+		Users:         &memoryUserRepository{s},
+		Posts:         &memoryPostRepository{s},
+		Comments:      &memoryCommentRepository{s},
+		Revisions:     &memoryRevisionRepository{s},
+		Attachments:   &memoryAttachmentRepository{s},
+		Engagement:    &memoryEngagementRepository{s},
+		Follows:       &memoryFollowRepository{s},
+		Notifications: &memoryNotificationRepository{s},
+		Tags:          &memoryTagRepository{s},
+		Categories:    &memoryCategoryRepository{s},
+		Tokens:        &memoryTokenRepository{s},
+		Nonces:        &memoryNonceRepository{s},
+		Search:        NewIndexSearcher(),
+	}
+}
+
+// 分配自增id, 调用方需持有写锁
+func (s *memoryStore) newID(table string) uint {
+	s.seq[table]++
+	return s.seq[table]
+}
+
+type memoryUserRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user *User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.deleted[user.Username]; ok {
+		return ErrDuplicate
+	}
+	for _, u := range r.s.users {
+		if u.Username == user.Username || sameWallet(u.WalletAddress, user.WalletAddress) {
+			return ErrDuplicate
+		}
+	}
+	now := time.Now()
+	user.ID = r.s.newID("users")
+	user.CreatedAt, user.UpdatedAt = now, now
+	r.s.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) GetByID(ctx context.Context, id uint) (*User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (r *memoryUserRepository) GetByUsername(ctx context.Context, username string) (*User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, u := range r.s.users {
+		if u.Username == username {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) GetByWalletAddress(ctx context.Context, address string) (*User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, u := range r.s.users {
+		if sameWallet(u.WalletAddress, &address) {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var found *User
+	for _, u := range r.s.users {
+		if u.Email == email && (found == nil || u.ID < found.ID) {
+			found = &u
+		}
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func (r *memoryUserRepository) SetWalletAddress(ctx context.Context, id uint, address string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, other := range r.s.users {
+		if other.ID != id && sameWallet(other.WalletAddress, &address) {
+			return ErrDuplicate
+		}
+	}
+	u.WalletAddress = &address
+	u.UpdatedAt = time.Now()
+	r.s.users[id] = u
+	return nil
+}
+
+func (r *memoryUserRepository) SetRole(ctx context.Context, id uint, role Role) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	u.Role = role
+	u.UpdatedAt = time.Now()
+	r.s.users[id] = u
+	return nil
+}
+
+func (r *memoryUserRepository) SetPassword(ctx context.Context, id uint, hash string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	u.Password = hash
+	u.UpdatedAt = time.Now()
+	r.s.users[id] = u
+	return nil
+}
+
+func (r *memoryUserRepository) SetEmailVerified(ctx context.Context, id uint, email string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok || u.Email != email {
+		return ErrNotFound
+	}
+	u.EmailVerifiedAt = &at
+	u.UpdatedAt = time.Now()
+	r.s.users[id] = u
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateProfile(ctx context.Context, user *User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[user.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	u.DisplayName, u.AvatarURL, u.Bio = user.DisplayName, user.AvatarURL, user.Bio
+	u.Email, u.EmailVerifiedAt = user.Email, user.EmailVerifiedAt
+	u.UpdatedAt = time.Now()
+	r.s.users[user.ID] = u
+	return nil
+}
+
+func (r *memoryUserRepository) SetSuspended(ctx context.Context, id uint, at *time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	u.SuspendedAt = at
+	u.UpdatedAt = time.Now()
+	r.s.users[id] = u
+	return nil
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, q UserQuery) ([]User, int64, error) {
+	r.s.mu.RLock()
+	users := make([]User, 0)
+	keyword := strings.ToLower(q.Keyword)
+	for _, u := range r.s.users {
+		switch {
+		case keyword != "" && !strings.Contains(strings.ToLower(u.Username), keyword) &&
+			!strings.Contains(strings.ToLower(u.DisplayName), keyword) && !strings.Contains(strings.ToLower(u.Email), keyword),
+			q.Role != "" && u.Role != q.Role,
+			q.Suspended != nil && *q.Suspended != (u.SuspendedAt != nil):
+			continue
+		}
+		users = append(users, u)
+	}
+	r.s.mu.RUnlock()
+	sortByID(users, func(u User) uint { return u.ID })
+	return pageSlice(users, q.Page.Offset, q.Page.Limit), int64(len(users)), nil
+}
+
+// 内存实现直接移除用户和文章, 与文章的删除方式一致; 用户名单独保留
+func (r *memoryUserRepository) Delete(ctx context.Context, id uint) ([]uint, []uint, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, nil, ErrNotFound
+	}
+	postIDs, commentIDs := make([]uint, 0), make([]uint, 0)
+	for pid, p := range r.s.posts {
+		if p.UserID == id {
+			postIDs = append(postIDs, pid)
+			delete(r.s.posts, pid)
+		}
+	}
+	now := time.Now()
+	for cid, c := range r.s.comments {
+		if c.UserID == id && !c.DeletedAt.Valid {
+			commentIDs = append(commentIDs, cid)
+			c.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+			r.s.comments[cid] = c
+		}
+	}
+	for key := range r.s.follows {
+		if key.followerID == id || key.followeeID == id {
+			delete(r.s.follows, key)
+		}
+	}
+	delete(r.s.users, id)
+	r.s.deleted[u.Username] = struct{}{}
+	return postIDs, commentIDs, nil
+}
+
+func sameWallet(a, b *string) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+type memoryPostRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryPostRepository) Create(ctx context.Context, post *Post) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	post.ID = r.s.newID("posts")
+	post.CreatedAt, post.UpdatedAt = now, now
+	if post.Status == "" {
+		post.Status = PostPublished
+	}
+	r.s.posts[post.ID] = *post
+	return nil
+}
+
+func (r *memoryPostRepository) GetByID(ctx context.Context, id uint) (*Post, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.posts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r *memoryPostRepository) Update(ctx context.Context, post *Post) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	p, ok := r.s.posts[post.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	p.Title, p.Content, p.CategoryID = post.Title, post.Content, post.CategoryID
+	p.ContentHTML, p.TOC = post.ContentHTML, post.TOC
+	p.Status, p.PublishAt, p.PublishedAt = post.Status, post.PublishAt, post.PublishedAt
+	p.UpdatedAt = time.Now()
+	r.s.posts[p.ID] = p
+	post.UpdatedAt = p.UpdatedAt
+	return nil
+}
+
+func (r *memoryPostRepository) Delete(ctx context.Context, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.posts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.s.posts, id)
+	return nil
+}
+
+func (r *memoryPostRepository) List(ctx context.Context, q PostQuery) ([]Post, int64, error) {
+	r.s.mu.RLock()
+	posts := make([]Post, 0)
+	keyword := strings.ToLower(q.Keyword)
+	for _, p := range r.s.posts {
+		switch {
+		case q.AuthorID != 0 && p.UserID != q.AuthorID,
+			q.From != nil && p.CreatedAt.Before(*q.From),
+			q.To != nil && p.CreatedAt.After(*q.To),
+			keyword != "" && !strings.Contains(strings.ToLower(p.Title), keyword) && !strings.Contains(strings.ToLower(p.Content), keyword),
+			len(q.Tags) > 0 && !r.s.matchTags(p.ID, q.Tags, q.AllTags),
+			q.CategoryIDs != nil && (p.CategoryID == nil || !slices.Contains(q.CategoryIDs, *p.CategoryID)),
+			q.Status != "" && p.Status != q.Status,
+			q.FollowedBy != 0 && !r.s.isFollowing(q.FollowedBy, p.UserID),
+			!p.VisibleTo(q.ViewerID):
+			continue
+		}
+		posts = append(posts, p)
+	}
+	r.s.mu.RUnlock()
+
+	// 按排序字段比较, 相等时比较id
+	less := func(a, b Post) bool {
+		if c := comparePostField(a, b, q.Sort.Field); c != 0 {
+			return (c < 0) != q.Sort.Desc
+		}
+		if a.ID == b.ID {
+			return false
+		}
+		return (a.ID < b.ID) != q.Sort.Desc
+	}
+	sort.Slice(posts, func(i, j int) bool { return less(posts[i], posts[j]) })
+	total := int64(len(posts))
+
+	start := q.Page.Offset
+	if c := q.Page.After; c != nil {
+		value, err := postCursorValue(q.Sort.Field, c.Value)
+		if err != nil {
+			return nil, 0, err
+		}
+		pivot := Post{Model: gorm.Model{ID: c.ID}}
+		setPostField(&pivot, q.Sort.Field, value)
+		start = sort.Search(len(posts), func(i int) bool { return less(pivot, posts[i]) })
+	}
+	return pageSlice(posts, start, q.Page.Limit), total, nil
+}
+
+func (r *memoryPostRepository) ListAfterID(ctx context.Context, afterID uint, limit int) ([]Post, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	posts := make([]Post, 0)
+	for _, p := range r.s.posts {
+		if p.ID > afterID {
+			posts = append(posts, p)
+		}
+	}
+	sortByID(posts, func(p Post) uint { return p.ID })
+	return pageSlice(posts, 0, limit), nil
+}
+
+func (r *memoryPostRepository) PublishDue(ctx context.Context, now time.Time) ([]Post, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	posts := make([]Post, 0)
+	for id, p := range r.s.posts {
+		if p.Status != PostScheduled || p.PublishAt == nil || p.PublishAt.After(now) {
+			continue
+		}
+		p.Status, p.PublishedAt, p.PublishAt = PostPublished, p.PublishAt, nil
+		r.s.posts[id] = p
+		posts = append(posts, p)
+	}
+	sortByID(posts, func(p Post) uint { return p.ID })
+	return posts, nil
+}
+
+// 文章是否包含任一(all为true时全部)标签, 调用方需持有读锁
+func (s *memoryStore) matchTags(postID uint, slugs []string, all bool) bool {
+	matched := 0
+	for _, id := range s.postTags[postID] {
+		if slices.Contains(slugs, s.tags[id].Slug) {
+			matched++
+		}
+	}
+	if all {
+		return matched == len(slugs)
+	}
+	return matched > 0
+}
+
+func comparePostField(a, b Post, field string) int {
+	switch field {
+	case "updated_at":
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	case "title":
+		return strings.Compare(a.Title, b.Title)
+	default:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	}
+}
+
+func setPostField(p *Post, field string, value any) {
+	switch field {
+	case "updated_at":
+		p.UpdatedAt = value.(time.Time)
+	case "title":
+		p.Title = value.(string)
+	default:
+		p.CreatedAt = value.(time.Time)
+	}
+}
+
+// 从start开始取最多limit条
+func pageSlice[T any](items []T, start, limit int) []T {
+	if start >= len(items) {
+		return []T{}
+	}
+	end := len(items)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	return items[start:end]
+}
+
+type engagementKey struct {
+	userID, postID uint
+}
+
+type memoryEngagementRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryEngagementRepository) set(m map[engagementKey]time.Time, userID, postID uint, on bool) bool {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := engagementKey{userID, postID}
+	_, exists := m[key]
+	if on && !exists {
+		m[key] = time.Now()
+	} else if !on && exists {
+		delete(m, key)
+	}
+	return on != exists
+}
+
+func (r *memoryEngagementRepository) Like(ctx context.Context, userID, postID uint) (bool, error) {
+	return r.set(r.s.likes, userID, postID, true), nil
+}
+
+func (r *memoryEngagementRepository) Unlike(ctx context.Context, userID, postID uint) (bool, error) {
+	return r.set(r.s.likes, userID, postID, false), nil
+}
+
+func (r *memoryEngagementRepository) Bookmark(ctx context.Context, userID, postID uint) (bool, error) {
+	return r.set(r.s.marks, userID, postID, true), nil
+}
+
+func (r *memoryEngagementRepository) Unbookmark(ctx context.Context, userID, postID uint) (bool, error) {
+	return r.set(r.s.marks, userID, postID, false), nil
+}
+
+func (r *memoryEngagementRepository) Status(ctx context.Context, userID, postID uint) (bool, bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	key := engagementKey{userID, postID}
+	_, liked := r.s.likes[key]
+	_, bookmarked := r.s.marks[key]
+	return liked, bookmarked, nil
+}
+
+func (r *memoryEngagementRepository) CountLikes(ctx context.Context, postIDs []uint) (map[uint]int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	counts := make(map[uint]int64, len(postIDs))
+	for _, id := range postIDs {
+		counts[id] = 0
+	}
+	for key := range r.s.likes {
+		if _, ok := counts[key.postID]; ok {
+			counts[key.postID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *memoryEngagementRepository) ListBookmarks(ctx context.Context, userID uint, page Page) ([]Post, int64, error) {
+	r.s.mu.RLock()
+	type marked struct {
+		post Post
+		at   time.Time
+	}
+	items := make([]marked, 0)
+	for key, at := range r.s.marks {
+		p, ok := r.s.posts[key.postID]
+		if key.userID != userID || !ok || !p.VisibleTo(userID) {
+			continue
+		}
+		items = append(items, marked{p, at})
+	}
+	r.s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].at.Equal(items[j].at) {
+			return items[i].at.After(items[j].at)
+		}
+		return items[i].post.ID > items[j].post.ID
+	})
+	posts := make([]Post, 0, len(items))
+	for _, m := range items {
+		posts = append(posts, m.post)
+	}
+	return pageSlice(posts, page.Offset, page.Limit), int64(len(posts)), nil
+}
+
+func (r *memoryEngagementRepository) AddViews(ctx context.Context, views map[uint]int64) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, n := range views {
+		if p, ok := r.s.posts[id]; ok {
+			p.ViewCount += n
+			r.s.posts[id] = p
+		}
+	}
+	return nil
+}
+
+func (r *memoryEngagementRepository) MostLiked(ctx context.Context, limit int) ([]PostScore, error) {
+	return r.rank(limit, func(key engagementKey, at time.Time) int64 { return 1 }, nil)
+}
+
+func (r *memoryEngagementRepository) Trending(ctx context.Context, since time.Time, commentWeight int64, limit int) ([]PostScore, error) {
+	recentLikes := func(key engagementKey, at time.Time) int64 {
+		if at.Before(since) {
+			return 0
+		}
+		return 1
+	}
+	recentComments := func(c Comment) int64 {
+		if c.DeletedAt.Valid || c.CreatedAt.Before(since) {
+			return 0
+		}
+		return commentWeight
+	}
+	return r.rank(limit, recentLikes, recentComments)
+}
+
+// 累加点赞和评论的分数, 只保留分数大于0的已发布文章; 分数相同时按浏览数和id倒序
+func (r *memoryEngagementRepository) rank(limit int, like func(engagementKey, time.Time) int64, comment func(Comment) int64) ([]PostScore, error) {
+	r.s.mu.RLock()
+	scores := make(map[uint]int64)
+	for key, at := range r.s.likes {
+		scores[key.postID] += like(key, at)
+	}
+	if comment != nil {
+		for _, c := range r.s.comments {
+			scores[c.PostID] += comment(c)
+		}
+	}
+	ranked := make([]PostScore, 0, len(scores))
+	views := make(map[uint]int64, len(scores))
+	for id, score := range scores {
+		p, ok := r.s.posts[id]
+		if !ok || p.Status != PostPublished || score == 0 {
+			continue
+		}
+		ranked = append(ranked, PostScore{PostID: id, Score: score})
+		views[id] = p.ViewCount
+	}
+	r.s.mu.RUnlock()
+
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if comment != nil && views[a.PostID] != views[b.PostID] {
+			return views[a.PostID] > views[b.PostID]
+		}
+		return a.PostID > b.PostID
+	})
+	return pageSlice(ranked, 0, limit), nil
+}
+
+type followKey struct {
+	followerID, followeeID uint
+}
+
+// 调用方需持有锁
+func (s *memoryStore) isFollowing(followerID, followeeID uint) bool {
+	_, ok := s.follows[followKey{followerID, followeeID}]
+	return ok
+}
+
+type memoryFollowRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryFollowRepository) Follow(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := followKey{followerID, followeeID}
+	if _, ok := r.s.follows[key]; ok {
+		return false, nil
+	}
+	r.s.follows[key] = time.Now()
+	return true, nil
+}
+
+func (r *memoryFollowRepository) Unfollow(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	key := followKey{followerID, followeeID}
+	if _, ok := r.s.follows[key]; !ok {
+		return false, nil
+	}
+	delete(r.s.follows, key)
+	return true, nil
+}
+
+func (r *memoryFollowRepository) ListFollowers(ctx context.Context, userID uint, page Page) ([]User, int64, error) {
+	return r.listUsers(page, func(k followKey) (uint, bool) { return k.followerID, k.followeeID == userID })
+}
+
+func (r *memoryFollowRepository) ListFollowing(ctx context.Context, userID uint, page Page) ([]User, int64, error) {
+	return r.listUsers(page, func(k followKey) (uint, bool) { return k.followeeID, k.followerID == userID })
+}
+
+// match返回关系另一端的用户id及是否匹配
+func (r *memoryFollowRepository) listUsers(page Page, match func(followKey) (uint, bool)) ([]User, int64, error) {
+	r.s.mu.RLock()
+	type followed struct {
+		user User
+		at   time.Time
+	}
+	items := make([]followed, 0)
+	for key, at := range r.s.follows {
+		id, ok := match(key)
+		if !ok {
+			continue
+		}
+		if u, ok := r.s.users[id]; ok {
+			items = append(items, followed{u, at})
+		}
+	}
+	r.s.mu.RUnlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].at.Equal(items[j].at) {
+			return items[i].at.After(items[j].at)
+		}
+		return items[i].user.ID > items[j].user.ID
+	})
+	users := make([]User, 0, len(items))
+	for _, f := range items {
+		users = append(users, f.user)
+	}
+	return pageSlice(users, page.Offset, page.Limit), int64(len(users)), nil
+}
+
+func (r *memoryFollowRepository) FollowerIDs(ctx context.Context, userID uint) ([]uint, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	ids := make([]uint, 0)
+	for key := range r.s.follows {
+		if key.followeeID == userID {
+			ids = append(ids, key.followerID)
+		}
+	}
+	return ids, nil
+}
+
+type memoryNotificationRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryNotificationRepository) Create(ctx context.Context, n *Notification) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n.ID = r.s.newID("notifications")
+	n.CreatedAt = time.Now()
+	r.s.notices[n.ID] = *n
+	return nil
+}
+
+func (r *memoryNotificationRepository) ListByUserID(ctx context.Context, userID uint, unreadOnly bool, page Page) ([]Notification, int64, error) {
+	r.s.mu.RLock()
+	notifications := make([]Notification, 0)
+	for _, n := range r.s.notices {
+		if n.UserID == userID && (!unreadOnly || n.ReadAt == nil) {
+			notifications = append(notifications, n)
+		}
+	}
+	r.s.mu.RUnlock()
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID > notifications[j].ID })
+
+	start := page.Offset
+	if page.After != nil {
+		start = sort.Search(len(notifications), func(i int) bool { return notifications[i].ID < page.After.ID })
+	}
+	return pageSlice(notifications, start, page.Limit), int64(len(notifications)), nil
+}
+
+func (r *memoryNotificationRepository) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var count int64
+	for _, n := range r.s.notices {
+		if n.UserID == userID && n.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memoryNotificationRepository) MarkRead(ctx context.Context, userID, id uint, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	n, ok := r.s.notices[id]
+	if !ok || n.UserID != userID {
+		return ErrNotFound
+	}
+	if n.ReadAt == nil {
+		n.ReadAt = &at
+		r.s.notices[id] = n
+	}
+	return nil
+}
+
+func (r *memoryNotificationRepository) MarkAllRead(ctx context.Context, userID uint, at time.Time) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var count int64
+	for id, n := range r.s.notices {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &at
+			r.s.notices[id] = n
+			count++
+		}
+	}
+	return count, nil
+}
+
+type memoryRevisionRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryRevisionRepository) Create(ctx context.Context, rev *PostRevision) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	revs := r.s.revs[rev.PostID]
+	rev.ID = r.s.newID("post_revisions")
+	rev.Version = len(revs) + 1
+	if rev.CreatedAt.IsZero() {
+		rev.CreatedAt = time.Now()
+	}
+	r.s.revs[rev.PostID] = append(revs, *rev)
+	return nil
+}
+
+func (r *memoryRevisionRepository) GetByVersion(ctx context.Context, postID uint, version int) (*PostRevision, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	revs := r.s.revs[postID]
+	if version < 1 || version > len(revs) {
+		return nil, ErrNotFound
+	}
+	rev := revs[version-1]
+	return &rev, nil
+}
+
+func (r *memoryRevisionRepository) Latest(ctx context.Context, postID uint) (*PostRevision, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	revs := r.s.revs[postID]
+	if len(revs) == 0 {
+		return nil, ErrNotFound
+	}
+	rev := revs[len(revs)-1]
+	return &rev, nil
+}
+
+func (r *memoryRevisionRepository) ListByPostID(ctx context.Context, postID uint, page Page) ([]PostRevision, int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	revs := slices.Clone(r.s.revs[postID])
+	slices.Reverse(revs)
+	return pageSlice(revs, page.Offset, page.Limit), int64(len(revs)), nil
+}
+
+type memoryAttachmentRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryAttachmentRepository) Create(ctx context.Context, a *Attachment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, f := range r.s.files {
+		if f.FileKey == a.FileKey {
+			return ErrDuplicate
+		}
+	}
+	a.ID = r.s.newID("attachments")
+	a.CreatedAt = time.Now()
+	r.s.files[a.ID] = *a
+	return nil
+}
+
+func (r *memoryAttachmentRepository) GetByID(ctx context.Context, id uint) (*Attachment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	a, ok := r.s.files[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &a, nil
+}
+
+func (r *memoryAttachmentRepository) GetByKey(ctx context.Context, key string) (*Attachment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, a := range r.s.files {
+		if a.FileKey == key || (a.ThumbKey != "" && a.ThumbKey == key) {
+			return &a, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAttachmentRepository) ListByPostID(ctx context.Context, postID uint) ([]Attachment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	attachments := make([]Attachment, 0)
+	for _, a := range r.s.files {
+		if a.PostID == postID {
+			attachments = append(attachments, a)
+		}
+	}
+	sortByID(attachments, func(a Attachment) uint { return a.ID })
+	return attachments, nil
+}
+
+func (r *memoryAttachmentRepository) Delete(ctx context.Context, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.files[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.s.files, id)
+	return nil
+}
+
+type memoryCommentRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryCommentRepository) Create(ctx context.Context, comment *Comment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	now := time.Now()
+	comment.ID = r.s.newID("comments")
+	comment.CreatedAt, comment.UpdatedAt = now, now
+	r.s.comments[comment.ID] = *comment
+	return nil
+}
+
+func (r *memoryCommentRepository) GetByID(ctx context.Context, id uint) (*Comment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.comments[id]
+	if !ok || c.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return &c, nil
+}
+
+func (r *memoryCommentRepository) Update(ctx context.Context, comment *Comment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, ok := r.s.comments[comment.ID]
+	if !ok || c.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	c.Content, c.EditedAt = comment.Content, comment.EditedAt
+	c.UpdatedAt = time.Now()
+	r.s.comments[c.ID] = c
+	comment.UpdatedAt = c.UpdatedAt
+	return nil
+}
+
+// 软删除, 与gorm保持一致
+func (r *memoryCommentRepository) Delete(ctx context.Context, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, ok := r.s.comments[id]
+	if !ok || c.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	c.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.s.comments[id] = c
+	return nil
+}
+
+func (r *memoryCommentRepository) ListByPostID(ctx context.Context, postID uint, page Page) ([]Comment, int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	comments := make([]Comment, 0)
+	for _, c := range r.s.comments {
+		if c.PostID == postID && !c.DeletedAt.Valid {
+			comments = append(comments, c)
+		}
+	}
+	sortByID(comments, func(c Comment) uint { return c.ID })
+
+	start := page.Offset
+	if page.After != nil {
+		start = sort.Search(len(comments), func(i int) bool { return comments[i].ID > page.After.ID })
+	}
+	return pageSlice(comments, start, page.Limit), int64(len(comments)), nil
+}
+
+func (r *memoryCommentRepository) ListThread(ctx context.Context, postID uint) ([]Comment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	comments := make([]Comment, 0)
+	for _, c := range r.s.comments {
+		if c.PostID == postID {
+			comments = append(comments, c)
+		}
+	}
+	sortByID(comments, func(c Comment) uint { return c.ID })
+	return comments, nil
+}
+
+func (r *memoryCommentRepository) ListRoots(ctx context.Context, postID uint, page Page) ([]Comment, int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	hasReplies := make(map[uint]bool)
+	for _, c := range r.s.comments {
+		if c.ParentID != nil {
+			hasReplies[*c.ParentID] = true
+		}
+	}
+	roots := make([]Comment, 0)
+	for _, c := range r.s.comments {
+		if c.PostID == postID && c.ParentID == nil && (!c.DeletedAt.Valid || hasReplies[c.ID]) {
+			roots = append(roots, c)
+		}
+	}
+	sortByID(roots, func(c Comment) uint { return c.ID })
+
+	start := page.Offset
+	if page.After != nil {
+		start = sort.Search(len(roots), func(i int) bool { return roots[i].ID > page.After.ID })
+	}
+	return pageSlice(roots, start, page.Limit), int64(len(roots)), nil
+}
+
+func (r *memoryCommentRepository) ListReplies(ctx context.Context, parentIDs []uint) ([]Comment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	parents := make(map[uint]bool, len(parentIDs))
+	for _, id := range parentIDs {
+		parents[id] = true
+	}
+	comments := make([]Comment, 0)
+	for _, c := range r.s.comments {
+		if c.ParentID != nil && parents[*c.ParentID] {
+			comments = append(comments, c)
+		}
+	}
+	sortByID(comments, func(c Comment) uint { return c.ID })
+	return comments, nil
+}
+
+func (r *memoryCommentRepository) CountByPostIDs(ctx context.Context, postIDs []uint) (map[uint]int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	counts := make(map[uint]int64, len(postIDs))
+	for _, id := range postIDs {
+		counts[id] = 0
+	}
+	for _, c := range r.s.comments {
+		if _, ok := counts[c.PostID]; ok && !c.DeletedAt.Valid {
+			counts[c.PostID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *memoryCommentRepository) ListAfterID(ctx context.Context, afterID uint, limit int) ([]Comment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	comments := make([]Comment, 0)
+	for _, c := range r.s.comments {
+		if c.ID > afterID && !c.DeletedAt.Valid {
+			comments = append(comments, c)
+		}
+	}
+	sortByID(comments, func(c Comment) uint { return c.ID })
+	return pageSlice(comments, 0, limit), nil
+}
+
+type memoryTagRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryTagRepository) Create(ctx context.Context, tag *Tag) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, t := range r.s.tags {
+		if t.Slug == tag.Slug {
+			return ErrDuplicate
+		}
+	}
+	tag.ID = r.s.newID("tags")
+	tag.CreatedAt = time.Now()
+	r.s.tags[tag.ID] = *tag
+	return nil
+}
+
+func (r *memoryTagRepository) GetBySlugs(ctx context.Context, slugs []string) ([]Tag, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	tags := make([]Tag, 0, len(slugs))
+	for _, t := range r.s.tags {
+		if slices.Contains(slugs, t.Slug) {
+			tags = append(tags, t)
+		}
+	}
+	return tags, nil
+}
+
+func (r *memoryTagRepository) Delete(ctx context.Context, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.tags[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.s.tags, id)
+	for postID, ids := range r.s.postTags {
+		r.s.postTags[postID] = slices.DeleteFunc(ids, func(t uint) bool { return t == id })
+	}
+	return nil
+}
+
+func (r *memoryTagRepository) Cloud(ctx context.Context, limit int) ([]TagCount, error) {
+	r.s.mu.RLock()
+	counts := make(map[uint]int64, len(r.s.tags))
+	for postID, ids := range r.s.postTags {
+		if p, ok := r.s.posts[postID]; !ok || p.Status != PostPublished {
+			continue
+		}
+		for _, id := range ids {
+			counts[id]++
+		}
+	}
+	tags := make([]TagCount, 0, len(r.s.tags))
+	for _, t := range r.s.tags {
+		tags = append(tags, TagCount{Tag: t, Count: counts[t.ID]})
+	}
+	r.s.mu.RUnlock()
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Slug < tags[j].Slug
+	})
+	return pageSlice(tags, 0, limit), nil
+}
+
+func (r *memoryTagRepository) SetPostTags(ctx context.Context, postID uint, tagIDs []uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.postTags[postID] = slices.Clone(tagIDs)
+	return nil
+}
+
+func (r *memoryTagRepository) ListByPostIDs(ctx context.Context, postIDs []uint) (map[uint][]Tag, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	result := make(map[uint][]Tag, len(postIDs))
+	for _, postID := range postIDs {
+		for _, id := range r.s.postTags[postID] {
+			result[postID] = append(result[postID], r.s.tags[id])
+		}
+		slices.SortFunc(result[postID], func(a, b Tag) int { return strings.Compare(a.Slug, b.Slug) })
+	}
+	return result, nil
+}
+
+type memoryCategoryRepository struct {
+	s *memoryStore
+}
+
+// 校验slug唯一, 调用方需持有写锁
+func (r *memoryCategoryRepository) slugTaken(slug string, id uint) bool {
+	for _, c := range r.s.cats {
+		if c.Slug == slug && c.ID != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryCategoryRepository) Create(ctx context.Context, category *Category) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.slugTaken(category.Slug, 0) {
+		return ErrDuplicate
+	}
+	now := time.Now()
+	category.ID = r.s.newID("categories")
+	category.CreatedAt, category.UpdatedAt = now, now
+	r.s.cats[category.ID] = *category
+	return nil
+}
+
+func (r *memoryCategoryRepository) GetByID(ctx context.Context, id uint) (*Category, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.cats[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &c, nil
+}
+
+func (r *memoryCategoryRepository) Update(ctx context.Context, category *Category) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	c, ok := r.s.cats[category.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if r.slugTaken(category.Slug, category.ID) {
+		return ErrDuplicate
+	}
+	c.Name, c.Slug, c.Description, c.ParentID = category.Name, category.Slug, category.Description, category.ParentID
+	c.UpdatedAt = time.Now()
+	r.s.cats[c.ID] = c
+	return nil
+}
+
+func (r *memoryCategoryRepository) Delete(ctx context.Context, id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.cats[id]; !ok {
+		return ErrNotFound
+	}
+	delete(r.s.cats, id)
+	for pid, p := range r.s.posts {
+		if p.CategoryID != nil && *p.CategoryID == id {
+			p.CategoryID = nil
+			r.s.posts[pid] = p
+		}
+	}
+	return nil
+}
+
+func (r *memoryCategoryRepository) List(ctx context.Context) ([]Category, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	categories := make([]Category, 0, len(r.s.cats))
+	for _, c := range r.s.cats {
+		categories = append(categories, c)
+	}
+	sortByID(categories, func(c Category) uint { return c.ID })
+	return categories, nil
+}
+
+type memoryTokenRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(ctx context.Context, token *RefreshToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, t := range r.s.refresh {
+		if t.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	token.ID = r.s.newID("refresh_tokens")
+	token.CreatedAt = time.Now()
+	r.s.refresh[token.ID] = *token
+	return nil
+}
+
+func (r *memoryTokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, t := range r.s.refresh {
+		if t.TokenHash == tokenHash {
+			return &t, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTokenRepository) RevokeRefreshToken(ctx context.Context, id uint, at time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	t, ok := r.s.refresh[id]
+	if !ok || t.RevokedAt != nil {
+		return false, nil
+	}
+	t.RevokedAt = &at
+	r.s.refresh[id] = t
+	return true, nil
+}
+
+func (r *memoryTokenRepository) RevokeRefreshFamily(ctx context.Context, familyID string, at time.Time) ([]RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var tokens []RefreshToken
+	for id, t := range r.s.refresh {
+		if t.FamilyID != familyID {
+			continue
+		}
+		if t.RevokedAt == nil {
+			t.RevokedAt = &at
+			r.s.refresh[id] = t
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+func (r *memoryTokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.revoked[jti] = expiresAt
+	return nil
+}
+
+func (r *memoryTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	_, ok := r.s.revoked[jti]
+	return ok, nil
+}
+
+func (r *memoryTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uint, at time.Time) ([]RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var tokens []RefreshToken
+	for id, t := range r.s.refresh {
+		if t.UserID != userID || t.RevokedAt != nil {
+			continue
+		}
+		tokens = append(tokens, t)
+		t.RevokedAt = &at
+		r.s.refresh[id] = t
+	}
+	return tokens, nil
+}
+
+func (r *memoryTokenRepository) CreateUserToken(ctx context.Context, token *UserToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, t := range r.s.userToks {
+		if t.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	token.ID = r.s.newID("user_tokens")
+	token.CreatedAt = time.Now()
+	r.s.userToks[token.ID] = *token
+	return nil
+}
+
+func (r *memoryTokenRepository) ConsumeUserToken(ctx context.Context, purpose UserTokenPurpose, tokenHash string, now time.Time) (*UserToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, t := range r.s.userToks {
+		if t.TokenHash != tokenHash || t.Purpose != purpose {
+			continue
+		}
+		if t.UsedAt != nil || !now.Before(t.ExpiresAt) {
+			return nil, ErrNotFound
+		}
+		t.UsedAt = &now
+		r.s.userToks[id] = t
+		return &t, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryTokenRepository) RevokeUserTokens(ctx context.Context, userID uint, purpose UserTokenPurpose, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, t := range r.s.userToks {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &at
+			r.s.userToks[id] = t
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, t := range r.s.userToks {
+		if t.ExpiresAt.Before(now) {
+			delete(r.s.userToks, id)
+		}
+	}
+	for jti, exp := range r.s.revoked {
+		if exp.Before(now) {
+			delete(r.s.revoked, jti)
+		}
+	}
+	for id, t := range r.s.refresh {
+		if t.ExpiresAt.Before(now) {
+			delete(r.s.refresh, id)
+		}
+	}
+	return nil
+}
+
+type memoryNonceRepository struct {
+	s *memoryStore
+}
+
+func (r *memoryNonceRepository) CreateNonce(ctx context.Context, nonce *SiweNonce) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.nonces[nonce.Nonce]; ok {
+		return ErrDuplicate
+	}
+	r.s.nonces[nonce.Nonce] = nonce.ExpiresAt
+	return nil
+}
+
+func (r *memoryNonceRepository) ConsumeNonce(ctx context.Context, nonce string, now time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	exp, ok := r.s.nonces[nonce]
+	if !ok || !now.Before(exp) {
+		return false, nil
+	}
+	delete(r.s.nonces, nonce)
+	return true, nil
+}
+
+func (r *memoryNonceRepository) DeleteExpiredNonces(ctx context.Context, now time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for nonce, exp := range r.s.nonces {
+		if !now.Before(exp) {
+			delete(r.s.nonces, nonce)
+		}
+	}
+	return nil
+}
+
+// 按id升序排序, map遍历顺序不固定
+func sortByID[T any](items []T, id func(T) uint) {
+	sort.Slice(items, func(i, j int) bool { return id(items[i]) < id(items[j]) })
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 分别使用内存存储和迁移后的SQLite运行同一个测试
+func forEachRepositories(t *testing.T, test func(t *testing.T, repos *Repositories)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryRepositories())
+	})
+	t.Run("gorm", func(t *testing.T) {
+		db := openTestDB(t)
+		migrator, err := NewMigrator(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := migrator.Up(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+		test(t, NewGormRepositories(db))
+	})
+}
+
+func TestConsumeUserToken(t *testing.T) {
+	forEachRepositories(t, func(t *testing.T, repos *Repositories) {
+		ctx := context.Background()
+		now := time.Now()
+		user := &User{Username: "alice", Password: "hash", Email: "alice@example.com", Role: RoleAuthor}
+		if err := repos.Users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		create := func(hash string, purpose UserTokenPurpose, expiresAt time.Time) {
+			t.Helper()
+			err := repos.Tokens.CreateUserToken(ctx, &UserToken{
+				UserID: user.ID, Purpose: purpose, TokenHash: hash, Email: user.Email, ExpiresAt: expiresAt,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		create("once", TokenVerifyEmail, now.Add(time.Hour))
+		got, err := repos.Tokens.ConsumeUserToken(ctx, TokenVerifyEmail, "once", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.UserID != user.ID || got.Email != "alice@example.com" || got.UsedAt == nil {
+			t.Fatalf("unexpected token: %+v", got)
+		}
+		if _, err := repos.Tokens.ConsumeUserToken(ctx, TokenVerifyEmail, "once", now); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("consume twice err = %v, want ErrNotFound", err)
+		}
+
+		// 用途不同的token不能使用, 也不会被消耗
+		create("reset", TokenResetPassword, now.Add(time.Hour))
+		if _, err := repos.Tokens.ConsumeUserToken(ctx, TokenVerifyEmail, "reset", now); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("consume with another purpose err = %v, want ErrNotFound", err)
+		}
+		if _, err := repos.Tokens.ConsumeUserToken(ctx, TokenResetPassword, "reset", now); err != nil {
+			t.Fatal(err)
+		}
+
+		create("expired", TokenResetPassword, now.Add(-time.Second))
+		if _, err := repos.Tokens.ConsumeUserToken(ctx, TokenResetPassword, "expired", now); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("consume expired err = %v, want ErrNotFound", err)
+		}
+		create("deadline", TokenResetPassword, now)
+		if _, err := repos.Tokens.ConsumeUserToken(ctx, TokenResetPassword, "deadline", now); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("consume at expires_at err = %v, want ErrNotFound", err)
+		}
+
+		// 发送验证链接后修改了邮箱, 旧链接不能验证新邮箱
+		create("old-email", TokenVerifyEmail, now.Add(time.Hour))
+		user.Email = "new@example.com"
+		if err := repos.Users.UpdateProfile(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		got, err = repos.Tokens.ConsumeUserToken(ctx, TokenVerifyEmail, "old-email", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repos.Users.SetEmailVerified(ctx, got.UserID, got.Email, now); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("verify old email err = %v, want ErrNotFound", err)
+		}
+		u, err := repos.Users.GetByID(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.EmailVerifiedAt != nil {
+			t.Fatalf("new email verified by a token sent to the old email")
+		}
+
+		// 撤销后的token不能使用
+		create("revoked", TokenVerifyEmail, now.Add(time.Hour))
+		if err := repos.Tokens.RevokeUserTokens(ctx, user.ID, TokenVerifyEmail, now); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repos.Tokens.ConsumeUserToken(ctx, TokenVerifyEmail, "revoked", now); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("consume revoked err = %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 文章修订, 每次修改标题或正文后保存一份快照
+type PostRevision struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	PostID    uint      `gorm:"uniqueIndex:idx_post_version" json:"post_id"`
+	Version   int       `gorm:"uniqueIndex:idx_post_version" json:"version"` // 从1开始, 由存储层分配
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	AuthorID  uint      `json:"author_id"` // 本次修改的用户
+	Note      string    `gorm:"size:64" json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 解析路径中的修订版本号
+func validateRevision(c *gin.Context) (int, bool) {
+	v, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || v <= 0 {
+		writeProblem(c, invalidParam("rev", "revision must be a positive integer"))
+		return 0, false
+	}
+	return v, true
+}
+
+// 修订历史, 按版本倒序, 不返回正文
+func (h *Handler) ListPostRevisionsHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		writeProblem(c, err)
+		return
+	}
+	if page.After != nil {
+		writeProblem(c, invalidParam("cursor", "revisions do not support cursor"))
+		return
+	}
+	actor, ok := getCurrentActor(c)
+	if !ok {
+		return
+	}
+
+	revisions, total, err := h.posts.Revisions(c.Request.Context(), actor, pid, page)
+	if err != nil {
+		respondError(c, "ListPostRevisions", err)
+		return
+	}
+
+	items := make([]gin.H, 0, len(revisions))
+	for _, rev := range revisions {
+		items = append(items, gin.H{
+			"version":    rev.Version,
+			"title":      rev.Title,
+			"author_id":  rev.AuthorID,
+			"note":       rev.Note,
+			"created_at": rev.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"revisions": items,
+		"meta": PageMeta{
+			Total:    total,
+			Page:     pageNum,
+			PageSize: page.Limit,
+			HasMore:  int64(page.Offset+len(revisions)) < total,
+		},
+	})
+}
+
+func (h *Handler) GetPostRevisionHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	version, ok := validateRevision(c)
+	if !ok {
+		return
+	}
+	actor, ok := getCurrentActor(c)
+	if !ok {
+		return
+	}
+
+	rev, err := h.posts.Revision(c.Request.Context(), actor, pid, version)
+	if err != nil {
+		respondError(c, "GetPostRevision", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"revision": rev,
+	})
+}
+
+// 两个版本之间的unified diff, to默认为最新版本, from默认为to的上一版本
+func (h *Handler) DiffPostRevisionsHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	var versions [2]int
+	for i, field := range []string{"from", "to"} {
+		v := c.Query(field)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeProblem(c, invalidParam(field, field+" must be a positive integer"))
+			return
+		}
+		versions[i] = n
+	}
+	actor, ok := getCurrentActor(c)
+	if !ok {
+		return
+	}
+
+	diff, err := h.posts.DiffRevisions(c.Request.Context(), actor, pid, versions[0], versions[1])
+	if err != nil {
+		respondError(c, "DiffPostRevisions", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"from":    diff.From,
+		"to":      diff.To,
+		"diff":    diff.Diff,
+	})
+}
+
+// 回滚到指定版本, 回滚本身也会生成一个新版本
+func (h *Handler) RollbackPostHandler(c *gin.Context) {
+	pid, ok := validatePostID(c)
+	if !ok {
+		return
+	}
+	version, ok := validateRevision(c)
+	if !ok {
+		return
+	}
+	actor, ok := getCurrentActor(c)
+	if !ok {
+		return
+	}
+
+	post, err := h.posts.Rollback(c.Request.Context(), actor, pid, version)
+	if err != nil {
+		respondError(c, "RollbackPost", err)
+		return
+	}
+
+	requestLogger(c).Info("RollbackPost successfully", zap.Uint("post_id", post.ID), zap.Int("version", version))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"post":    postJSON(post),
+	})
+}
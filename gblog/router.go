@@ -0,0 +1,153 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// http处理器, 依赖的服务通过NewHandler注入
+type Handler struct {
+	users         *UserService
+	posts         *PostService
+	comments      *CommentService
+	auth          *AuthService
+	siwe          *SiweService
+	search        *SearchService
+	taxonomy      *TaxonomyService
+	attachments   *AttachmentService
+	engagement    *EngagementService
+	feed          *FeedService
+	notifications *NotificationService
+	rateLimits    RateLimitStore
+	account       *AccountService
+	wsUpgrader    *websocket.Upgrader
+}
+
+func NewHandler(svc *Services) *Handler {
+	return &Handler{
+		users:         svc.Users,
+		posts:         svc.Posts,
+		comments:      svc.Comments,
+		auth:          svc.Auth,
+		siwe:          svc.Siwe,
+		search:        svc.Search,
+		taxonomy:      svc.Taxonomy,
+		attachments:   svc.Attachments,
+		engagement:    svc.Engagement,
+		feed:          svc.Feed,
+		notifications: svc.Notifications,
+		rateLimits:    svc.RateLimits,
+		account:       svc.Account,
+	}
+}
+
+// 注册路由
+func NewRouter(h *Handler, cfg *Config) (*gin.Engine, error) {
+	// 访问日志由RequestMiddleware记录, 不使用gin.Default中的Logger; Recovery在其后, panic时仍会记录500
+	r := gin.New()
+	r.Use(RequestMiddleware(), RecoveryMiddleware())
+	r.NoRoute(func(c *gin.Context) { writeProblem(c, ErrRouteNotFound) })
+	registerValidation()
+	// 未配置时不信任任何代理, 直接使用连接的对端地址, 防止伪造X-Forwarded-For绕过按IP限流
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return nil, err
+	}
+	h.wsUpgrader = newWSUpgrader(cfg.Server.AllowedOrigins)
+
+	// 认证接口按IP限流, 登录另外按用户名限流
+	ipLimit := RateLimitMiddleware(h.rateLimits, "ip", cfg.RateLimit.IP, clientIPKey)
+	accountLimit := RateLimitMiddleware(h.rateLimits, "account", cfg.RateLimit.Account, formUsernameKey)
+	r.POST("/register", ipLimit, PasswordEncrypt(), h.registerHandler)
+	r.POST("/login", ipLimit, accountLimit, h.loginHandler)
+	r.POST("/refresh", ipLimit, h.RefreshHandler)
+	r.GET("/siwe/nonce", ipLimit, h.SiweNonceHandler)
+	r.POST("/siwe/verify", ipLimit, h.SiweVerifyHandler)
+	r.POST("/email/verify", ipLimit, h.VerifyEmailHandler)
+	// 找回密码另外按邮箱限流, 防止向同一邮箱频繁发信
+	r.POST("/password/forgot", ipLimit, RateLimitMiddleware(h.rateLimits, "email", cfg.RateLimit.Account, formEmailKey), h.ForgotPasswordHandler)
+	r.POST("/password/reset", ipLimit, h.ResetPasswordHandler)
+	r.GET(filesPath+"*key", QueryTokenMiddleware(), OptionalJwtAuthMiddleware(h.auth), h.ServeFileHandler)
+
+	auth := r.Group("/auth")
+	auth.Use(JwtAuthMiddleware(h.auth))
+
+	auth.POST("/logout", h.LogoutHandler)
+	auth.PUT("/password", h.ChangePasswordHandler)
+	auth.POST("/email/verification", ipLimit, h.ResendVerificationHandler)
+	auth.POST("/siwe/link", h.SiweLinkHandler)
+	auth.GET("/me", h.GetMeHandler)
+	auth.PUT("/me", h.UpdateMeHandler)
+	auth.POST("/me/delete", h.DeleteMeHandler)
+
+	auth.GET("/search", h.SearchHandler)
+
+	auth.GET("/tags", h.ListTagsHandler)
+	auth.POST("/tags", RequirePermission(PermTaxonomyManage), h.CreateTagHandler)
+	auth.DELETE("/tags/:tid", RequirePermission(PermTaxonomyManage), h.DeleteTagHandler)
+	auth.GET("/categories", h.ListCategoriesHandler)
+	auth.POST("/categories", RequirePermission(PermTaxonomyManage), h.CreateCategoryHandler)
+	auth.PUT("/categories/:cid", RequirePermission(PermTaxonomyManage), h.UpdateCategoryHandler)
+	auth.DELETE("/categories/:cid", RequirePermission(PermTaxonomyManage), h.DeleteCategoryHandler)
+
+	auth.GET("/posts", h.ListPostsHandler)
+	auth.GET("/posts/most-liked", h.MostLikedPostsHandler)
+	auth.GET("/posts/trending", h.TrendingPostsHandler)
+	auth.GET("/bookmarks", h.ListBookmarksHandler)
+	auth.GET("/feed", h.FeedHandler)
+
+	auth.GET("/users/:id", h.GetProfileHandler)
+	auth.PUT("/users/:id/follow", h.FollowUserHandler)
+	auth.DELETE("/users/:id/follow", h.UnfollowUserHandler)
+	auth.GET("/users/:id/followers", h.ListFollowersHandler)
+	auth.GET("/users/:id/following", h.ListFollowingHandler)
+
+	auth.GET("/notifications", h.ListNotificationsHandler)
+	auth.GET("/notifications/unread-count", h.UnreadNotificationCountHandler)
+	auth.POST("/notifications/read-all", h.MarkAllNotificationsReadHandler)
+	auth.POST("/notifications/:nid/read", h.MarkNotificationReadHandler)
+	auth.POST("/post", RequirePermission(PermPostCreate), h.CreatePostHandler)
+	auth.PUT("/post/:id", h.UpdatePostHandler)
+	auth.GET("/post/:id", h.GetPostHandler)
+	auth.DELETE("/post/:id", h.DeletePostHandler)
+	auth.GET("/post/:id/revisions", h.ListPostRevisionsHandler)
+	auth.GET("/post/:id/revisions/:rev", h.GetPostRevisionHandler)
+	auth.POST("/post/:id/revisions/:rev/rollback", h.RollbackPostHandler)
+	auth.GET("/post/:id/diff", h.DiffPostRevisionsHandler)
+	auth.POST("/post/:id/attachments", h.UploadAttachmentHandler)
+	auth.GET("/post/:id/attachments", h.ListAttachmentsHandler)
+	auth.DELETE("/post/:id/attachments/:aid", h.DeleteAttachmentHandler)
+	auth.PUT("/post/:id/like", h.LikePostHandler)
+	auth.DELETE("/post/:id/like", h.UnlikePostHandler)
+	auth.PUT("/post/:id/bookmark", h.BookmarkPostHandler)
+	auth.DELETE("/post/:id/bookmark", h.UnbookmarkPostHandler)
+
+	auth.POST("/post/:id/comment", RequirePermission(PermCommentCreate), h.CreateCommentHandler)
+	auth.GET("/post/:id/comments", h.GetCommentsByPostID)
+	auth.GET("/post/:id/comment/tree", h.GetCommentTreeHandler)
+	auth.PUT("/post/:id/comment/:cid", h.UpdateCommentHandler)
+	auth.DELETE("/post/:id/comment/:cid", h.DeleteCommentHandler)
+
+	// 长连接接口, token也可以放在access_token参数中
+	stream := r.Group("/auth", QueryTokenMiddleware(), JwtAuthMiddleware(h.auth))
+	stream.GET("/notifications/stream", h.NotificationStreamHandler)
+	stream.GET("/post/:id/comments/ws", h.CommentStreamHandler)
+
+	admin := auth.Group("/admin", RequirePermission(PermUserManage))
+	admin.GET("/users", h.ListUsersHandler)
+	admin.PUT("/users/:id/role", h.SetUserRoleHandler)
+	admin.PUT("/users/:id/suspension", h.SuspendUserHandler)
+	admin.DELETE("/users/:id/suspension", h.UnsuspendUserHandler)
+
+	// 接口文档, 在校验之后注册, 不出现在文档中
+	if err := checkAPIRoutes(r.Routes(), apiRoutes); err != nil {
+		return nil, err
+	}
+	spec, err := openAPIHandler(buildOpenAPI(apiRoutes))
+	if err != nil {
+		return nil, err
+	}
+	r.GET("/openapi.json", spec)
+	r.GET("/docs", swaggerUIHandler)
+
+	return r, nil
+}
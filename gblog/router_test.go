@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+// 使用内存存储启动完整的路由
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv, _ := newTestServerWithServices(t)
+	return srv
+}
+
+// 同时返回服务, 用于直接准备测试数据, 如修改用户角色
+func newTestServerWithServices(t *testing.T) (*httptest.Server, *Services) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := defaultConfig()
+	cfg.Database.Driver = memoryDriver
+	cfg.JWT.Secret = testJWTSecret
+	cfg.Upload.Dir = t.TempDir()
+	cfg.Mail.Driver = mailDriverFile
+	cfg.Mail.Dir = t.TempDir()
+	// 所有请求都来自127.0.0.1
+	cfg.RateLimit.IP.Burst = 1000
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	jwtConf = cfg.JWT
+
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+	var err error
+	if repos.Blobs, err = NewBlobStore(ctx, cfg.Upload); err != nil {
+		t.Fatal(err)
+	}
+	repos.Broker = NewMemoryBroker()
+	repos.RateLimits = NewMemoryRateLimitStore()
+	if repos.Mailer, err = NewMailer(cfg.Mail); err != nil {
+		t.Fatal(err)
+	}
+
+	services := NewServices(repos, &cfg)
+	r, err := NewRouter(NewHandler(services), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, services
+}
+
+type testResponse struct {
+	status int
+	body   map[string]any
+}
+
+// 按点分隔的路径取json中的值, 如 post.id
+func (r testResponse) get(path string) any {
+	var v any = r.body
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func (r testResponse) id(path string) uint {
+	f, _ := r.get(path).(float64)
+	return uint(f)
+}
+
+// 发送表单请求, token为空时不带Authorization头
+func doRequest(t *testing.T, srv *httptest.Server, method, path, token string, form url.Values) testResponse {
+	t.Helper()
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequest(method, srv.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return sendRequest(t, req)
+}
+
+func sendRequest(t *testing.T, req *http.Request) testResponse {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := testResponse{status: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(&r.body); err != nil {
+		t.Fatalf("%s %s: decode response: %v", req.Method, req.URL.Path, err)
+	}
+	return r
+}
+
+func expectStatus(t *testing.T, r testResponse, status int) {
+	t.Helper()
+	if r.status != status {
+		t.Fatalf("status = %d, want %d, body = %v", r.status, status, r.body)
+	}
+}
+
+// 注册接口只接受multipart表单
+func register(t *testing.T, srv *httptest.Server, fields map[string]string) testResponse {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/register", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return sendRequest(t, req)
+}
+
+// 注册用户并返回access token
+func registerUser(t *testing.T, srv *httptest.Server, username string) string {
+	t.Helper()
+	r := register(t, srv, map[string]string{"username": username, "password": "password123"})
+	expectStatus(t, r, http.StatusOK)
+	return r.get("token").(string)
+}
+
+func createPost(t *testing.T, srv *httptest.Server, token, title string) uint {
+	t.Helper()
+	r := doRequest(t, srv, "POST", "/auth/post", token, url.Values{"title": {title}, "content": {"# " + title}, "status": {"published"}})
+	expectStatus(t, r, http.StatusOK)
+	return r.id("post.id")
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	srv := newTestServer(t)
+
+	r := register(t, srv, map[string]string{"username": "alice", "password": "password123"})
+	expectStatus(t, r, http.StatusOK)
+	if r.get("token") == "" || r.get("refresh_token") == "" {
+		t.Fatalf("register returned no tokens: %v", r.body)
+	}
+
+	r = register(t, srv, map[string]string{"username": "alice", "password": "password456"})
+	expectStatus(t, r, http.StatusConflict)
+	if code := r.get("code"); code != "user_exists" {
+		t.Fatalf("code = %v, want user_exists", code)
+	}
+
+	r = doRequest(t, srv, "POST", "/login", "", url.Values{"username": {"alice"}, "password": {"wrong-password"}})
+	expectStatus(t, r, http.StatusUnauthorized)
+	r = doRequest(t, srv, "POST", "/login", "", url.Values{"username": {"nobody"}, "password": {"password123"}})
+	expectStatus(t, r, http.StatusUnauthorized)
+
+	r = doRequest(t, srv, "POST", "/login", "", url.Values{"username": {"alice"}, "password": {"password123"}})
+	expectStatus(t, r, http.StatusOK)
+	if name := r.get("user.username"); name != "alice" {
+		t.Fatalf("user.username = %v, want alice", name)
+	}
+	token := r.get("token").(string)
+
+	r = doRequest(t, srv, "GET", "/auth/me", token, nil)
+	expectStatus(t, r, http.StatusOK)
+	if name := r.get("user.username"); name != "alice" {
+		t.Fatalf("me.username = %v, want alice", name)
+	}
+
+	expectStatus(t, doRequest(t, srv, "GET", "/auth/me", "", nil), http.StatusUnauthorized)
+	expectStatus(t, doRequest(t, srv, "GET", "/auth/me", "not-a-token", nil), http.StatusUnauthorized)
+}
+
+func TestPostCRUD(t *testing.T) {
+	srv := newTestServer(t)
+	token := registerUser(t, srv, "alice")
+
+	expectStatus(t, doRequest(t, srv, "POST", "/auth/post", token, url.Values{"content": {"no title"}}), http.StatusBadRequest)
+
+	id := createPost(t, srv, token, "hello")
+	path := fmt.Sprintf("/auth/post/%d", id)
+
+	r := doRequest(t, srv, "GET", path, token, nil)
+	expectStatus(t, r, http.StatusOK)
+	if title := r.get("post.title"); title != "hello" {
+		t.Fatalf("title = %v, want hello", title)
+	}
+
+	r = doRequest(t, srv, "PUT", path, token, url.Values{"title": {"hello again"}})
+	expectStatus(t, r, http.StatusOK)
+	r = doRequest(t, srv, "GET", path, token, nil)
+	expectStatus(t, r, http.StatusOK)
+	if title := r.get("post.title"); title != "hello again" {
+		t.Fatalf("title after update = %v, want hello again", title)
+	}
+
+	r = doRequest(t, srv, "GET", "/auth/posts", token, nil)
+	expectStatus(t, r, http.StatusOK)
+	if posts, _ := r.get("posts").([]any); len(posts) != 1 {
+		t.Fatalf("len(posts) = %d, want 1", len(posts))
+	}
+
+	expectStatus(t, doRequest(t, srv, "DELETE", path, token, nil), http.StatusOK)
+	expectStatus(t, doRequest(t, srv, "GET", path, token, nil), http.StatusNotFound)
+	expectStatus(t, doRequest(t, srv, "GET", "/auth/post/abc", token, nil), http.StatusBadRequest)
+}
+
+func TestCommentCRUD(t *testing.T) {
+	srv := newTestServer(t)
+	token := registerUser(t, srv, "alice")
+	pid := createPost(t, srv, token, "hello")
+	base := fmt.Sprintf("/auth/post/%d", pid)
+
+	r := doRequest(t, srv, "POST", base+"/comment", token, url.Values{"content": {"first"}})
+	expectStatus(t, r, http.StatusOK)
+	cid := r.id("comment.id")
+	r = doRequest(t, srv, "POST", base+"/comment", token, url.Values{"content": {"reply"}, "parent_id": {fmt.Sprint(cid)}})
+	expectStatus(t, r, http.StatusOK)
+	if parent := r.id("comment.parent_id"); parent != cid {
+		t.Fatalf("parent_id = %d, want %d", parent, cid)
+	}
+	expectStatus(t, doRequest(t, srv, "POST", "/auth/post/999/comment", token, url.Values{"content": {"x"}}), http.StatusNotFound)
+
+	r = doRequest(t, srv, "GET", base+"/comments", token, nil)
+	expectStatus(t, r, http.StatusOK)
+	if comments, _ := r.get("comments").([]any); len(comments) != 2 {
+		t.Fatalf("len(comments) = %d, want 2", len(comments))
+	}
+
+	commentPath := fmt.Sprintf("%s/comment/%d", base, cid)
+	r = doRequest(t, srv, "PUT", commentPath, token, url.Values{"content": {"edited"}})
+	expectStatus(t, r, http.StatusOK)
+	if content := r.get("comment.content"); content != "edited" {
+		t.Fatalf("content = %v, want edited", content)
+	}
+
+	expectStatus(t, doRequest(t, srv, "DELETE", commentPath, token, nil), http.StatusOK)
+	expectStatus(t, doRequest(t, srv, "PUT", commentPath, token, url.Values{"content": {"again"}}), http.StatusNotFound)
+
+	// 有回复的评论删除后在评论树中保留占位节点
+	r = doRequest(t, srv, "GET", base+"/comment/tree", token, nil)
+	expectStatus(t, r, http.StatusOK)
+	tree, _ := r.get("comments").([]any)
+	if len(tree) != 1 {
+		t.Fatalf("len(tree) = %d, want 1", len(tree))
+	}
+	root := testResponse{body: tree[0].(map[string]any)}
+	if root.get("deleted") != true || len(root.get("replies").([]any)) != 1 {
+		t.Fatalf("unexpected tree root: %v", root.body)
+	}
+}
+
+func TestNonOwnerForbidden(t *testing.T) {
+	srv := newTestServer(t)
+	alice := registerUser(t, srv, "alice")
+	bob := registerUser(t, srv, "bob")
+
+	pid := createPost(t, srv, alice, "hello")
+	postPath := fmt.Sprintf("/auth/post/%d", pid)
+	r := doRequest(t, srv, "POST", postPath+"/comment", alice, url.Values{"content": {"first"}})
+	expectStatus(t, r, http.StatusOK)
+	commentPath := fmt.Sprintf("%s/comment/%d", postPath, r.id("comment.id"))
+
+	tests := []struct {
+		name, method, path string
+		form               url.Values
+		code               string
+	}{
+		{"update post", "PUT", postPath, url.Values{"title": {"mine"}}, "post_not_owned"},
+		{"delete post", "DELETE", postPath, nil, "post_not_owned"},
+		{"update comment", "PUT", commentPath, url.Values{"content": {"mine"}}, "comment_not_owned"},
+		{"delete comment", "DELETE", commentPath, nil, "comment_not_owned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := doRequest(t, srv, tt.method, tt.path, bob, tt.form)
+			expectStatus(t, r, http.StatusForbidden)
+			if code := r.get("code"); code != tt.code {
+				t.Fatalf("code = %v, want %s", code, tt.code)
+			}
+		})
+	}
+
+	// 作者的文章和评论没有被修改
+	r = doRequest(t, srv, "GET", postPath, alice, nil)
+	expectStatus(t, r, http.StatusOK)
+	if title := r.get("post.title"); title != "hello" {
+		t.Fatalf("title = %v, want hello", title)
+	}
+	expectStatus(t, doRequest(t, srv, "PUT", commentPath, alice, url.Values{"content": {"still mine"}}), http.StatusOK)
+}
+
+// 注册指定角色的用户; 修改角色会吊销token, 因此重新登录获取token
+func registerWithRole(t *testing.T, srv *httptest.Server, services *Services, username string, role Role) string {
+	t.Helper()
+	claims, err := ParseToken(registerUser(t, srv, username))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := services.Users.SetRole(context.Background(), claims.UserID, role); err != nil {
+		t.Fatal(err)
+	}
+	r := doRequest(t, srv, "POST", "/login", "", url.Values{"username": {username}, "password": {"password123"}})
+	expectStatus(t, r, http.StatusOK)
+	return r.get("token").(string)
+}
+
+func TestRolePermissions(t *testing.T) {
+	srv, services := newTestServerWithServices(t)
+	admin := registerWithRole(t, srv, services, "admin", RoleAdmin)
+	moderator := registerWithRole(t, srv, services, "moderator", RoleModerator)
+	reader := registerWithRole(t, srv, services, "reader", RoleReader)
+	alice := registerUser(t, srv, "alice")
+
+	pid := createPost(t, srv, alice, "hello")
+	postPath := fmt.Sprintf("/auth/post/%d", pid)
+	comment := func(token string) string {
+		t.Helper()
+		r := doRequest(t, srv, "POST", postPath+"/comment", token, url.Values{"content": {"comment"}})
+		expectStatus(t, r, http.StatusOK)
+		return fmt.Sprintf("%s/comment/%d", postPath, r.id("comment.id"))
+	}
+	// 读者可以评论
+	readerComment := comment(reader)
+
+	tests := []struct {
+		name, token, method, path string
+		form                      url.Values
+		status                    int
+		code                      string
+	}{
+		{"reader creates post", reader, "POST", "/auth/post", url.Values{"title": {"x"}, "content": {"x"}}, http.StatusForbidden, "permission_denied"},
+		{"author lists users", alice, "GET", "/auth/admin/users", nil, http.StatusForbidden, "permission_denied"},
+		{"moderator lists users", moderator, "GET", "/auth/admin/users", nil, http.StatusForbidden, "permission_denied"},
+		{"moderator sets role", moderator, "PUT", fmt.Sprintf("/auth/admin/users/%d/role", 1), url.Values{"role": {"admin"}}, http.StatusForbidden, "permission_denied"},
+		{"moderator edits comment of others", moderator, "PUT", readerComment, url.Values{"content": {"edited"}}, http.StatusForbidden, "comment_not_owned"},
+		{"author deletes comment of others", alice, "DELETE", readerComment, nil, http.StatusForbidden, "comment_not_owned"},
+		{"moderator deletes comment of others", moderator, "DELETE", readerComment, nil, http.StatusOK, ""},
+		{"admin deletes comment of others", admin, "DELETE", comment(alice), nil, http.StatusOK, ""},
+		{"admin lists users", admin, "GET", "/auth/admin/users", nil, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := doRequest(t, srv, tt.method, tt.path, tt.token, tt.form)
+			expectStatus(t, r, tt.status)
+			if tt.code != "" && r.get("code") != tt.code {
+				t.Fatalf("code = %v, want %s", r.get("code"), tt.code)
+			}
+		})
+	}
+
+	r := doRequest(t, srv, "GET", postPath+"/comments", alice, nil)
+	expectStatus(t, r, http.StatusOK)
+	if comments, _ := r.get("comments").([]any); len(comments) != 0 {
+		t.Fatalf("len(comments) = %d, want 0 after moderation", len(comments))
+	}
+}
+
+// 文章改回草稿后, 其他用户看不到文章, 也不能修改和删除其中的评论
+func TestCommentOnHiddenPost(t *testing.T) {
+	srv := newTestServer(t)
+	alice := registerUser(t, srv, "alice")
+	bob := registerUser(t, srv, "bob")
+
+	pid := createPost(t, srv, alice, "hello")
+	postPath := fmt.Sprintf("/auth/post/%d", pid)
+	r := doRequest(t, srv, "POST", postPath+"/comment", bob, url.Values{"content": {"first"}})
+	expectStatus(t, r, http.StatusOK)
+	commentPath := fmt.Sprintf("%s/comment/%d", postPath, r.id("comment.id"))
+	expectStatus(t, doRequest(t, srv, "PUT", postPath, alice, url.Values{"status": {"draft"}}), http.StatusOK)
+
+	for _, req := range []struct {
+		method string
+		form   url.Values
+	}{{"PUT", url.Values{"content": {"edited"}}}, {"DELETE", nil}} {
+		r := doRequest(t, srv, req.method, commentPath, bob, req.form)
+		expectStatus(t, r, http.StatusNotFound)
+		if code := r.get("code"); code != "post_not_found" {
+			t.Fatalf("%s code = %v, want post_not_found", req.method, code)
+		}
+	}
+
+	// 作者仍能看到评论, 内容没有变化
+	r = doRequest(t, srv, "GET", postPath+"/comments", alice, nil)
+	expectStatus(t, r, http.StatusOK)
+	comments, _ := r.get("comments").([]any)
+	if len(comments) != 1 || comments[0].(map[string]any)["content"] != "first" {
+		t.Fatalf("unexpected comments: %v", comments)
+	}
+}
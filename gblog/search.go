@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	SearchTypePost    = "post"
+	SearchTypeComment = "comment"
+)
+
+// 搜索引擎, 见SearchConfig
+const (
+	searchEngineAuto     = "auto"
+	searchEngineFulltext = "fulltext"
+	searchEngineIndex    = "index"
+)
+
+var ErrEmptySearchQuery = newAppError(KindInvalid, "empty_search_query", "search query is empty")
+
+// 搜索条件
+type SearchQuery struct {
+	Query  string
+	Types  []string // 为空时搜索全部类型
+	Limit  int
+	Offset int
+}
+
+func (q SearchQuery) wants(typ string) bool {
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// 搜索结果, Title和Snippet为高亮后的html
+type SearchHit struct {
+	Type    string  `json:"type"`
+	ID      uint    `json:"id"`
+	PostID  uint    `json:"post_id"`
+	Title   string  `json:"title,omitempty"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// 全文搜索引擎, 文章和评论变更时由service同步
+type Searcher interface {
+	IndexPost(ctx context.Context, post *Post) error
+	RemovePost(ctx context.Context, id uint) error
+	IndexComment(ctx context.Context, comment *Comment) error
+	RemoveComment(ctx context.Context, id uint) error
+	Search(ctx context.Context, q SearchQuery) (hits []SearchHit, total int, err error)
+}
+
+// 搜索服务, 文章和评论的索引同步由PostService和CommentService完成
+type SearchService struct {
+	searcher Searcher
+}
+
+func (s *SearchService) Search(ctx context.Context, q SearchQuery) ([]SearchHit, int, error) {
+	if len(queryTerms(q.Query)) == 0 {
+		return nil, 0, ErrEmptySearchQuery
+	}
+	return s.searcher.Search(ctx, q)
+}
+
+// 分词结果, start/end为原文中的字节位置
+type token struct {
+	term       string
+	start, end int
+}
+
+// 中日韩文字没有空格分词, 按相邻两字切分
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// 分词: 英文数字按单词小写, 中日韩文字按二元组
+func tokenize(text string) []token {
+	var tokens []token
+	wordStart := -1
+	var cjk []token // 连续的中日韩单字
+
+	flushWord := func(end int) {
+		if wordStart >= 0 {
+			tokens = append(tokens, token{term: strings.ToLower(text[wordStart:end]), start: wordStart, end: end})
+			wordStart = -1
+		}
+	}
+	flushCJK := func() {
+		switch len(cjk) {
+		case 0:
+		case 1:
+			tokens = append(tokens, cjk[0])
+		default:
+			for i := 0; i+1 < len(cjk); i++ {
+				tokens = append(tokens, token{term: cjk[i].term + cjk[i+1].term, start: cjk[i].start, end: cjk[i+1].end})
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for i, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord(i)
+			cjk = append(cjk, token{term: string(r), start: i, end: i + utf8.RuneLen(r)})
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			if wordStart < 0 {
+				wordStart = i
+			}
+		default:
+			flushWord(i)
+			flushCJK()
+		}
+	}
+	flushWord(len(text))
+	flushCJK()
+	return tokens
+}
+
+// 查询词去重
+func queryTerms(q string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range tokenize(q) {
+		if !seen[t.term] {
+			seen[t.term] = true
+			terms = append(terms, t.term)
+		}
+	}
+	return terms
+}
+
+// 词是否匹配查询词(完全匹配或前缀匹配)
+func termMatches(term string, terms []string) bool {
+	for _, q := range terms {
+		if strings.HasPrefix(term, q) {
+			return true
+		}
+	}
+	return false
+}
+
+const snippetRunes = 120
+
+// 高亮匹配的词, 返回转义后的html; limit>0时截取第一个匹配附近的片段
+func highlight(text string, terms []string, limit int) string {
+	var spans []token
+	for _, t := range tokenize(text) {
+		if termMatches(t.term, terms) {
+			spans = append(spans, t)
+		}
+	}
+
+	from, to := 0, len(text)
+	if limit > 0 && utf8.RuneCountInString(text) > limit {
+		if len(spans) > 0 {
+			from = backRunes(text, spans[0].start, limit/4)
+		}
+		to = forwardRunes(text, from, limit)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, s := range spans {
+		if s.start < pos || s.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:s.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[s.start:s.end]))
+		b.WriteString("</mark>")
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// 从字节位置i向前n个字符
+func backRunes(s string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return i
+}
+
+// 从字节位置i向后n个字符
+func forwardRunes(s string, i, n int) int {
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
+
+// 生成高亮后的搜索结果
+func newPostHit(post *Post, terms []string, score float64) SearchHit {
+	return SearchHit{
+		Type:    SearchTypePost,
+		ID:      post.ID,
+		PostID:  post.ID,
+		Title:   highlight(post.Title, terms, 0),
+		Snippet: highlight(post.Content, terms, snippetRunes),
+		Score:   score,
+	}
+}
+
+func newCommentHit(comment *Comment, terms []string, score float64) SearchHit {
+	return SearchHit{
+		Type:    SearchTypeComment,
+		ID:      comment.ID,
+		PostID:  comment.PostID,
+		Snippet: highlight(comment.Content, terms, snippetRunes),
+		Score:   score,
+	}
+}
+
+// 全文搜索文章和评论
+func (h *Handler) SearchHandler(c *gin.Context) {
+	q := SearchQuery{Query: strings.TrimSpace(c.Query("q"))}
+	switch typ := c.DefaultQuery("type", "all"); typ {
+	case "all":
+	case SearchTypePost, SearchTypeComment:
+		q.Types = []string{typ}
+	default:
+		writeProblem(c, invalidParam("type", "type must be all, post or comment"))
+		return
+	}
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		writeProblem(c, err)
+		return
+	}
+	if page.After != nil {
+		// 搜索结果按得分排序, 只支持页码分页
+		writeProblem(c, invalidParam("cursor", "search does not support cursor"))
+		return
+	}
+	q.Limit, q.Offset = page.Limit, page.Offset
+
+	hits, total, err := h.search.Search(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, "Search", err)
+		return
+	}
+
+	requestLogger(c).Info("Search successfully", zap.String("q", q.Query), zap.Int("total", total))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"hits":    hits,
+		"meta": PageMeta{
+			Total:    int64(total),
+			Page:     pageNum,
+			PageSize: page.Limit,
+			HasMore:  q.Offset+len(hits) < total,
+		},
+	})
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	titleWeight  = 3.0 // 标题中的词按3次计
+	prefixWeight = 0.5 // 前缀匹配的得分折扣
+)
+
+type indexKey struct {
+	typ string
+	id  uint
+}
+
+type indexDoc struct {
+	postID  uint
+	title   string
+	content string
+	length  float64 // 加权后的词数
+}
+
+// 内置倒排索引, 用于不支持FULLTEXT的数据库; 数据只在内存中, 启动时从数据库重建
+type IndexSearcher struct {
+	mu       sync.RWMutex
+	docs     map[indexKey]*indexDoc
+	postings map[string]map[indexKey]float64 // 词 -> 文档 -> 加权词频
+	comments map[uint]map[uint]bool          // 文章id -> 评论id, 删除文章时一并删除
+	totalLen float64
+	terms    []string // 排序后的词表, 用于前缀匹配, 为nil时需重建
+}
+
+func NewIndexSearcher() *IndexSearcher {
+	return &IndexSearcher{
+		docs:     make(map[indexKey]*indexDoc),
+		postings: make(map[string]map[indexKey]float64),
+		comments: make(map[uint]map[uint]bool),
+	}
+}
+
+// 从存储中批量加载全部文章和评论建立索引
+func buildSearchIndex(ctx context.Context, posts PostRepository, comments CommentRepository) (*IndexSearcher, error) {
+	const batch = 500
+	ix := NewIndexSearcher()
+	for after := uint(0); ; {
+		items, err := posts.ListAfterID(ctx, after, batch)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			ix.IndexPost(ctx, &items[i])
+		}
+		if len(items) < batch {
+			break
+		}
+		after = items[len(items)-1].ID
+	}
+	for after := uint(0); ; {
+		items, err := comments.ListAfterID(ctx, after, batch)
+		if err != nil {
+			return nil, err
+		}
+		for i := range items {
+			ix.IndexComment(ctx, &items[i])
+		}
+		if len(items) < batch {
+			break
+		}
+		after = items[len(items)-1].ID
+	}
+	return ix, nil
+}
+
+func (ix *IndexSearcher) IndexPost(ctx context.Context, post *Post) error {
+	if post.Status != PostPublished {
+		// 草稿等未发布的文章不可搜索
+		return ix.RemovePost(ctx, post.ID)
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.add(indexKey{SearchTypePost, post.ID}, &indexDoc{postID: post.ID, title: post.Title, content: post.Content})
+	return nil
+}
+
+func (ix *IndexSearcher) RemovePost(ctx context.Context, id uint) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(indexKey{SearchTypePost, id})
+	for cid := range ix.comments[id] {
+		ix.remove(indexKey{SearchTypeComment, cid})
+	}
+	delete(ix.comments, id)
+	return nil
+}
+
+func (ix *IndexSearcher) IndexComment(ctx context.Context, comment *Comment) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	// 文章已删除的评论不再索引
+	if _, ok := ix.docs[indexKey{SearchTypePost, comment.PostID}]; !ok {
+		return nil
+	}
+	ix.add(indexKey{SearchTypeComment, comment.ID}, &indexDoc{postID: comment.PostID, content: comment.Content})
+	if ix.comments[comment.PostID] == nil {
+		ix.comments[comment.PostID] = make(map[uint]bool)
+	}
+	ix.comments[comment.PostID][comment.ID] = true
+	return nil
+}
+
+func (ix *IndexSearcher) RemoveComment(ctx context.Context, id uint) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if doc, ok := ix.docs[indexKey{SearchTypeComment, id}]; ok {
+		delete(ix.comments[doc.postID], id)
+	}
+	ix.remove(indexKey{SearchTypeComment, id})
+	return nil
+}
+
+// 添加或替换文档, 调用方需持有写锁
+func (ix *IndexSearcher) add(key indexKey, doc *indexDoc) {
+	ix.remove(key)
+	tf := make(map[string]float64)
+	for _, t := range tokenize(doc.title) {
+		tf[t.term] += titleWeight
+	}
+	for _, t := range tokenize(doc.content) {
+		tf[t.term]++
+	}
+	for term, n := range tf {
+		if ix.postings[term] == nil {
+			ix.postings[term] = make(map[indexKey]float64)
+			ix.terms = nil
+		}
+		ix.postings[term][key] = n
+		doc.length += n
+	}
+	ix.docs[key] = doc
+	ix.totalLen += doc.length
+}
+
+// 删除文档, 调用方需持有写锁
+func (ix *IndexSearcher) remove(key indexKey) {
+	doc, ok := ix.docs[key]
+	if !ok {
+		return
+	}
+	for _, t := range tokenize(doc.title + " " + doc.content) {
+		if docs := ix.postings[t.term]; docs != nil {
+			delete(docs, key)
+			if len(docs) == 0 {
+				delete(ix.postings, t.term)
+				ix.terms = nil
+			}
+		}
+	}
+	ix.totalLen -= doc.length
+	delete(ix.docs, key)
+}
+
+// 在排序后的词表中查找以prefix开头的词
+func prefixRange(terms []string, prefix string) []string {
+	i := sort.SearchStrings(terms, prefix)
+	j := i
+	for j < len(terms) && strings.HasPrefix(terms[j], prefix) {
+		j++
+	}
+	return terms[i:j]
+}
+
+// 排序后的词表, 词表变化后首次查询时重建
+func (ix *IndexSearcher) sortedTerms() []string {
+	ix.mu.RLock()
+	terms := ix.terms
+	ix.mu.RUnlock()
+	if terms != nil {
+		return terms
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.terms == nil {
+		ix.terms = make([]string, 0, len(ix.postings))
+		for term := range ix.postings {
+			ix.terms = append(ix.terms, term)
+		}
+		sort.Strings(ix.terms)
+	}
+	return ix.terms
+}
+
+// 所有查询词都需匹配; 每个查询词取完全匹配或前缀匹配中得分最高的词, 按BM25累加
+func (ix *IndexSearcher) Search(ctx context.Context, q SearchQuery) ([]SearchHit, int, error) {
+	terms := queryTerms(q.Query)
+	if len(terms) == 0 {
+		return nil, 0, ErrEmptySearchQuery
+	}
+	sorted := ix.sortedTerms()
+
+	ix.mu.RLock()
+	n := float64(len(ix.docs))
+	avgLen := 1.0
+	if n > 0 && ix.totalLen > 0 {
+		avgLen = ix.totalLen / n
+	}
+
+	var scores map[indexKey]float64
+	for _, qt := range terms {
+		termScores := make(map[indexKey]float64)
+		for _, term := range prefixRange(sorted, qt) {
+			docs := ix.postings[term]
+			weight := 1.0
+			if term != qt {
+				weight = prefixWeight
+			}
+			df := float64(len(docs))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for key, tf := range docs {
+				if !q.wants(key.typ) {
+					continue
+				}
+				if _, ok := scores[key]; scores != nil && !ok {
+					continue
+				}
+				norm := tf + bm25K1*(1-bm25B+bm25B*ix.docs[key].length/avgLen)
+				s := weight * idf * tf * (bm25K1 + 1) / norm
+				termScores[key] = max(termScores[key], s)
+			}
+		}
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for key, s := range scores {
+			if ts, ok := termScores[key]; ok {
+				scores[key] = s + ts
+			} else {
+				delete(scores, key)
+			}
+		}
+	}
+
+	keys := make([]indexKey, 0, len(scores))
+	for key := range scores {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if a.typ != b.typ {
+			return a.typ > b.typ // 同分时文章在前
+		}
+		return a.id > b.id
+	})
+
+	page := pageSlice(keys, q.Offset, q.Limit)
+	hits := make([]SearchHit, 0, len(page))
+	for _, key := range page {
+		doc := ix.docs[key]
+		score := math.Round(scores[key]*1000) / 1000
+		if key.typ == SearchTypePost {
+			post := &Post{Title: doc.title, Content: doc.content}
+			post.ID = key.id
+			hits = append(hits, newPostHit(post, terms, score))
+		} else {
+			comment := &Comment{Content: doc.content, PostID: doc.postID}
+			comment.ID = key.id
+			hits = append(hits, newCommentHit(comment, terms, score))
+		}
+	}
+	ix.mu.RUnlock()
+	return hits, len(keys), nil
+}
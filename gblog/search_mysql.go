@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 全文搜索需要的FULLTEXT索引, 由迁移0016_fulltext_search创建
+var fulltextIndexes = []struct {
+	model any
+	name  string
+}{
+	{&Post{}, "ft_posts_title_content"},
+	{&Post{}, "ft_posts_title"},
+	{&Comment{}, "ft_comments_content"},
+}
+
+// 基于MySQL FULLTEXT索引的搜索, 索引由数据库维护, 写操作无需同步
+type FulltextSearcher struct {
+	db *gorm.DB
+}
+
+// 检查FULLTEXT索引是否存在, 不存在时需先执行 gblog migrate up
+func NewFulltextSearcher(db *gorm.DB) (*FulltextSearcher, error) {
+	for _, idx := range fulltextIndexes {
+		if !db.Migrator().HasIndex(idx.model, idx.name) {
+			return nil, fmt.Errorf("fulltext index %s not found, run `gblog migrate up`", idx.name)
+		}
+	}
+	return &FulltextSearcher{db: db}, nil
+}
+
+func (s *FulltextSearcher) IndexPost(ctx context.Context, post *Post) error          { return nil }
+func (s *FulltextSearcher) RemovePost(ctx context.Context, id uint) error            { return nil }
+func (s *FulltextSearcher) IndexComment(ctx context.Context, comment *Comment) error { return nil }
+func (s *FulltextSearcher) RemoveComment(ctx context.Context, id uint) error         { return nil }
+
+// 生成BOOLEAN MODE查询: 每个词都必须出现, 英文词做前缀匹配
+func booleanQuery(terms []string) string {
+	parts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if r := []rune(t); isCJK(r[0]) {
+			parts = append(parts, `+"`+t+`"`)
+		} else {
+			parts = append(parts, "+"+t+"*")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+type fulltextRow struct {
+	Type    string
+	ID      uint
+	PostID  uint
+	Title   string
+	Content string
+	Score   float64
+}
+
+func (s *FulltextSearcher) Search(ctx context.Context, q SearchQuery) ([]SearchHit, int, error) {
+	terms := queryTerms(q.Query)
+	if len(terms) == 0 {
+		return nil, 0, ErrEmptySearchQuery
+	}
+	against := booleanQuery(terms)
+
+	// 标题中的匹配额外加分; 只返回已发布文章及其评论
+	var selects, counts []string
+	var args, countArgs []any
+	if q.wants(SearchTypePost) {
+		selects = append(selects, `SELECT 'post' AS type, id, id AS post_id, title, content,
+			MATCH(title, content) AGAINST (? IN BOOLEAN MODE) + 2 * MATCH(title) AGAINST (? IN BOOLEAN MODE) AS score
+			FROM posts WHERE deleted_at IS NULL AND status = 'published' AND MATCH(title, content) AGAINST (? IN BOOLEAN MODE)`)
+		args = append(args, against, against, against)
+		counts = append(counts, "SELECT COUNT(*) FROM posts WHERE deleted_at IS NULL AND status = 'published' AND MATCH(title, content) AGAINST (? IN BOOLEAN MODE)")
+		countArgs = append(countArgs, against)
+	}
+	if q.wants(SearchTypeComment) {
+		selects = append(selects, `SELECT 'comment' AS type, c.id, c.post_id, '' AS title, c.content,
+			MATCH(c.content) AGAINST (? IN BOOLEAN MODE) AS score
+			FROM comments c JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.status = 'published'
+			WHERE c.deleted_at IS NULL AND MATCH(c.content) AGAINST (? IN BOOLEAN MODE)`)
+		args = append(args, against, against)
+		counts = append(counts, `SELECT COUNT(*) FROM comments c JOIN posts p ON p.id = c.post_id AND p.deleted_at IS NULL AND p.status = 'published'
+			WHERE c.deleted_at IS NULL AND MATCH(c.content) AGAINST (? IN BOOLEAN MODE)`)
+		countArgs = append(countArgs, against)
+	}
+
+	db := s.db.WithContext(ctx)
+	total := 0
+	for i, sql := range counts {
+		var n int64
+		if err := db.Raw(sql, countArgs[i]).Scan(&n).Error; err != nil {
+			return nil, 0, err
+		}
+		total += int(n)
+	}
+
+	var rows []fulltextRow
+	sql := strings.Join(selects, " UNION ALL ") + " ORDER BY score DESC, type DESC, id DESC LIMIT ? OFFSET ?"
+	args = append(args, q.Limit, q.Offset)
+	if err := db.Raw(sql, args...).Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]SearchHit, 0, len(rows))
+	for _, row := range rows {
+		if row.Type == SearchTypePost {
+			post := &Post{Title: row.Title, Content: row.Content}
+			post.ID = row.ID
+			hits = append(hits, newPostHit(post, terms, row.Score))
+		} else {
+			comment := &Comment{Content: row.Content, PostID: row.PostID}
+			comment.ID = row.ID
+			hits = append(hits, newCommentHit(comment, terms, row.Score))
+		}
+	}
+	return hits, total, nil
+}
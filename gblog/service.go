@@ -0,0 +1,569 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserExists        = newAppError(KindConflict, "user_exists", "user already exists")
+	ErrUserNotExist      = newAppError(KindInvalid, "user_not_exist", "user not exist")
+	ErrInvalidLogin      = newAppError(KindUnauthorized, "invalid_login", "username or password is not correct")
+	ErrUserSuspended     = newAppError(KindForbidden, "user_suspended", "user is suspended")
+	ErrSuspendSelf       = newAppError(KindInvalid, "suspend_self", "can't suspend yourself")
+	ErrPostNotFound      = newAppError(KindNotFound, "post_not_found", "can't get post")
+	ErrPostNotOwned      = newAppError(KindForbidden, "post_not_owned", "post is not belongs to the user")
+	ErrInvalidRole       = newAppError(KindInvalid, "invalid_role", "role is not valid")
+	ErrInvalidPostStatus = newAppError(KindInvalid, "invalid_post_status", "post status must be draft, scheduled, published or archived")
+	ErrInvalidPublishAt  = newAppError(KindInvalid, "invalid_publish_at", "publish_at must be a future time and is only allowed for scheduled posts")
+)
+
+// 业务服务集合, 由main组装后注入handler
+type Services struct {
+	Users         *UserService
+	Posts         *PostService
+	Comments      *CommentService
+	Auth          *AuthService
+	Siwe          *SiweService
+	Search        *SearchService
+	Taxonomy      *TaxonomyService
+	Attachments   *AttachmentService
+	Engagement    *EngagementService
+	Feed          *FeedService
+	Notifications *NotificationService
+	RateLimits    RateLimitStore
+	Account       *AccountService
+}
+
+func NewServices(repos *Repositories, cfg *Config) *Services {
+	taxonomy := &TaxonomyService{tags: repos.Tags, categories: repos.Categories}
+	notifications := &NotificationService{notifications: repos.Notifications, users: repos.Users, broker: repos.Broker, now: time.Now}
+	views := NewViewCounter(repos.Engagement)
+	posts := &PostService{posts: repos.Posts, comments: repos.Comments, revisions: repos.Revisions, engagement: repos.Engagement, views: views, markdown: NewMarkdownRenderer(), tags: repos.Tags, taxonomy: taxonomy, search: repos.Search, notifications: notifications, now: time.Now}
+	feed := &FeedService{users: repos.Users, follows: repos.Follows, posts: posts, notifications: notifications, timelineSize: cfg.Feed.TimelineSize}
+	if cfg.Feed.Mode == feedModeWrite {
+		feed.timeline = NewMemoryTimelineCache(cfg.Feed.TimelineSize)
+	}
+	posts.feed = feed
+	auth := &AuthService{tokens: repos.Tokens, users: repos.Users, now: time.Now}
+	guard := &LoginGuard{store: repos.RateLimits, cfg: cfg.RateLimit.Lockout}
+	siwe := &SiweService{cfg: cfg.Siwe, nonces: repos.Nonces, users: repos.Users, defaultRole: cfg.RBAC.DefaultRole, now: time.Now}
+	return &Services{
+		Users:         &UserService{users: repos.Users, auth: auth, guard: guard, defaultRole: cfg.RBAC.DefaultRole, now: time.Now},
+		Posts:         posts,
+		Comments:      &CommentService{posts: repos.Posts, comments: repos.Comments, search: repos.Search, notifications: notifications, broker: repos.Broker, maxDepth: cfg.Comment.MaxDepth, now: time.Now},
+		Auth:          auth,
+		Siwe:          siwe,
+		Search:        &SearchService{searcher: repos.Search},
+		Taxonomy:      taxonomy,
+		Attachments:   &AttachmentService{posts: posts, attachments: repos.Attachments, store: repos.Blobs, cfg: cfg.Upload, now: time.Now},
+		Feed:          feed,
+		Notifications: notifications,
+		RateLimits:    repos.RateLimits,
+		Account:       &AccountService{users: repos.Users, tokens: repos.Tokens, auth: auth, siwe: siwe, guard: guard, mailer: repos.Mailer, search: repos.Search, cfg: cfg.Account, now: time.Now},
+		Engagement:    &EngagementService{posts: posts, engagement: repos.Engagement, views: views, trendingWindow: time.Duration(cfg.Post.TrendingWindow), now: time.Now},
+	}
+}
+
+type UserService struct {
+	users       UserRepository
+	auth        *AuthService
+	guard       *LoginGuard
+	defaultRole Role
+	now         func() time.Time
+}
+
+// 注册, password需为已加密的密码; 一个邮箱只能注册一个用户
+func (s *UserService) Register(ctx context.Context, user *User) error {
+	if _, err := s.users.GetByUsername(ctx, user.Username); err == nil {
+		return ErrUserExists
+	} else if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	user.Email = normalizeEmail(user.Email)
+	if user.Email != "" {
+		if _, err := s.users.GetByEmail(ctx, user.Email); err == nil {
+			return ErrEmailExists
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	user.Role = s.defaultRole
+	if err := s.users.Create(ctx, user); err != nil {
+		if errors.Is(err, ErrDuplicate) {
+			return ErrUserExists
+		}
+		return err
+	}
+	return nil
+}
+
+// 修改用户角色; 角色写在token中, 角色变化时吊销该用户已登录的token, 需重新登录
+func (s *UserService) SetRole(ctx context.Context, id uint, role Role) (*User, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUserNotExist
+		}
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+	if err := s.users.SetRole(ctx, id, role); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUserNotExist
+		}
+		return nil, err
+	}
+	if err := s.auth.RevokeUser(ctx, id); err != nil {
+		return nil, err
+	}
+	user.Role = role
+	return user, nil
+}
+
+// 管理员查询用户列表
+func (s *UserService) List(ctx context.Context, q UserQuery) ([]User, int64, error) {
+	if q.Role != "" && !q.Role.Valid() {
+		return nil, 0, ErrInvalidRole
+	}
+	return s.users.List(ctx, q)
+}
+
+// 封禁用户, 已登录的token全部失效; 重复封禁不改变封禁时间
+func (s *UserService) Suspend(ctx context.Context, actorID, id uint) (*User, error) {
+	if actorID == id {
+		return nil, ErrSuspendSelf
+	}
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUserNotExist
+		}
+		return nil, err
+	}
+	if user.SuspendedAt == nil {
+		now := s.now()
+		if err := s.users.SetSuspended(ctx, id, &now); err != nil {
+			return nil, err
+		}
+		user.SuspendedAt = &now
+	}
+	if err := s.auth.RevokeUser(ctx, id); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// 解除封禁
+func (s *UserService) Unsuspend(ctx context.Context, id uint) (*User, error) {
+	if err := s.users.SetSuspended(ctx, id, nil); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUserNotExist
+		}
+		return nil, err
+	}
+	return s.users.GetByID(ctx, id)
+}
+
+// 启动时将配置中的用户提升为管理员, 不存在的用户忽略; 与SetRole相同, 提升后需重新登录
+func (s *UserService) PromoteAdmins(ctx context.Context, usernames []string) error {
+	for _, name := range usernames {
+		user, err := s.users.GetByUsername(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := s.SetRole(ctx, user.ID, RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 用户不存在时用于比较的密码hash, 使两种登录失败的耗时一致
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("gblog"), passwordCost)
+
+// 登录, 校验用户名和密码; 用户不存在和密码错误返回相同的错误
+func (s *UserService) Login(ctx context.Context, username, password string) (*User, error) {
+	if err := s.guard.Check(ctx, username); err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetByUsername(ctx, username)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	hash := dummyPasswordHash
+	if user != nil {
+		hash = []byte(user.Password)
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || user == nil {
+		s.guard.Failed(ctx, username)
+		return nil, ErrInvalidLogin
+	}
+	s.guard.Succeeded(ctx, username)
+	// 密码正确才提示封禁, 不向猜测密码的人暴露账号状态
+	if user.SuspendedAt != nil {
+		return nil, ErrUserSuspended
+	}
+	return user, nil
+}
+
+type PostService struct {
+	posts         PostRepository
+	comments      CommentRepository
+	revisions     PostRevisionRepository
+	engagement    EngagementRepository
+	views         *ViewCounter
+	feed          *FeedService
+	notifications *NotificationService
+	tags          TagRepository
+	taxonomy      *TaxonomyService
+	search        Searcher
+	markdown      *MarkdownRenderer
+	now           func() time.Time
+}
+
+// 文章的创建和修改参数
+type PostInput struct {
+	Title      string
+	Content    string
+	CategoryID *uint      // 为nil时不修改, 为0时清空分类
+	Tags       []string   // 标签名, 为nil时不修改, 为空切片时清空标签
+	Status     PostStatus // 为空时不修改; 创建时默认发布, 提交PublishAt时默认定时发布
+	PublishAt  *time.Time
+}
+
+func (s *PostService) Create(ctx context.Context, userID uint, in PostInput) (*Post, error) {
+	post := &Post{
+		Title:   in.Title,
+		Content: in.Content,
+		UserID:  userID,
+	}
+	if in.Status == "" && in.PublishAt == nil {
+		in.Status = PostPublished
+	}
+	if err := s.applyInput(ctx, post, in); err != nil {
+		return nil, err
+	}
+	if err := s.render(post); err != nil {
+		return nil, err
+	}
+	if err := s.posts.Create(ctx, post); err != nil {
+		return nil, err
+	}
+	if err := s.recordRevision(ctx, nil, post, userID, revisionNoteInitial); err != nil {
+		return nil, err
+	}
+	if err := s.saveTags(ctx, post, in); err != nil {
+		return nil, err
+	}
+	s.syncIndex(ctx, post)
+	if post.Status == PostPublished {
+		s.published(ctx, post)
+	}
+	return post, nil
+}
+
+// 校验状态和分类并解析标签, 标签在文章保存后写入
+func (s *PostService) applyInput(ctx context.Context, post *Post, in PostInput) error {
+	if err := s.applyStatus(post, in); err != nil {
+		return err
+	}
+	if in.CategoryID != nil {
+		if *in.CategoryID == 0 {
+			post.CategoryID = nil
+		} else {
+			if _, err := s.taxonomy.getCategory(ctx, *in.CategoryID); err != nil {
+				return err
+			}
+			id := *in.CategoryID
+			post.CategoryID = &id
+		}
+	}
+	if in.Tags != nil {
+		tags, err := s.taxonomy.ResolveTags(ctx, in.Tags)
+		if err != nil {
+			return err
+		}
+		post.Tags = tags
+	}
+	return nil
+}
+
+// 渲染Markdown正文, 结果随文章保存
+func (s *PostService) render(post *Post) error {
+	html, toc, err := s.markdown.Render(post.Content)
+	if err != nil {
+		return fmt.Errorf("render markdown: %w", err)
+	}
+	post.ContentHTML, post.TOC = html, toc
+	return nil
+}
+
+// 状态流转: 定时发布需要未来的发布时间, 首次发布时记录发布时间
+func (s *PostService) applyStatus(post *Post, in PostInput) error {
+	status := in.Status
+	if status == "" {
+		if in.PublishAt == nil {
+			return nil
+		}
+		status = PostScheduled
+	}
+	if !status.Valid() {
+		return ErrInvalidPostStatus
+	}
+	if in.PublishAt != nil && status != PostScheduled {
+		return ErrInvalidPublishAt
+	}
+
+	now := s.now()
+	post.Status = status
+	switch status {
+	case PostScheduled:
+		publishAt := in.PublishAt
+		if publishAt == nil {
+			publishAt = post.PublishAt
+		}
+		if publishAt == nil || !publishAt.After(now) {
+			return ErrInvalidPublishAt
+		}
+		post.PublishAt = publishAt
+	case PostPublished:
+		post.PublishAt = nil
+		if post.PublishedAt == nil {
+			post.PublishedAt = &now
+		}
+	default:
+		post.PublishAt = nil
+	}
+	return nil
+}
+
+func (s *PostService) saveTags(ctx context.Context, post *Post, in PostInput) error {
+	if in.Tags == nil {
+		return s.fill(ctx, post)
+	}
+	ids := make([]uint, 0, len(post.Tags))
+	for _, t := range post.Tags {
+		ids = append(ids, t.ID)
+	}
+	if err := s.tags.SetPostTags(ctx, post.ID, ids); err != nil {
+		return err
+	}
+	return s.fill(ctx, post)
+}
+
+// 获取文章, 未发布的文章只有作者可见
+func (s *PostService) Get(ctx context.Context, viewerID, id uint) (*Post, error) {
+	post, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !post.VisibleTo(viewerID) {
+		return nil, ErrPostNotFound
+	}
+	if post.ContentHTML == "" && post.Content != "" {
+		// 渲染功能上线前保存的文章, 读取时渲染
+		if err := s.render(post); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.fill(ctx, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func (s *PostService) get(ctx context.Context, id uint) (*Post, error) {
+	post, err := s.posts.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrPostNotFound
+		}
+		return nil, err
+	}
+	return post, nil
+}
+
+// 填充文章的标签、评论数和点赞数, 浏览数加上尚未写入的部分
+func (s *PostService) fill(ctx context.Context, posts ...*Post) error {
+	ids := make([]uint, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	counts, err := s.comments.CountByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	likes, err := s.engagement.CountLikes(ctx, ids)
+	if err != nil {
+		return err
+	}
+	tags, err := s.tags.ListByPostIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		p.CommentCount = counts[p.ID]
+		p.LikeCount = likes[p.ID]
+		p.ViewCount += s.views.Pending(p.ID)
+		p.Tags = tags[p.ID]
+	}
+	return nil
+}
+
+// 获取文章并校验操作权限
+func (s *PostService) getAuthorized(ctx context.Context, actor Actor, id uint, own, any Permission) (*Post, error) {
+	post, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanOn(post.UserID, own, any) {
+		return nil, ErrPostNotOwned
+	}
+	return post, nil
+}
+
+// 更新文章, 空字段不修改
+func (s *PostService) Update(ctx context.Context, actor Actor, id uint, in PostInput) (*Post, error) {
+	post, err := s.getAuthorized(ctx, actor, id, PermPostUpdateOwn, PermPostUpdateAny)
+	if err != nil {
+		return nil, err
+	}
+	before := *post
+	if in.Title != "" {
+		post.Title = in.Title
+	}
+	if in.Content != "" {
+		post.Content = in.Content
+	}
+	wasPublished := post.Status == PostPublished
+	if err := s.applyInput(ctx, post, in); err != nil {
+		return nil, err
+	}
+	if err := s.render(post); err != nil {
+		return nil, err
+	}
+	if err := s.posts.Update(ctx, post); err != nil {
+		return nil, err
+	}
+	if err := s.recordRevision(ctx, &before, post, actor.UserID, ""); err != nil {
+		return nil, err
+	}
+	if err := s.saveTags(ctx, post, in); err != nil {
+		return nil, err
+	}
+	s.syncIndex(ctx, post)
+	if !wasPublished && post.Status == PostPublished {
+		s.published(ctx, post)
+	}
+	return post, nil
+}
+
+func (s *PostService) Delete(ctx context.Context, actor Actor, id uint) (*Post, error) {
+	post, err := s.getAuthorized(ctx, actor, id, PermPostDeleteOwn, PermPostDeleteAny)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.posts.Delete(ctx, post.ID); err != nil {
+		return nil, err
+	}
+	if err := s.search.RemovePost(ctx, post.ID); err != nil {
+		LoggerFrom(ctx).Warn("remove post from search index failed", zap.Uint("post_id", post.ID), zap.Error(err))
+	}
+	return post, nil
+}
+
+// 发布到期的定时文章, 由后台任务周期调用
+func (s *PostService) PublishDue(ctx context.Context) error {
+	posts, err := s.posts.PublishDue(ctx, s.now())
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		LoggerFrom(ctx).Info("scheduled post published", zap.Uint("post_id", posts[i].ID))
+		s.syncIndex(ctx, &posts[i])
+		s.published(ctx, &posts[i])
+	}
+	return nil
+}
+
+// 文章变为已发布后: 补充评论索引, 写入关注者的时间线, 通知正文中@的用户
+func (s *PostService) published(ctx context.Context, post *Post) {
+	s.indexComments(ctx, post.ID)
+	s.feed.PostPublished(ctx, post)
+	s.notifications.PostPublished(ctx, post)
+}
+
+// 文章未发布时评论不在索引中, 发布后补充索引
+func (s *PostService) indexComments(ctx context.Context, postID uint) {
+	comments, err := s.comments.ListThread(ctx, postID)
+	if err != nil {
+		LoggerFrom(ctx).Warn("index post comments failed", zap.Uint("post_id", postID), zap.Error(err))
+		return
+	}
+	for i := range comments {
+		if comments[i].DeletedAt.Valid {
+			continue
+		}
+		if err := s.search.IndexComment(ctx, &comments[i]); err != nil {
+			LoggerFrom(ctx).Warn("index comment failed", zap.Uint("comment_id", comments[i].ID), zap.Error(err))
+		}
+	}
+}
+
+// 同步搜索索引, 失败不影响文章本身的写入
+func (s *PostService) syncIndex(ctx context.Context, post *Post) {
+	if err := s.search.IndexPost(ctx, post); err != nil {
+		LoggerFrom(ctx).Warn("index post failed", zap.Uint("post_id", post.ID), zap.Error(err))
+	}
+}
+
+// 分页查询文章列表
+func (s *PostService) List(ctx context.Context, q PostQuery) ([]Post, PageMeta, error) {
+	sortKey := q.Sort.String()
+	if q.Page.After != nil && q.Page.After.Sort != sortKey {
+		return nil, PageMeta{}, ErrInvalidCursor
+	}
+	if q.CategoryID != 0 {
+		ids, err := s.taxonomy.Descendants(ctx, q.CategoryID)
+		if err != nil {
+			return nil, PageMeta{}, err
+		}
+		q.CategoryIDs = ids
+	}
+
+	limit := q.Page.Limit
+	q.Page.Limit = limit + 1
+	posts, total, err := s.posts.List(ctx, q)
+	if err != nil {
+		return nil, PageMeta{}, err
+	}
+	posts, more := trimPage(posts, limit)
+	ptrs := make([]*Post, len(posts))
+	for i := range posts {
+		ptrs[i] = &posts[i]
+	}
+	if err := s.fill(ctx, ptrs...); err != nil {
+		return nil, PageMeta{}, err
+	}
+
+	meta := PageMeta{Total: total, PageSize: limit, HasMore: more}
+	if more {
+		last := posts[len(posts)-1]
+		meta.NextCursor = Cursor{Sort: sortKey, Value: postSortValue(last, q.Sort.Field), ID: last.ID}.Encode()
+	}
+	return posts, meta, nil
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrEmailExists          = newAppError(KindConflict, "email_exists", "email is already used by another user")
+	ErrEmailNotSet          = newAppError(KindInvalid, "email_not_set", "user has no email")
+	ErrEmailAlreadyVerified = newAppError(KindConflict, "email_already_verified", "email is already verified")
+	ErrUserTokenInvalid     = newAppError(KindInvalid, "user_token_invalid", "token is invalid or expired")
+	ErrOldPasswordIncorrect = newAppError(KindInvalid, "old_password_incorrect", "old password is not correct")
+	ErrPasswordIncorrect    = newAppError(KindInvalid, "password_incorrect", "password is not correct")
+	ErrProfileNotFound      = newAppError(KindNotFound, "user_not_found", "user not found")
+	ErrSiweSignatureMissing = newAppError(KindInvalid, "siwe_signature_required", "siwe message and signature are required")
+)
+
+// 发送一封邮件的超时时间
+const mailSendTimeout = 30 * time.Second
+
+// 个人资料、注销, 邮箱验证、找回密码和修改密码
+type AccountService struct {
+	users  UserRepository
+	tokens TokenRepository
+	auth   *AuthService
+	siwe   *SiweService
+	guard  *LoginGuard
+	mailer Mailer
+	search Searcher
+	cfg    AccountConfig
+	now    func() time.Time
+}
+
+// 邮箱统一转为小写, 避免大小写不同导致找不到用户
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// 生成一次性token, 只保存hash, 返回明文用于发送邮件
+func (s *AccountService) createToken(ctx context.Context, user *User, purpose UserTokenPurpose, expire Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = s.tokens.CreateUserToken(ctx, &UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: s.now().Add(time.Duration(expire)),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// 邮件中的有效期, 如"24小时"、"30分钟"
+func expireText(d Duration) string {
+	if td := time.Duration(d); td >= time.Hour && td%time.Hour == 0 {
+		return fmt.Sprintf("%d小时", td/time.Hour)
+	}
+	return fmt.Sprintf("%d分钟", time.Duration(d)/time.Minute)
+}
+
+// 邮件中的链接, 指向前端页面, 由前端调用对应接口提交token
+func (s *AccountService) link(path, token string) string {
+	return strings.TrimRight(s.cfg.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// 后台发送邮件, 失败只记录日志; 请求的耗时不受发送和用户是否存在影响
+func (s *AccountService) send(mail *Mail) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, mail); err != nil {
+			zap.L().Error("send mail failed", zap.String("to", mail.To), zap.String("subject", mail.Subject), zap.Error(err))
+		}
+	}()
+}
+
+// 发送邮箱验证邮件, 之前发送的验证链接作废
+func (s *AccountService) SendVerification(ctx context.Context, user *User) error {
+	if user.Email == "" {
+		return ErrEmailNotSet
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+	if err := s.tokens.RevokeUserTokens(ctx, user.ID, TokenVerifyEmail, s.now()); err != nil {
+		return err
+	}
+	token, err := s.createToken(ctx, user, TokenVerifyEmail, s.cfg.VerifyEmailExpire)
+	if err != nil {
+		return err
+	}
+	s.send(&Mail{
+		To:      user.Email,
+		Subject: "验证你的GBlog邮箱",
+		Body: fmt.Sprintf("%s, 你好:\n\n请打开以下链接验证邮箱, 链接%s内有效:\n%s\n\n如果这不是你的操作, 请忽略本邮件。\n",
+			user.Username, expireText(s.cfg.VerifyEmailExpire), s.link("/verify-email", token)),
+	})
+	return nil
+}
+
+// 已登录用户重新发送验证邮件
+func (s *AccountService) ResendVerification(ctx context.Context, userID uint) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrUserNotExist
+		}
+		return err
+	}
+	return s.SendVerification(ctx, user)
+}
+
+// 使用验证链接中的token验证邮箱, 发送后修改过邮箱的token无效
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	t, err := s.consume(ctx, TokenVerifyEmail, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.SetEmailVerified(ctx, t.UserID, t.Email, s.now()); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUserTokenInvalid
+		}
+		return nil, err
+	}
+	return s.users.GetByID(ctx, t.UserID)
+}
+
+func (s *AccountService) consume(ctx context.Context, purpose UserTokenPurpose, token string) (*UserToken, error) {
+	t, err := s.tokens.ConsumeUserToken(ctx, purpose, hashToken(token), s.now())
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUserTokenInvalid
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// 忘记密码, 向已验证的邮箱发送重置链接; 邮箱不存在或未验证时同样返回成功, 避免泄露用户信息
+func (s *AccountService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.users.GetByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	// 未验证的邮箱可能不属于该用户, 不能用于重置密码
+	if user.EmailVerifiedAt == nil {
+		LoggerFrom(ctx).Info("skip password reset for unverified email", zap.Uint("user_id", user.ID))
+		return nil
+	}
+	if err := s.tokens.RevokeUserTokens(ctx, user.ID, TokenResetPassword, s.now()); err != nil {
+		return err
+	}
+	token, err := s.createToken(ctx, user, TokenResetPassword, s.cfg.ResetPasswordExpire)
+	if err != nil {
+		return err
+	}
+	s.send(&Mail{
+		To:      user.Email,
+		Subject: "重置你的GBlog密码",
+		Body: fmt.Sprintf("%s, 你好:\n\n请打开以下链接重置密码, 链接%s内有效且只能使用一次:\n%s\n\n如果这不是你的操作, 请忽略本邮件, 你的密码不会改变。\n",
+			user.Username, expireText(s.cfg.ResetPasswordExpire), s.link("/reset-password", token)),
+	})
+	return nil
+}
+
+// 使用重置链接中的token设置新密码, 成功后所有登录状态失效
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	t, err := s.consume(ctx, TokenResetPassword, token)
+	if err != nil {
+		return err
+	}
+	user, err := s.users.GetByID(ctx, t.UserID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrUserTokenInvalid
+		}
+		return err
+	}
+	if err := s.setPassword(ctx, user, password); err != nil {
+		return err
+	}
+	s.guard.Succeeded(ctx, user.Username)
+	return nil
+}
+
+// 已登录用户修改密码, 其他登录状态失效, 返回新的token对
+func (s *AccountService) ChangePassword(ctx context.Context, userID uint, oldPassword, newPassword string) (*TokenPair, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrUserNotExist
+		}
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(oldPassword)); err != nil {
+		return nil, ErrOldPasswordIncorrect
+	}
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return nil, err
+	}
+	return s.auth.Issue(ctx, user)
+}
+
+// 保存新密码, 并作废未使用的重置链接和所有token
+func (s *AccountService) setPassword(ctx context.Context, user *User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return err
+	}
+	if err := s.users.SetPassword(ctx, user.ID, string(hash)); err != nil {
+		return err
+	}
+	user.Password = string(hash)
+	if err := s.tokens.RevokeUserTokens(ctx, user.ID, TokenResetPassword, s.now()); err != nil {
+		return err
+	}
+	return s.auth.RevokeUser(ctx, user.ID)
+}
+
+// 个人资料的修改参数, 为nil的字段不修改
+type ProfileInput struct {
+	DisplayName *string
+	AvatarURL   *string
+	Bio         *string
+	Email       *string // 修改后需重新验证, 空字符串表示解绑邮箱
+}
+
+// 查看用户资料, 已注销的用户不存在
+func (s *AccountService) Profile(ctx context.Context, id uint) (*User, error) {
+	user, err := s.users.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// 修改自己的资料, 邮箱变化时发送新的验证邮件
+func (s *AccountService) UpdateProfile(ctx context.Context, userID uint, in ProfileInput) (*User, error) {
+	user, err := s.Profile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if in.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*in.DisplayName)
+	}
+	if in.AvatarURL != nil {
+		user.AvatarURL = strings.TrimSpace(*in.AvatarURL)
+	}
+	if in.Bio != nil {
+		user.Bio = strings.TrimSpace(*in.Bio)
+	}
+	emailChanged := false
+	if in.Email != nil {
+		if email := normalizeEmail(*in.Email); email != user.Email {
+			if email != "" {
+				if owner, err := s.users.GetByEmail(ctx, email); err == nil && owner.ID != user.ID {
+					return nil, ErrEmailExists
+				} else if err != nil && !errors.Is(err, ErrNotFound) {
+					return nil, err
+				}
+			}
+			user.Email, user.EmailVerifiedAt = email, nil
+			emailChanged = true
+		}
+	}
+	if err := s.users.UpdateProfile(ctx, user); err != nil {
+		return nil, err
+	}
+	if emailChanged {
+		// 发往旧邮箱的验证和重置链接作废
+		if err := s.tokens.RevokeUserTokens(ctx, user.ID, TokenResetPassword, s.now()); err != nil {
+			return nil, err
+		}
+		if user.Email == "" {
+			if err := s.tokens.RevokeUserTokens(ctx, user.ID, TokenVerifyEmail, s.now()); err != nil {
+				return nil, err
+			}
+		} else if err := s.SendVerification(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// 注销账号, 设置了密码的账号需要确认密码, 只通过钱包登录的账号需要重新签名SIWE消息;
+// 文章和评论一并删除, 所有登录失效
+func (s *AccountService) DeleteAccount(ctx context.Context, userID uint, password, message, signature string) error {
+	user, err := s.Profile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case user.Password != "":
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return ErrPasswordIncorrect
+		}
+	case user.WalletAddress != nil:
+		// 只有token不足以注销账号, 签名中的nonce只能使用一次
+		if message == "" || signature == "" {
+			return ErrSiweSignatureMissing
+		}
+		addr, err := s.siwe.verify(ctx, message, signature)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(addr, *user.WalletAddress) {
+			return ErrSiweSignatureInvalid
+		}
+	default:
+		return ErrPasswordIncorrect
+	}
+	postIDs, commentIDs, err := s.users.Delete(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrProfileNotFound
+		}
+		return err
+	}
+	if err := s.auth.RevokeUser(ctx, user.ID); err != nil {
+		return err
+	}
+	for _, purpose := range []UserTokenPurpose{TokenVerifyEmail, TokenResetPassword} {
+		if err := s.tokens.RevokeUserTokens(ctx, user.ID, purpose, s.now()); err != nil {
+			return err
+		}
+	}
+	for _, id := range postIDs {
+		if err := s.search.RemovePost(ctx, id); err != nil {
+			LoggerFrom(ctx).Warn("remove post from search index failed", zap.Uint("post_id", id), zap.Error(err))
+		}
+	}
+	for _, id := range commentIDs {
+		if err := s.search.RemoveComment(ctx, id); err != nil {
+			LoggerFrom(ctx).Warn("remove comment from search index failed", zap.Uint("comment_id", id), zap.Error(err))
+		}
+	}
+	LoggerFrom(ctx).Info("account deleted", zap.Int("posts", len(postIDs)), zap.Int("comments", len(commentIDs)))
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/disintegration/imaging"
+	"go.uber.org/zap"
+	_ "golang.org/x/image/webp" // 注册webp解码
+)
+
+var (
+	ErrNoUploadFile       = newAppError(KindInvalid, "no_upload_file", "file is required")
+	ErrFileTooLarge       = newAppError(KindTooLarge, "file_too_large", "file is too large")
+	ErrFileTypeNotAllowed = newAppError(KindUnsupportedMedia, "file_type_not_allowed", "file type is not allowed")
+	ErrInvalidImage       = newAppError(KindUnsupportedMedia, "invalid_image", "image is corrupted or too large")
+	ErrAttachmentNotFound = newAppError(KindNotFound, "attachment_not_found", "can't get attachment")
+)
+
+// 解码前检查像素数, 防止解压炸弹
+const maxImagePixels = 50_000_000
+
+// 常见类型的扩展名, mime.ExtensionsByType返回的顺序不固定
+var uploadExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// 附件服务: 校验上传文件, 保存到BlobStore并生成图片缩略图
+type AttachmentService struct {
+	posts       *PostService
+	attachments AttachmentRepository
+	store       BlobStore
+	cfg         UploadConfig
+	now         func() time.Time
+}
+
+// 单个文件的最大字节数
+func (s *AttachmentService) MaxBytes() int64 {
+	return int64(s.cfg.MaxSize) << 20
+}
+
+// 上传附件到文章, 需要文章的修改权限; 类型按文件内容识别
+func (s *AttachmentService) Upload(ctx context.Context, actor Actor, postID uint, filename string, data []byte) (*Attachment, error) {
+	if _, err := s.posts.getAuthorized(ctx, actor, postID, PermPostUpdateOwn, PermPostUpdateAny); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrNoUploadFile
+	}
+	if int64(len(data)) > s.MaxBytes() {
+		return nil, ErrFileTooLarge
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !slices.Contains(s.cfg.AllowedTypes, contentType) {
+		return nil, ErrFileTypeNotAllowed
+	}
+
+	name, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	ext := uploadExtensions[contentType]
+	if ext == "" {
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	a := &Attachment{
+		PostID:      postID,
+		UserID:      actor.UserID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		FileKey:     s.now().Format("2006/01/") + name + ext,
+	}
+
+	var thumb []byte
+	var thumbType string
+	if isImageType(contentType) {
+		if thumb, thumbType, err = s.thumbnail(a, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.store.Put(ctx, a.FileKey, bytes.NewReader(data), a.Size, contentType); err != nil {
+		return nil, fmt.Errorf("store upload: %w", err)
+	}
+	if thumb != nil {
+		a.ThumbKey = s.now().Format("2006/01/") + name + "_thumb" + uploadExtensions[thumbType]
+		if err := s.store.Put(ctx, a.ThumbKey, bytes.NewReader(thumb), int64(len(thumb)), thumbType); err != nil {
+			s.removeBlobs(ctx, a)
+			return nil, fmt.Errorf("store thumbnail: %w", err)
+		}
+	}
+	if err := s.attachments.Create(ctx, a); err != nil {
+		s.removeBlobs(ctx, a)
+		return nil, err
+	}
+	return a, nil
+}
+
+func isImageType(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// 只保留文件名部分, 过长时截断
+func cleanFilename(name string) string {
+	name = filepath.Base(filepath.FromSlash(name))
+	if name == "." || name == string(filepath.Separator) {
+		return ""
+	}
+	for utf8.RuneCountInString(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// 记录图片尺寸, 宽度超过配置时生成缩略图; png和gif缩略图保存为png以保留透明度
+func (s *AttachmentService) thumbnail(a *Attachment, data []byte) ([]byte, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, "", ErrInvalidImage
+	}
+	a.Width, a.Height = cfg.Width, cfg.Height
+	if cfg.Width <= s.cfg.ThumbnailWidth {
+		return nil, "", nil
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", ErrInvalidImage
+	}
+	thumb := imaging.Resize(img, s.cfg.ThumbnailWidth, 0, imaging.Lanczos)
+
+	format, contentType := imaging.JPEG, "image/jpeg"
+	if a.ContentType == "image/png" || a.ContentType == "image/gif" {
+		format, contentType = imaging.PNG, "image/png"
+	}
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, thumb, format, imaging.JPEGQuality(85)); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// 删除已保存的文件, 失败只记录日志
+func (s *AttachmentService) removeBlobs(ctx context.Context, a *Attachment) {
+	for _, key := range []string{a.FileKey, a.ThumbKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.store.Delete(ctx, key); err != nil {
+			LoggerFrom(ctx).Warn("delete blob failed", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// 文章的附件列表, 与文章可见性相同
+func (s *AttachmentService) List(ctx context.Context, viewerID, postID uint) ([]Attachment, error) {
+	post, err := s.posts.get(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if !post.VisibleTo(viewerID) {
+		return nil, ErrPostNotFound
+	}
+	return s.attachments.ListByPostID(ctx, postID)
+}
+
+// 删除附件及其文件, 需要文章的修改权限
+func (s *AttachmentService) Delete(ctx context.Context, actor Actor, postID, id uint) error {
+	if _, err := s.posts.getAuthorized(ctx, actor, postID, PermPostUpdateOwn, PermPostUpdateAny); err != nil {
+		return err
+	}
+	a, err := s.attachments.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrAttachmentNotFound
+		}
+		return err
+	}
+	if a.PostID != postID {
+		return ErrAttachmentNotFound
+	}
+	if err := s.attachments.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.removeBlobs(ctx, a)
+	return nil
+}
+
+// 打开的附件或缩略图文件
+type AttachmentFile struct {
+	*Attachment
+	io.ReadCloser
+	// 文章已发布, 文件可以公开缓存
+	Public bool
+}
+
+// 打开附件或缩略图文件, 只能打开有附件记录的key; 与文章可见性相同, 未发布文章的文件只有作者能打开
+func (s *AttachmentService) Open(ctx context.Context, viewerID uint, key string) (*AttachmentFile, error) {
+	if key == "" {
+		return nil, ErrAttachmentNotFound
+	}
+	a, err := s.attachments.GetByKey(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	post, err := s.posts.get(ctx, a.PostID)
+	if err != nil {
+		if errors.Is(err, ErrPostNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	if !post.VisibleTo(viewerID) {
+		return nil, ErrAttachmentNotFound
+	}
+	r, err := s.store.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return &AttachmentFile{Attachment: a, ReadCloser: r, Public: post.Status == PostPublished}, nil
+}
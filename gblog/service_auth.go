@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRefreshTokenInvalid = newAppError(KindUnauthorized, "refresh_token_invalid", "refresh token is invalid")
+	ErrRefreshTokenReused  = newAppError(KindUnauthorized, "refresh_token_reused", "refresh token is reused, please login again")
+	ErrLoginLocked         = newAppError(KindTooManyRequests, "login_locked", "too many failed login attempts, please try again later")
+)
+
+// 签发的token对
+type TokenPair struct {
+	UserID          uint
+	AccessToken     string
+	AccessExpiresAt time.Time
+	RefreshToken    string
+}
+
+// 认证服务: 签发/轮换refresh token, 维护jti黑名单
+type AuthService struct {
+	tokens TokenRepository
+	users  UserRepository
+	now    func() time.Time
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// 登录成功后签发新的token对, 开启新的token家族
+func (s *AuthService) Issue(ctx context.Context, user *User) (*TokenPair, error) {
+	family, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, family)
+}
+
+// 登录、刷新都经过这里签发token, 被封禁的用户无法获得新token
+func (s *AuthService) issue(ctx context.Context, user *User, family string) (*TokenPair, error) {
+	if user.SuspendedAt != nil {
+		return nil, ErrUserSuspended
+	}
+	access, claims, err := GenerateToken(user.ID, user.Username, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	rt := &RefreshToken{
+		UserID:          user.ID,
+		TokenHash:       hashToken(refresh),
+		FamilyID:        family,
+		AccessJTI:       claims.ID,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		ExpiresAt:       s.now().Add(time.Duration(jwtConf.RefreshExpire)),
+	}
+	if err := s.tokens.CreateRefreshToken(ctx, rt); err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		UserID:          user.ID,
+		AccessToken:     access,
+		AccessExpiresAt: claims.ExpiresAt.Time,
+		RefreshToken:    refresh,
+	}, nil
+}
+
+// 轮换refresh token; 已使用过的token再次出现视为泄露, 吊销整个家族
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	rt, err := s.tokens.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	if rt.RevokedAt != nil {
+		if err := s.revokeFamily(ctx, rt.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if !s.now().Before(rt.ExpiresAt) {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 先作废旧token, 并发的重复刷新只有一个能成功
+	ok, err := s.tokens.RevokeRefreshToken(ctx, rt.ID, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.revokeFamily(ctx, rt.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.users.GetByID(ctx, rt.UserID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrRefreshTokenInvalid
+		}
+		return nil, err
+	}
+	return s.issue(ctx, user, rt.FamilyID)
+}
+
+// 吊销家族内所有refresh token及其对应的access token
+func (s *AuthService) revokeFamily(ctx context.Context, family string) error {
+	tokens, err := s.tokens.RevokeRefreshFamily(ctx, family, s.now())
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if err := s.tokens.RevokeAccessToken(ctx, t.AccessJTI, t.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 吊销用户所有的refresh token及其对应的access token, 用于修改密码后使其他登录失效
+func (s *AuthService) RevokeUser(ctx context.Context, userID uint) error {
+	tokens, err := s.tokens.RevokeUserRefreshTokens(ctx, userID, s.now())
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		if err := s.tokens.RevokeAccessToken(ctx, t.AccessJTI, t.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 退出登录, refreshToken可为空
+func (s *AuthService) Logout(ctx context.Context, claims *Claims, refreshToken string) error {
+	if err := s.tokens.RevokeAccessToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	rt, err := s.tokens.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	// 只能作废自己的token
+	if rt.UserID != claims.UserID {
+		return nil
+	}
+	return s.revokeFamily(ctx, rt.FamilyID)
+}
+
+// access token是否在黑名单中
+func (s *AuthService) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if jti == "" {
+		// 旧版本签发的token没有jti
+		return false, nil
+	}
+	return s.tokens.IsAccessTokenRevoked(ctx, jti)
+}
+
+// 清理已过期的黑名单和refresh token
+func (s *AuthService) PurgeExpired(ctx context.Context) error {
+	return s.tokens.DeleteExpired(ctx, s.now())
+}
+
+// 登录失败锁定: 同一用户名连续失败达到阈值后锁定, 之后每次失败锁定时间翻倍;
+// 按提交的用户名计数, 不存在的用户名同样会被锁定, 避免据此判断用户是否存在
+type LoginGuard struct {
+	store RateLimitStore
+	cfg   LockoutConfig
+}
+
+func loginFailKey(username string) string {
+	return "login_fail:" + username
+}
+
+// 用户名被锁定时返回ErrLoginLocked; 与限流中间件相同, 存储出错时放行
+func (g *LoginGuard) Check(ctx context.Context, username string) error {
+	d, err := g.store.LockedFor(ctx, loginFailKey(username))
+	if err != nil {
+		LoggerFrom(ctx).Error("check login lock failed", zap.Error(err))
+		return nil
+	}
+	if d > 0 {
+		return ErrLoginLocked
+	}
+	return nil
+}
+
+// 记录一次失败, 达到阈值时锁定; 失败计数在max_duration内没有新的失败后清零
+func (g *LoginGuard) Failed(ctx context.Context, username string) {
+	key := loginFailKey(username)
+	n, err := g.store.Incr(ctx, key, time.Duration(g.cfg.MaxDuration))
+	if err != nil {
+		LoggerFrom(ctx).Error("record login failure failed", zap.Error(err))
+		return
+	}
+	over := n - int64(g.cfg.Threshold)
+	if over < 0 {
+		return
+	}
+	d := time.Duration(g.cfg.MaxDuration)
+	// 避免移位溢出
+	if over < 32 {
+		d = min(d, time.Duration(g.cfg.Duration)<<over)
+	}
+	if err := g.store.Lock(ctx, key, d); err != nil {
+		LoggerFrom(ctx).Error("lock login failed", zap.Error(err))
+		return
+	}
+	LoggerFrom(ctx).Warn("login locked", zap.String("username", username), zap.Int64("failures", n), zap.Duration("duration", d))
+}
+
+// 登录成功后清零失败计数
+func (g *LoginGuard) Succeeded(ctx context.Context, username string) {
+	if err := g.store.Reset(ctx, loginFailKey(username)); err != nil {
+		LoggerFrom(ctx).Error("reset login failures failed", zap.Error(err))
+	}
+}
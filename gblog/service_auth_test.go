@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// 使用内存存储的认证服务, 当前时间由now控制
+func newTestAuthService(t *testing.T, now *time.Time) (*AuthService, *User) {
+	t.Helper()
+	cfg := defaultConfig()
+	cfg.JWT.Secret = testJWTSecret
+	jwtConf = cfg.JWT
+
+	repos := NewMemoryRepositories()
+	user := &User{Username: "alice", Password: "hash", Role: RoleAuthor}
+	if err := repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return &AuthService{tokens: repos.Tokens, users: repos.Users, now: func() time.Time { return *now }}, user
+}
+
+// access token是否已被吊销
+func accessRevoked(t *testing.T, s *AuthService, pair *TokenPair) bool {
+	t.Helper()
+	claims, err := ParseToken(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := s.IsRevoked(context.Background(), claims.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revoked
+}
+
+func TestAuthServiceRefreshRotation(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s, user := newTestAuthService(t, &now)
+
+	first, err := s.Issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken || second.UserID != user.ID {
+		t.Fatalf("refresh did not rotate the token pair: %+v", second)
+	}
+	third, err := s.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Refresh(ctx, "unknown"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("unknown token err = %v, want ErrRefreshTokenInvalid", err)
+	}
+	now = now.Add(time.Duration(jwtConf.RefreshExpire))
+	if _, err := s.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("expired token err = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+// 已轮换的refresh token再次使用视为泄露, 整个家族失效, 其他登录不受影响
+func TestAuthServiceRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s, user := newTestAuthService(t, &now)
+
+	first, err := s.Issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := s.Refresh(ctx, second.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.Issue(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accessRevoked(t, s, third) {
+		t.Fatal("latest access token is revoked before reuse")
+	}
+
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replay err = %v, want ErrRefreshTokenReused", err)
+	}
+	// 家族中最新的token也不能再使用
+	if _, err := s.Refresh(ctx, third.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("refresh after reuse err = %v, want ErrRefreshTokenReused", err)
+	}
+	for i, pair := range []*TokenPair{first, second, third} {
+		if !accessRevoked(t, s, pair) {
+			t.Errorf("access token %d of the family is not revoked", i)
+		}
+	}
+
+	if accessRevoked(t, s, other) {
+		t.Fatal("access token of another login is revoked")
+	}
+	if _, err := s.Refresh(ctx, other.RefreshToken); err != nil {
+		t.Fatalf("refresh another login: %v", err)
+	}
+}
+
+func TestLoginGuardLockout(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := &LoginGuard{
+		store: newTestRateLimitStore(&now),
+		cfg:   LockoutConfig{Threshold: 3, Duration: Duration(time.Minute), MaxDuration: Duration(5 * time.Minute)},
+	}
+	locked := func() bool {
+		t.Helper()
+		err := g.Check(ctx, "alice")
+		if err != nil && !errors.Is(err, ErrLoginLocked) {
+			t.Fatal(err)
+		}
+		return err != nil
+	}
+
+	g.Failed(ctx, "alice")
+	g.Failed(ctx, "alice")
+	if locked() {
+		t.Fatal("locked before reaching the threshold")
+	}
+	g.Failed(ctx, "alice")
+	if !locked() {
+		t.Fatal("not locked after reaching the threshold")
+	}
+	if err := g.Check(ctx, "bob"); err != nil {
+		t.Fatalf("other username: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if locked() {
+		t.Fatal("still locked after the lockout duration")
+	}
+
+	// 之后每次失败锁定时间翻倍, 不超过max_duration
+	for _, d := range []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		g.Failed(ctx, "alice")
+		now = now.Add(d - time.Second)
+		if !locked() {
+			t.Fatalf("unlocked before %s", d)
+		}
+		now = now.Add(time.Second)
+		if locked() {
+			t.Fatalf("still locked after %s", d)
+		}
+	}
+
+	// 锁定max_duration后计数也已过期, 重新从阈值开始计算
+	g.Failed(ctx, "alice")
+	g.Failed(ctx, "alice")
+	if locked() {
+		t.Fatal("failures were not reset after max_duration")
+	}
+	g.Failed(ctx, "alice")
+	if !locked() {
+		t.Fatal("not locked after reaching the threshold again")
+	}
+
+	// 登录成功后清零
+	now = now.Add(time.Minute)
+	g.Succeeded(ctx, "alice")
+	g.Failed(ctx, "alice")
+	g.Failed(ctx, "alice")
+	if locked() {
+		t.Fatal("locked after a successful login reset the failures")
+	}
+}
+
+// 存储出错时不锁定, 与限流中间件一致
+func TestLoginGuardFailsOpen(t *testing.T) {
+	ctx := context.Background()
+	g := &LoginGuard{store: failingRateLimitStore{}, cfg: LockoutConfig{Threshold: 1, Duration: Duration(time.Minute), MaxDuration: Duration(time.Hour)}}
+	g.Failed(ctx, "alice")
+	g.Succeeded(ctx, "alice")
+	if err := g.Check(ctx, "alice"); err != nil {
+		t.Fatalf("Check with failing store = %v, want nil", err)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrEmptyCommentContent  = newAppError(KindInvalid, "empty_comment_content", "comment content is empty")
+	ErrCommentNotFound      = newAppError(KindNotFound, "comment_not_found", "can't get comment")
+	ErrCommentNotOwned      = newAppError(KindForbidden, "comment_not_owned", "comment is not belongs to the user")
+	ErrParentCommentInvalid = newAppError(KindInvalid, "parent_comment_invalid", "parent comment is not valid")
+	ErrCommentTooDeep       = newAppError(KindInvalid, "comment_too_deep", "comment nesting is too deep")
+)
+
+type CommentService struct {
+	posts         PostRepository
+	comments      CommentRepository
+	search        Searcher
+	notifications *NotificationService
+	broker        Broker
+	maxDepth      int // 最大嵌套层数, 顶层评论为第1层
+	now           func() time.Time
+}
+
+// 发表评论, parentID为0时为顶层评论, 否则为对该评论的回复
+func (s *CommentService) Create(ctx context.Context, userID, postID, parentID uint, content string) (*Comment, error) {
+	if content == "" {
+		return nil, ErrEmptyCommentContent
+	}
+	post, err := s.checkPost(ctx, userID, postID)
+	if err != nil {
+		return nil, err
+	}
+	comment := &Comment{
+		Content: content,
+		UserID:  userID,
+		PostID:  postID,
+	}
+	var parent *Comment
+	if parentID != 0 {
+		parent, err = s.comments.GetByID(ctx, parentID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil, ErrParentCommentInvalid
+			}
+			return nil, err
+		}
+		if parent.PostID != postID {
+			return nil, ErrParentCommentInvalid
+		}
+		if parent.Depth+1 >= s.maxDepth {
+			return nil, ErrCommentTooDeep
+		}
+		comment.ParentID = &parent.ID
+		comment.Depth = parent.Depth + 1
+	}
+	if err := s.comments.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+	s.syncIndex(ctx, comment)
+	s.notifications.CommentCreated(ctx, post, parent, comment)
+	s.publish(ctx, commentEventCreated, comment)
+	return comment, nil
+}
+
+// 文章评论的推送主题
+func commentTopic(postID uint) string {
+	return fmt.Sprintf("comments:%d", postID)
+}
+
+// 推送评论事件给正在查看该文章的用户, 失败时只记录日志
+func (s *CommentService) publish(ctx context.Context, event string, comment *Comment) {
+	payload, err := commentEvent(event, comment)
+	if err != nil {
+		return
+	}
+	if err := s.broker.Publish(ctx, commentTopic(comment.PostID), payload); err != nil {
+		LoggerFrom(ctx).Warn("publish comment failed", zap.Uint("comment_id", comment.ID), zap.Error(err))
+	}
+}
+
+// 订阅文章的新评论, 只有能看到文章的用户可以订阅
+func (s *CommentService) Subscribe(ctx context.Context, viewerID, postID uint) (<-chan []byte, error) {
+	if _, err := s.checkPost(ctx, viewerID, postID); err != nil {
+		return nil, err
+	}
+	return s.broker.Subscribe(ctx, commentTopic(postID))
+}
+
+// 校验文章存在且对用户可见, 未发布文章的评论同样只有作者可见
+func (s *CommentService) checkPost(ctx context.Context, viewerID, postID uint) (*Post, error) {
+	post, err := s.posts.GetByID(ctx, postID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrPostNotFound
+		}
+		return nil, err
+	}
+	if !post.VisibleTo(viewerID) {
+		return nil, ErrPostNotFound
+	}
+	return post, nil
+}
+
+// 获取文章下的评论
+func (s *CommentService) get(ctx context.Context, postID, id uint) (*Comment, error) {
+	comment, err := s.comments.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	if comment.PostID != postID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// 修改评论内容, 只有作者本人可以修改; 看不到文章时返回文章不存在
+func (s *CommentService) Update(ctx context.Context, actor Actor, postID, id uint, content string) (*Comment, error) {
+	if content == "" {
+		return nil, ErrEmptyCommentContent
+	}
+	if _, err := s.checkPost(ctx, actor.UserID, postID); err != nil {
+		return nil, err
+	}
+	comment, err := s.get(ctx, postID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != actor.UserID || !actor.Can(PermCommentUpdateOwn) {
+		return nil, ErrCommentNotOwned
+	}
+	now := s.now()
+	comment.Content = content
+	comment.EditedAt = &now
+	if err := s.comments.Update(ctx, comment); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	s.syncIndex(ctx, comment)
+	return comment, nil
+}
+
+// 同步搜索索引, 失败不影响评论本身的写入
+func (s *CommentService) syncIndex(ctx context.Context, comment *Comment) {
+	if err := s.search.IndexComment(ctx, comment); err != nil {
+		LoggerFrom(ctx).Warn("index comment failed", zap.Uint("comment_id", comment.ID), zap.Error(err))
+	}
+}
+
+// 按时间顺序分页查询文章评论
+func (s *CommentService) ListByPost(ctx context.Context, viewerID, postID uint, page Page) ([]Comment, PageMeta, error) {
+	if page.After != nil && page.After.Sort != "id" {
+		return nil, PageMeta{}, ErrInvalidCursor
+	}
+	if _, err := s.checkPost(ctx, viewerID, postID); err != nil {
+		return nil, PageMeta{}, err
+	}
+
+	limit := page.Limit
+	page.Limit = limit + 1
+	comments, total, err := s.comments.ListByPostID(ctx, postID, page)
+	if err != nil {
+		return nil, PageMeta{}, err
+	}
+	comments, more := trimPage(comments, limit)
+
+	meta := PageMeta{Total: total, PageSize: limit, HasMore: more}
+	if more {
+		meta.NextCursor = Cursor{Sort: "id", ID: comments[len(comments)-1].ID}.Encode()
+	}
+	return comments, meta, nil
+}
+
+// 评论树节点, 已删除但仍有回复的评论保留为占位节点
+type CommentNode struct {
+	ID        uint           `json:"id"`
+	ParentID  *uint          `json:"parent_id"`
+	UserID    uint           `json:"user_id,omitempty"`
+	Content   string         `json:"content"`
+	Deleted   bool           `json:"deleted"`
+	CreatedAt time.Time      `json:"created_at"`
+	EditedAt  *time.Time     `json:"edited_at,omitempty"`
+	Replies   []*CommentNode `json:"replies"`
+}
+
+// 查询文章的评论树, 按顶层评论分页, 每个顶层评论带全部回复
+//
+// 先分页查询顶层评论, 再逐层查询这一页的回复, 查询次数不超过最大嵌套深度
+func (s *CommentService) Tree(ctx context.Context, viewerID, postID uint, page Page) ([]*CommentNode, PageMeta, error) {
+	if page.After != nil && page.After.Sort != "id" {
+		return nil, PageMeta{}, ErrInvalidCursor
+	}
+	if _, err := s.checkPost(ctx, viewerID, postID); err != nil {
+		return nil, PageMeta{}, err
+	}
+
+	limit := page.Limit
+	page.Limit = limit + 1
+	roots, total, err := s.comments.ListRoots(ctx, postID, page)
+	if err != nil {
+		return nil, PageMeta{}, err
+	}
+	roots, more := trimPage(roots, limit)
+
+	// 逐层追加, 父评论总在回复之前
+	thread := roots
+	for level := roots; len(level) > 0; {
+		parentIDs := make([]uint, len(level))
+		for i := range level {
+			parentIDs[i] = level[i].ID
+		}
+		if level, err = s.comments.ListReplies(ctx, parentIDs); err != nil {
+			return nil, PageMeta{}, err
+		}
+		thread = append(thread, level...)
+	}
+
+	// 已删除且回复也都已删除的顶层评论会被去掉, 这一页可能少于page_size
+	meta := PageMeta{Total: total, PageSize: limit, HasMore: more}
+	if more {
+		meta.NextCursor = Cursor{Sort: "id", ID: roots[len(roots)-1].ID}.Encode()
+	}
+	return buildCommentTree(thread), meta, nil
+}
+
+// 按父评论在前的顺序构建评论树, 去掉没有未删除回复的已删除评论
+func buildCommentTree(comments []Comment) []*CommentNode {
+	nodes := make(map[uint]*CommentNode, len(comments))
+	roots := make([]*CommentNode, 0)
+	for _, c := range comments {
+		node := &CommentNode{
+			ID:        c.ID,
+			ParentID:  c.ParentID,
+			UserID:    c.UserID,
+			Content:   c.Content,
+			CreatedAt: c.CreatedAt,
+			EditedAt:  c.EditedAt,
+			Replies:   make([]*CommentNode, 0),
+		}
+		if c.DeletedAt.Valid {
+			node.Deleted = true
+			node.UserID, node.Content, node.EditedAt = 0, "", nil
+		}
+		nodes[c.ID] = node
+		// 回复的id总是大于父评论, 父评论已先加入
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Replies = append(parent.Replies, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return pruneDeleted(roots)
+}
+
+func pruneDeleted(nodes []*CommentNode) []*CommentNode {
+	kept := nodes[:0]
+	for _, n := range nodes {
+		n.Replies = pruneDeleted(n.Replies)
+		if n.Deleted && len(n.Replies) == 0 {
+			continue
+		}
+		kept = append(kept, n)
+	}
+	return kept
+}
+
+// 软删除评论: 评论作者可删除自己的评论, 版主和管理员可删除任意评论; 回复保留
+func (s *CommentService) Delete(ctx context.Context, actor Actor, postID, id uint) error {
+	if _, err := s.checkPost(ctx, actor.UserID, postID); err != nil {
+		return err
+	}
+	comment, err := s.get(ctx, postID, id)
+	if err != nil {
+		return err
+	}
+	if !actor.CanOn(comment.UserID, PermCommentDeleteOwn, PermCommentDeleteAny) {
+		return ErrCommentNotOwned
+	}
+	if err := s.comments.Delete(ctx, id); err != nil {
+		return err
+	}
+	if err := s.search.RemoveComment(ctx, id); err != nil {
+		LoggerFrom(ctx).Warn("remove comment from search index failed", zap.Uint("comment_id", id), zap.Error(err))
+	}
+	return nil
+}
+
+// 统计文章的评论数
+func (s *CommentService) Count(ctx context.Context, postID uint) (int64, error) {
+	counts, err := s.comments.CountByPostIDs(ctx, []uint{postID})
+	if err != nil {
+		return 0, err
+	}
+	return counts[postID], nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 浏览数缓冲: 请求只在内存中累加, 由后台任务定期批量写入
+type ViewCounter struct {
+	mu      sync.Mutex
+	pending map[uint]int64
+	repo    EngagementRepository
+}
+
+func NewViewCounter(repo EngagementRepository) *ViewCounter {
+	return &ViewCounter{pending: make(map[uint]int64), repo: repo}
+}
+
+func (v *ViewCounter) Add(postID uint) {
+	v.mu.Lock()
+	v.pending[postID]++
+	v.mu.Unlock()
+}
+
+// 尚未写入的浏览数
+func (v *ViewCounter) Pending(postID uint) int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.pending[postID]
+}
+
+// 写入缓冲的浏览数, 失败时放回缓冲等待下次写入
+func (v *ViewCounter) Flush(ctx context.Context) error {
+	v.mu.Lock()
+	views := v.pending
+	v.pending = make(map[uint]int64)
+	v.mu.Unlock()
+	if len(views) == 0 {
+		return nil
+	}
+
+	if err := v.repo.AddViews(ctx, views); err != nil {
+		v.mu.Lock()
+		for id, n := range views {
+			v.pending[id] += n
+		}
+		v.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// 热门文章中一条评论相当于多少个点赞
+const trendingCommentWeight = 2
+
+// 点赞、收藏、浏览数和排行榜
+type EngagementService struct {
+	posts          *PostService
+	engagement     EngagementRepository
+	views          *ViewCounter
+	trendingWindow time.Duration
+	now            func() time.Time
+}
+
+// 带排名分数的文章
+type RankedPost struct {
+	Post  *Post
+	Score int64
+}
+
+// 点赞, 需要能看到文章; 返回最新点赞数
+func (s *EngagementService) Like(ctx context.Context, userID, postID uint) (int64, error) {
+	if err := s.checkVisible(ctx, userID, postID); err != nil {
+		return 0, err
+	}
+	if _, err := s.engagement.Like(ctx, userID, postID); err != nil {
+		return 0, err
+	}
+	return s.likeCount(ctx, postID)
+}
+
+// 取消点赞, 文章已删除或不可见时也可以取消
+func (s *EngagementService) Unlike(ctx context.Context, userID, postID uint) (int64, error) {
+	if _, err := s.engagement.Unlike(ctx, userID, postID); err != nil {
+		return 0, err
+	}
+	return s.likeCount(ctx, postID)
+}
+
+func (s *EngagementService) checkVisible(ctx context.Context, userID, postID uint) error {
+	post, err := s.posts.get(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if !post.VisibleTo(userID) {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+func (s *EngagementService) likeCount(ctx context.Context, postID uint) (int64, error) {
+	counts, err := s.engagement.CountLikes(ctx, []uint{postID})
+	if err != nil {
+		return 0, err
+	}
+	return counts[postID], nil
+}
+
+func (s *EngagementService) Bookmark(ctx context.Context, userID, postID uint) error {
+	if err := s.checkVisible(ctx, userID, postID); err != nil {
+		return err
+	}
+	_, err := s.engagement.Bookmark(ctx, userID, postID)
+	return err
+}
+
+func (s *EngagementService) Unbookmark(ctx context.Context, userID, postID uint) error {
+	_, err := s.engagement.Unbookmark(ctx, userID, postID)
+	return err
+}
+
+// 用户是否点赞、收藏了文章
+func (s *EngagementService) Status(ctx context.Context, userID, postID uint) (liked, bookmarked bool, err error) {
+	return s.engagement.Status(ctx, userID, postID)
+}
+
+// 用户的收藏, 已删除和对用户不可见的文章不返回
+func (s *EngagementService) Bookmarks(ctx context.Context, userID uint, page Page) ([]Post, int64, error) {
+	posts, total, err := s.engagement.ListBookmarks(ctx, userID, page)
+	if err != nil {
+		return nil, 0, err
+	}
+	ptrs := make([]*Post, 0, len(posts))
+	for i := range posts {
+		ptrs = append(ptrs, &posts[i])
+	}
+	if err := s.posts.fill(ctx, ptrs...); err != nil {
+		return nil, 0, err
+	}
+	return posts, total, nil
+}
+
+// 记录一次浏览, 返回的文章浏览数同时加1
+func (s *EngagementService) RecordView(post *Post) {
+	s.views.Add(post.ID)
+	post.ViewCount++
+}
+
+func (s *EngagementService) FlushViews(ctx context.Context) error {
+	return s.views.Flush(ctx)
+}
+
+// 点赞数最多的已发布文章
+func (s *EngagementService) MostLiked(ctx context.Context, limit int) ([]RankedPost, error) {
+	scores, err := s.engagement.MostLiked(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.ranked(ctx, scores)
+}
+
+// 热门文章: 统计窗口内的点赞数加上加权的评论数, 分数相同时浏览数多的在前
+func (s *EngagementService) Trending(ctx context.Context, limit int) ([]RankedPost, error) {
+	scores, err := s.engagement.Trending(ctx, s.now().Add(-s.trendingWindow), trendingCommentWeight, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.ranked(ctx, scores)
+}
+
+// 按分数顺序加载文章, 查询后被删除的文章跳过
+func (s *EngagementService) ranked(ctx context.Context, scores []PostScore) ([]RankedPost, error) {
+	ranked := make([]RankedPost, 0, len(scores))
+	posts := make([]*Post, 0, len(scores))
+	for _, sc := range scores {
+		post, err := s.posts.get(ctx, sc.PostID)
+		if errors.Is(err, ErrPostNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ranked = append(ranked, RankedPost{Post: post, Score: sc.Score})
+		posts = append(posts, post)
+	}
+	if err := s.posts.fill(ctx, posts...); err != nil {
+		return nil, err
+	}
+	return ranked, nil
+}
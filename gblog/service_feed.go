@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+var ErrFollowSelf = newAppError(KindInvalid, "follow_self", "can't follow yourself")
+
+// 首页动态模式, 见FeedConfig.Mode
+const (
+	feedModeRead  = "read"
+	feedModeWrite = "write"
+)
+
+// 首页动态按创建时间倒序, 两种模式使用相同的游标格式
+var feedSort = PostSort{Field: "created_at", Desc: true}
+
+// 首页时间线缓存, 保存每个用户动态中的文章id; 可替换为Redis等共享缓存
+type TimelineCache interface {
+	// 用户的时间线, 按文章id倒序; 未缓存时ok为false
+	Get(userID uint) (ids []uint, ok bool)
+	Set(userID uint, ids []uint)
+	// 将文章写入这些用户已缓存的时间线, 未缓存的用户跳过
+	Push(userIDs []uint, postID uint)
+	Invalidate(userID uint)
+}
+
+// 进程内的时间线缓存, 每个用户最多保存size篇文章
+type memoryTimelineCache struct {
+	mu    sync.Mutex
+	size  int
+	lines map[uint][]uint
+}
+
+func NewMemoryTimelineCache(size int) TimelineCache {
+	return &memoryTimelineCache{size: size, lines: make(map[uint][]uint)}
+}
+
+func (c *memoryTimelineCache) Get(userID uint) ([]uint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ids, ok := c.lines[userID]
+	return slices.Clone(ids), ok
+}
+
+func (c *memoryTimelineCache) Set(userID uint, ids []uint) {
+	ids = slices.Clone(ids)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	c.mu.Lock()
+	c.lines[userID] = ids[:min(len(ids), c.size)]
+	c.mu.Unlock()
+}
+
+func (c *memoryTimelineCache) Push(userIDs []uint, postID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, uid := range userIDs {
+		ids, ok := c.lines[uid]
+		if !ok {
+			continue
+		}
+		// 重新发布的文章可能已在时间线中
+		i := sort.Search(len(ids), func(i int) bool { return ids[i] <= postID })
+		if i < len(ids) && ids[i] == postID {
+			continue
+		}
+		ids = slices.Insert(ids, i, postID)
+		c.lines[uid] = ids[:min(len(ids), c.size)]
+	}
+}
+
+func (c *memoryTimelineCache) Invalidate(userID uint) {
+	c.mu.Lock()
+	delete(c.lines, userID)
+	c.mu.Unlock()
+}
+
+// 关注关系和首页动态
+type FeedService struct {
+	users         UserRepository
+	follows       FollowRepository
+	posts         *PostService
+	notifications *NotificationService
+	// 为nil时使用fan-out-on-read, 每次读取时查询关注的作者的文章
+	timeline     TimelineCache
+	timelineSize int
+}
+
+func (s *FeedService) Follow(ctx context.Context, followerID, followeeID uint) error {
+	if followerID == followeeID {
+		return ErrFollowSelf
+	}
+	if _, err := s.users.GetByID(ctx, followeeID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrUserNotExist
+		}
+		return err
+	}
+	created, err := s.follows.Follow(ctx, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if created {
+		s.notifications.Followed(ctx, followerID, followeeID)
+		if s.timeline != nil {
+			s.timeline.Invalidate(followerID)
+		}
+	}
+	return nil
+}
+
+func (s *FeedService) Unfollow(ctx context.Context, followerID, followeeID uint) error {
+	removed, err := s.follows.Unfollow(ctx, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if removed && s.timeline != nil {
+		s.timeline.Invalidate(followerID)
+	}
+	return nil
+}
+
+func (s *FeedService) Followers(ctx context.Context, userID uint, page Page) ([]User, int64, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.follows.ListFollowers(ctx, userID, page)
+}
+
+func (s *FeedService) Following(ctx context.Context, userID uint, page Page) ([]User, int64, error) {
+	if err := s.checkUser(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.follows.ListFollowing(ctx, userID, page)
+}
+
+func (s *FeedService) checkUser(ctx context.Context, userID uint) error {
+	if _, err := s.users.GetByID(ctx, userID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrUserNotExist
+		}
+		return err
+	}
+	return nil
+}
+
+// 文章发布后写入关注者的时间线, 失败时只记录日志, 对应的缓存作废后重建
+func (s *FeedService) PostPublished(ctx context.Context, post *Post) {
+	if s.timeline == nil {
+		return
+	}
+	ids, err := s.follows.FollowerIDs(ctx, post.UserID)
+	if err != nil {
+		LoggerFrom(ctx).Warn("fan out post to timelines failed", zap.Uint("post_id", post.ID), zap.Error(err))
+		return
+	}
+	s.timeline.Push(ids, post.ID)
+}
+
+// 用户的首页动态
+func (s *FeedService) Feed(ctx context.Context, userID uint, page Page) ([]Post, PageMeta, error) {
+	if page.After != nil && page.After.Sort != feedSort.String() {
+		return nil, PageMeta{}, ErrInvalidCursor
+	}
+	if s.timeline == nil {
+		return s.posts.List(ctx, s.feedQuery(userID, page))
+	}
+
+	ids, ok := s.timeline.Get(userID)
+	if !ok {
+		var err error
+		if ids, err = s.rebuildTimeline(ctx, userID); err != nil {
+			return nil, PageMeta{}, err
+		}
+	}
+
+	start := page.Offset
+	if page.After != nil {
+		start = sort.Search(len(ids), func(i int) bool { return ids[i] < page.After.ID })
+	}
+	// 缓存中的文章可能已被删除或撤回, 读取时跳过
+	posts := make([]Post, 0, page.Limit)
+	i := start
+	for ; i < len(ids) && len(posts) < page.Limit; i++ {
+		post, err := s.posts.get(ctx, ids[i])
+		if errors.Is(err, ErrPostNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, PageMeta{}, err
+		}
+		if post.Status == PostPublished {
+			posts = append(posts, *post)
+		}
+	}
+	ptrs := make([]*Post, len(posts))
+	for j := range posts {
+		ptrs[j] = &posts[j]
+	}
+	if err := s.posts.fill(ctx, ptrs...); err != nil {
+		return nil, PageMeta{}, err
+	}
+
+	meta := PageMeta{Total: int64(len(ids)), PageSize: page.Limit, HasMore: i < len(ids)}
+	if meta.HasMore && len(posts) > 0 {
+		last := posts[len(posts)-1]
+		meta.NextCursor = Cursor{Sort: feedSort.String(), Value: postSortValue(last, feedSort.Field), ID: last.ID}.Encode()
+	}
+	return posts, meta, nil
+}
+
+func (s *FeedService) feedQuery(userID uint, page Page) PostQuery {
+	return PostQuery{
+		FollowedBy: userID,
+		Status:     PostPublished,
+		ViewerID:   userID,
+		Sort:       feedSort,
+		Page:       page,
+	}
+}
+
+// 按fan-out-on-read查询最近的文章重建时间线
+func (s *FeedService) rebuildTimeline(ctx context.Context, userID uint) ([]uint, error) {
+	posts, _, err := s.posts.posts.List(ctx, s.feedQuery(userID, Page{Limit: s.timelineSize}))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(posts))
+	for _, p := range posts {
+		ids = append(ids, p.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	s.timeline.Set(userID, ids)
+	return ids, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrNotificationNotFound = newAppError(KindNotFound, "notification_not_found", "can't get notification")
+
+// 单条文章或评论最多通知的@用户数
+const maxMentions = 10
+
+// @用户名, 用户名由字母、数字和 _ . - 组成; @前不能是字母或数字, 避免匹配邮箱
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])@([\p{L}\p{N}_.\-]+)`)
+
+// 通知的创建、查询和推送; 通知失败只记录日志, 不影响触发通知的操作
+type NotificationService struct {
+	notifications NotificationRepository
+	users         UserRepository
+	broker        Broker
+	now           func() time.Time
+}
+
+// 用户的推送主题
+func notificationTopic(userID uint) string {
+	return fmt.Sprintf("notifications:%d", userID)
+}
+
+// 保存通知并推送给在线的接收者, 不通知自己
+func (s *NotificationService) notify(ctx context.Context, n *Notification) {
+	if n.UserID == n.ActorID {
+		return
+	}
+	if err := s.notifications.Create(ctx, n); err != nil {
+		LoggerFrom(ctx).Warn("create notification failed", zap.Uint("recipient_id", n.UserID), zap.String("type", string(n.Type)), zap.Error(err))
+		return
+	}
+	if err := s.fillActors(ctx, n); err != nil {
+		LoggerFrom(ctx).Warn("load notification actor failed", zap.Uint("notification_id", n.ID), zap.Error(err))
+	}
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return
+	}
+	if err := s.broker.Publish(ctx, notificationTopic(n.UserID), payload); err != nil {
+		LoggerFrom(ctx).Warn("publish notification failed", zap.Uint("notification_id", n.ID), zap.Error(err))
+	}
+}
+
+// 新评论: 通知文章作者, 回复时通知被回复的评论作者, 以及评论中@的用户
+func (s *NotificationService) CommentCreated(ctx context.Context, post *Post, parent, comment *Comment) {
+	notified := map[uint]bool{comment.UserID: true}
+	if parent != nil {
+		s.notify(ctx, &Notification{UserID: parent.UserID, ActorID: comment.UserID, Type: NotifyReply, PostID: post.ID, CommentID: comment.ID})
+		notified[parent.UserID] = true
+	}
+	if !notified[post.UserID] {
+		s.notify(ctx, &Notification{UserID: post.UserID, ActorID: comment.UserID, Type: NotifyComment, PostID: post.ID, CommentID: comment.ID})
+		notified[post.UserID] = true
+	}
+	s.mentions(ctx, post, comment.Content, &Notification{ActorID: comment.UserID, Type: NotifyMention, PostID: post.ID, CommentID: comment.ID}, notified)
+}
+
+// 文章发布后通知正文中@的用户
+func (s *NotificationService) PostPublished(ctx context.Context, post *Post) {
+	notified := map[uint]bool{post.UserID: true}
+	s.mentions(ctx, post, post.Content, &Notification{ActorID: post.UserID, Type: NotifyMention, PostID: post.ID}, notified)
+}
+
+func (s *NotificationService) Followed(ctx context.Context, followerID, followeeID uint) {
+	s.notify(ctx, &Notification{UserID: followeeID, ActorID: followerID, Type: NotifyFollow})
+}
+
+// 通知文本中@的用户, 跳过已通知、不存在和看不到文章的用户
+func (s *NotificationService) mentions(ctx context.Context, post *Post, text string, tmpl *Notification, notified map[uint]bool) {
+	for _, name := range parseMentions(text) {
+		user, err := s.users.GetByUsername(ctx, name)
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				LoggerFrom(ctx).Warn("load mentioned user failed", zap.String("username", name), zap.Error(err))
+			}
+			continue
+		}
+		if notified[user.ID] || !post.VisibleTo(user.ID) {
+			continue
+		}
+		notified[user.ID] = true
+		n := *tmpl
+		n.UserID = user.ID
+		s.notify(ctx, &n)
+	}
+}
+
+// 文本中@的用户名, 去重后最多maxMentions个
+func parseMentions(text string) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// 句末的标点不是用户名的一部分
+		name := strings.TrimRight(m[1], ".-")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		if len(names) == maxMentions {
+			break
+		}
+	}
+	return names
+}
+
+// 填充触发者用户名, 已删除的用户留空
+func (s *NotificationService) fillActors(ctx context.Context, notifications ...*Notification) error {
+	names := make(map[uint]string)
+	for _, n := range notifications {
+		name, ok := names[n.ActorID]
+		if !ok {
+			user, err := s.users.GetByID(ctx, n.ActorID)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if user != nil {
+				name = user.Username
+			}
+			names[n.ActorID] = name
+		}
+		n.Actor = name
+	}
+	return nil
+}
+
+// 按时间倒序分页查询通知, 游标只使用id
+func (s *NotificationService) List(ctx context.Context, userID uint, unreadOnly bool, page Page) ([]Notification, PageMeta, error) {
+	if page.After != nil && page.After.Sort != "-id" {
+		return nil, PageMeta{}, ErrInvalidCursor
+	}
+	limit := page.Limit
+	page.Limit = limit + 1
+	notifications, total, err := s.notifications.ListByUserID(ctx, userID, unreadOnly, page)
+	if err != nil {
+		return nil, PageMeta{}, err
+	}
+	notifications, more := trimPage(notifications, limit)
+	ptrs := make([]*Notification, len(notifications))
+	for i := range notifications {
+		ptrs[i] = &notifications[i]
+	}
+	if err := s.fillActors(ctx, ptrs...); err != nil {
+		return nil, PageMeta{}, err
+	}
+
+	meta := PageMeta{Total: total, PageSize: limit, HasMore: more}
+	if more {
+		meta.NextCursor = Cursor{Sort: "-id", ID: notifications[len(notifications)-1].ID}.Encode()
+	}
+	return notifications, meta, nil
+}
+
+func (s *NotificationService) UnreadCount(ctx context.Context, userID uint) (int64, error) {
+	return s.notifications.CountUnread(ctx, userID)
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id uint) error {
+	if err := s.notifications.MarkRead(ctx, userID, id, s.now()); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrNotificationNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// 全部标记已读, 返回本次标记的数量
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID uint) (int64, error) {
+	return s.notifications.MarkAllRead(ctx, userID, s.now())
+}
+
+// 订阅用户的新通知, 消息为通知的json
+func (s *NotificationService) Subscribe(ctx context.Context, userID uint) (<-chan []byte, error) {
+	return s.broker.Subscribe(ctx, notificationTopic(userID))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var (
+	ErrRevisionNotFound = newAppError(KindNotFound, "revision_not_found", "can't get revision")
+	ErrInvalidRevision  = newAppError(KindInvalid, "invalid_revision", "revision range is not valid")
+)
+
+const (
+	revisionNoteInitial  = "initial"
+	revisionNoteRollback = "rollback to v%d"
+)
+
+// 记录修订: 标题和正文都未变化时不记录; 功能上线前创建的文章先补记修改前的版本
+func (s *PostService) recordRevision(ctx context.Context, before, post *Post, authorID uint, note string) error {
+	if before != nil {
+		if before.Title == post.Title && before.Content == post.Content {
+			return nil
+		}
+		if _, err := s.revisions.Latest(ctx, post.ID); errors.Is(err, ErrNotFound) {
+			base := &PostRevision{
+				PostID:    post.ID,
+				Title:     before.Title,
+				Content:   before.Content,
+				AuthorID:  before.UserID,
+				Note:      revisionNoteInitial,
+				CreatedAt: before.UpdatedAt,
+			}
+			if err := s.revisions.Create(ctx, base); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	return s.revisions.Create(ctx, &PostRevision{
+		PostID:   post.ID,
+		Title:    post.Title,
+		Content:  post.Content,
+		AuthorID: authorID,
+		Note:     note,
+	})
+}
+
+// 修订历史与文章修改权限相同: 作者本人, 或拥有修改任意文章权限的角色
+func (s *PostService) Revisions(ctx context.Context, actor Actor, postID uint, page Page) ([]PostRevision, int64, error) {
+	if _, err := s.getAuthorized(ctx, actor, postID, PermPostUpdateOwn, PermPostUpdateAny); err != nil {
+		return nil, 0, err
+	}
+	return s.revisions.ListByPostID(ctx, postID, page)
+}
+
+func (s *PostService) Revision(ctx context.Context, actor Actor, postID uint, version int) (*PostRevision, error) {
+	if _, err := s.getAuthorized(ctx, actor, postID, PermPostUpdateOwn, PermPostUpdateAny); err != nil {
+		return nil, err
+	}
+	return s.getRevision(ctx, postID, version)
+}
+
+func (s *PostService) getRevision(ctx context.Context, postID uint, version int) (*PostRevision, error) {
+	rev, err := s.revisions.GetByVersion(ctx, postID, version)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return rev, nil
+}
+
+// 修订之间的差异
+type RevisionDiff struct {
+	From int
+	To   int
+	Diff string // unified diff, 第一行为标题
+}
+
+// 比较两个版本, to为0时取最新版本, from为0时取to的上一版本
+func (s *PostService) DiffRevisions(ctx context.Context, actor Actor, postID uint, from, to int) (*RevisionDiff, error) {
+	if _, err := s.getAuthorized(ctx, actor, postID, PermPostUpdateOwn, PermPostUpdateAny); err != nil {
+		return nil, err
+	}
+	if to == 0 {
+		latest, err := s.revisions.Latest(ctx, postID)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return nil, ErrRevisionNotFound
+			}
+			return nil, err
+		}
+		to = latest.Version
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from <= 0 || from == to {
+		return nil, ErrInvalidRevision
+	}
+
+	a, err := s.getRevision(ctx, postID, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.getRevision(ctx, postID, to)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(revisionText(a)),
+		B:        difflib.SplitLines(revisionText(b)),
+		FromFile: fmt.Sprintf("v%d", from),
+		FromDate: a.CreatedAt.Format("2006-01-02 15:04:05"),
+		ToFile:   fmt.Sprintf("v%d", to),
+		ToDate:   b.CreatedAt.Format("2006-01-02 15:04:05"),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RevisionDiff{From: from, To: to, Diff: diff}, nil
+}
+
+// 参与比较的文本: 标题, 空行, 正文
+func revisionText(rev *PostRevision) string {
+	text := rev.Title + "\n\n" + rev.Content
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return text
+}
+
+// 回滚标题和正文到指定版本, 标签、分类和状态不变
+func (s *PostService) Rollback(ctx context.Context, actor Actor, postID uint, version int) (*Post, error) {
+	post, err := s.getAuthorized(ctx, actor, postID, PermPostUpdateOwn, PermPostUpdateAny)
+	if err != nil {
+		return nil, err
+	}
+	rev, err := s.getRevision(ctx, postID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *post
+	post.Title, post.Content = rev.Title, rev.Content
+	if err := s.render(post); err != nil {
+		return nil, err
+	}
+	if err := s.posts.Update(ctx, post); err != nil {
+		return nil, err
+	}
+	if err := s.recordRevision(ctx, &before, post, actor.UserID, fmt.Sprintf(revisionNoteRollback, version)); err != nil {
+		return nil, err
+	}
+	s.syncIndex(ctx, post)
+	if err := s.fill(ctx, post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
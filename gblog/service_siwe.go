@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSiweNonceInvalid     = newAppError(KindUnauthorized, "siwe_nonce_invalid", "siwe nonce is invalid or expired")
+	ErrSiweSignatureInvalid = newAppError(KindUnauthorized, "siwe_signature_invalid", "siwe signature is invalid")
+	ErrSiweDomainMismatch   = newAppError(KindUnauthorized, "siwe_domain_mismatch", "siwe domain is not correct")
+	ErrSiweMessageExpired   = newAppError(KindUnauthorized, "siwe_message_expired", "siwe message is expired or not yet valid")
+	ErrWalletLinked         = newAppError(KindConflict, "wallet_linked", "wallet address is linked to another user")
+)
+
+// Sign-In With Ethereum 登录服务
+type SiweService struct {
+	cfg         SiweConfig
+	nonces      NonceRepository
+	users       UserRepository
+	defaultRole Role // 自动创建用户时使用的角色
+	now         func() time.Time
+}
+
+// 生成一次性nonce
+func (s *SiweService) NewNonce(ctx context.Context) (string, error) {
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	n := &SiweNonce{
+		Nonce:     nonce,
+		ExpiresAt: s.now().Add(time.Duration(s.cfg.NonceExpire)),
+	}
+	if err := s.nonces.CreateNonce(ctx, n); err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+// 校验消息与签名, 返回校验和格式的钱包地址
+func (s *SiweService) verify(ctx context.Context, message, signature string) (string, error) {
+	msg, err := ParseSiweMessage(message)
+	if err != nil {
+		return "", err
+	}
+	if !strings.EqualFold(msg.Domain, s.cfg.Domain) {
+		return "", ErrSiweDomainMismatch
+	}
+	now := s.now()
+	if msg.ExpirationTime != nil && !now.Before(*msg.ExpirationTime) {
+		return "", ErrSiweMessageExpired
+	}
+	if msg.NotBefore != nil && now.Before(*msg.NotBefore) {
+		return "", ErrSiweMessageExpired
+	}
+
+	addr, err := RecoverPersonalSignAddress(message, signature)
+	if err != nil || !strings.EqualFold(addr, msg.Address) {
+		return "", ErrSiweSignatureInvalid
+	}
+
+	// 签名校验通过后再消耗nonce, 防止伪造请求耗尽nonce
+	ok, err := s.nonces.ConsumeNonce(ctx, msg.Nonce, now)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrSiweNonceInvalid
+	}
+	return addr, nil
+}
+
+// SIWE登录, 地址未绑定用户时以地址为用户名创建新用户
+func (s *SiweService) Login(ctx context.Context, message, signature string) (*User, error) {
+	addr, err := s.verify(ctx, message, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetByWalletAddress(ctx, addr)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	user, err = s.createWalletUser(ctx, addr, addr)
+	if errors.Is(err, ErrDuplicate) {
+		// 用户名被占用, 如该地址的账号已注销(用户名保留), 加随机后缀重新创建
+		var suffix string
+		if suffix, err = randomToken(4); err != nil {
+			return nil, err
+		}
+		user, err = s.createWalletUser(ctx, addr, addr+"-"+suffix)
+	}
+	if errors.Is(err, ErrDuplicate) {
+		// 并发登录时已被创建
+		if user, err := s.users.GetByWalletAddress(ctx, addr); err == nil {
+			return user, nil
+		}
+		return nil, ErrUserExists
+	}
+	return user, err
+}
+
+func (s *SiweService) createWalletUser(ctx context.Context, addr, username string) (*User, error) {
+	user := &User{
+		Username:      username,
+		WalletAddress: &addr,
+		Role:          s.defaultRole,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// 为已有用户绑定钱包地址
+func (s *SiweService) Link(ctx context.Context, userID uint, message, signature string) (*User, error) {
+	addr, err := s.verify(ctx, message, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	if owner, err := s.users.GetByWalletAddress(ctx, addr); err == nil {
+		if owner.ID != userID {
+			return nil, ErrWalletLinked
+		}
+		return owner, nil
+	} else if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if err := s.users.SetWalletAddress(ctx, userID, addr); err != nil {
+		if errors.Is(err, ErrDuplicate) {
+			return nil, ErrWalletLinked
+		}
+		return nil, err
+	}
+	return s.users.GetByID(ctx, userID)
+}
+
+// 清理过期nonce
+func (s *SiweService) PurgeExpired(ctx context.Context) error {
+	return s.nonces.DeleteExpiredNonces(ctx, s.now())
+}
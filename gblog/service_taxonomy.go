@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidTag          = newAppError(KindInvalid, "invalid_tag", "tag name is not valid")
+	ErrTooManyTags         = newAppError(KindInvalid, "too_many_tags", "too many tags")
+	ErrTagExists           = newAppError(KindConflict, "tag_exists", "tag already exists")
+	ErrTagNotFound         = newAppError(KindNotFound, "tag_not_found", "can't get tag")
+	ErrInvalidCategory     = newAppError(KindInvalid, "invalid_category", "category name is not valid")
+	ErrCategoryExists      = newAppError(KindConflict, "category_exists", "category already exists")
+	ErrCategoryNotFound    = newAppError(KindNotFound, "category_not_found", "can't get category")
+	ErrCategoryCycle       = newAppError(KindInvalid, "category_cycle", "category can't be moved under itself")
+	ErrCategoryHasChildren = newAppError(KindConflict, "category_has_children", "category has sub categories")
+)
+
+// 标签和分类服务
+type TaxonomyService struct {
+	tags       TagRepository
+	categories CategoryRepository
+}
+
+// 校验标签名并生成slug
+func tagSlug(name string) (string, error) {
+	slug := slugify(name)
+	if slug == "" || utf8.RuneCountInString(name) > maxTagRunes {
+		return "", ErrInvalidTag
+	}
+	return slug, nil
+}
+
+func (s *TaxonomyService) CreateTag(ctx context.Context, name string) (*Tag, error) {
+	slug, err := tagSlug(name)
+	if err != nil {
+		return nil, err
+	}
+	tag := &Tag{Name: name, Slug: slug}
+	if err := s.tags.Create(ctx, tag); err != nil {
+		if errors.Is(err, ErrDuplicate) {
+			return nil, ErrTagExists
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+// 按名称查找标签, 不存在的标签自动创建; 按slug去重
+func (s *TaxonomyService) ResolveTags(ctx context.Context, names []string) ([]Tag, error) {
+	slugs := make([]string, 0, len(names))
+	bySlug := make(map[string]string, len(names))
+	for _, name := range names {
+		slug, err := tagSlug(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := bySlug[slug]; !ok {
+			bySlug[slug] = name
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) > maxPostTags {
+		return nil, ErrTooManyTags
+	}
+	if len(slugs) == 0 {
+		return []Tag{}, nil
+	}
+
+	existing, err := s.tags.GetBySlugs(ctx, slugs)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]Tag, len(existing))
+	for _, t := range existing {
+		found[t.Slug] = t
+	}
+	tags := make([]Tag, 0, len(slugs))
+	for _, slug := range slugs {
+		if t, ok := found[slug]; ok {
+			tags = append(tags, t)
+			continue
+		}
+		t := Tag{Name: bySlug[slug], Slug: slug}
+		if err := s.tags.Create(ctx, &t); err != nil {
+			if !errors.Is(err, ErrDuplicate) {
+				return nil, err
+			}
+			// 并发创建, 重新读取
+			got, err := s.tags.GetBySlugs(ctx, []string{slug})
+			if err != nil || len(got) == 0 {
+				return nil, ErrTagExists
+			}
+			t = got[0]
+		}
+		tags = append(tags, t)
+	}
+	return tags, nil
+}
+
+func (s *TaxonomyService) DeleteTag(ctx context.Context, id uint) error {
+	if err := s.tags.Delete(ctx, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrTagNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// 标签云, 按文章数计算1-5级权重
+func (s *TaxonomyService) TagCloud(ctx context.Context, limit int) ([]TagCount, error) {
+	tags, err := s.tags.Cloud(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	var most int64
+	for _, t := range tags {
+		most = max(most, t.Count)
+	}
+	for i := range tags {
+		tags[i].Weight = 1
+		if most > 1 && tags[i].Count > 0 {
+			tags[i].Weight = 1 + int(math.Round(4*math.Log(float64(tags[i].Count))/math.Log(float64(most))))
+		}
+	}
+	return tags, nil
+}
+
+// 分类的创建和修改参数, ParentID为nil时不修改, 为0时表示顶级分类
+type CategoryInput struct {
+	Name        string
+	Slug        string
+	Description string
+	ParentID    *uint
+}
+
+func (s *TaxonomyService) getCategory(ctx context.Context, id uint) (*Category, error) {
+	category, err := s.categories.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *TaxonomyService) CreateCategory(ctx context.Context, in CategoryInput) (*Category, error) {
+	category := &Category{Description: in.Description}
+	if err := s.applyCategory(ctx, category, in); err != nil {
+		return nil, err
+	}
+	if category.Name == "" {
+		return nil, ErrInvalidCategory
+	}
+	if err := s.categories.Create(ctx, category); err != nil {
+		if errors.Is(err, ErrDuplicate) {
+			return nil, ErrCategoryExists
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+// 修改分类, 空字段不修改; 不能移动到自己或子分类下
+func (s *TaxonomyService) UpdateCategory(ctx context.Context, id uint, in CategoryInput) (*Category, error) {
+	category, err := s.getCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if in.Description != "" {
+		category.Description = in.Description
+	}
+	if err := s.applyCategory(ctx, category, in); err != nil {
+		return nil, err
+	}
+	if err := s.categories.Update(ctx, category); err != nil {
+		if errors.Is(err, ErrDuplicate) {
+			return nil, ErrCategoryExists
+		}
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return category, nil
+}
+
+// 应用名称、slug和上级分类
+func (s *TaxonomyService) applyCategory(ctx context.Context, category *Category, in CategoryInput) error {
+	if in.Name != "" {
+		category.Name = in.Name
+		if in.Slug == "" && category.Slug == "" {
+			in.Slug = in.Name
+		}
+	}
+	if in.Slug != "" {
+		category.Slug = slugify(in.Slug)
+		if category.Slug == "" {
+			return ErrInvalidCategory
+		}
+	}
+	if in.ParentID == nil {
+		return nil
+	}
+	if *in.ParentID == 0 {
+		category.ParentID = nil
+		return nil
+	}
+
+	categories, err := s.categories.List(ctx)
+	if err != nil {
+		return err
+	}
+	parents := make(map[uint]*uint, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+	if _, ok := parents[*in.ParentID]; !ok {
+		return ErrCategoryNotFound
+	}
+	// 新建分类的id为0, 不会形成环
+	for p := in.ParentID; p != nil && category.ID != 0; p = parents[*p] {
+		if *p == category.ID {
+			return ErrCategoryCycle
+		}
+	}
+	parentID := *in.ParentID
+	category.ParentID = &parentID
+	return nil
+}
+
+func (s *TaxonomyService) DeleteCategory(ctx context.Context, id uint) error {
+	categories, err := s.categories.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range categories {
+		if c.ParentID != nil && *c.ParentID == id {
+			return ErrCategoryHasChildren
+		}
+	}
+	if err := s.categories.Delete(ctx, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrCategoryNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// 分类树节点
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+func (s *TaxonomyService) CategoryTree(ctx context.Context) ([]*CategoryNode, error) {
+	categories, err := s.categories.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[uint]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &CategoryNode{Category: c, Children: make([]*CategoryNode, 0)}
+	}
+	roots := make([]*CategoryNode, 0)
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+// 返回分类及其所有子分类的id
+func (s *TaxonomyService) Descendants(ctx context.Context, id uint) ([]uint, error) {
+	categories, err := s.categories.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint, len(categories))
+	exists := false
+	for _, c := range categories {
+		if c.ID == id {
+			exists = true
+		}
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+	}
+	if !exists {
+		return nil, ErrCategoryNotFound
+	}
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 启动时提升为管理员的用户, 之前签发的token带有旧角色, 需要吊销
+func TestPromoteAdminsRevokesTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	auth, alice := newTestAuthService(t, &now)
+	users := &UserService{users: auth.users, auth: auth, defaultRole: RoleAuthor, now: time.Now}
+
+	pair, err := auth.Issue(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.PromoteAdmins(ctx, []string{"alice", "nobody"}); err != nil {
+		t.Fatal(err)
+	}
+	u, err := auth.users.GetByID(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Role != RoleAdmin {
+		t.Fatalf("role = %s, want admin", u.Role)
+	}
+	if !accessRevoked(t, auth, pair) {
+		t.Fatal("access token with the old role is not revoked")
+	}
+	if _, err := auth.Refresh(ctx, pair.RefreshToken); err == nil {
+		t.Fatal("refresh token issued before the promotion still works")
+	}
+
+	// 已经是管理员时不吊销
+	pair, err = auth.Issue(ctx, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.PromoteAdmins(ctx, []string{"alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if accessRevoked(t, auth, pair) {
+		t.Fatal("access token of an existing admin is revoked")
+	}
+}
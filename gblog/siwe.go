@@ -0,0 +1,233 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SIWE登录使用的一次性nonce
+type SiweNonce struct {
+	Nonce     string    `gorm:"primarykey;size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// EIP-4361消息
+type SiweMessage struct {
+	Domain         string
+	Address        string
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+var ErrSiweMessageFormat = newAppError(KindInvalid, "siwe_message_format", "siwe message format is not correct")
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// 解析EIP-4361消息
+func ParseSiweMessage(msg string) (*SiweMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(msg, "\r\n", "\n"), "\n")
+	if len(lines) < 2 || !strings.HasSuffix(lines[0], siweHeaderSuffix) {
+		return nil, ErrSiweMessageFormat
+	}
+
+	m := &SiweMessage{
+		Domain:  strings.TrimSuffix(lines[0], siweHeaderSuffix),
+		Address: strings.TrimSpace(lines[1]),
+	}
+	if i := strings.Index(m.Domain, "://"); i >= 0 {
+		m.Domain = m.Domain[i+3:]
+	}
+	if !isChecksumAddress(m.Address) {
+		return nil, ErrSiweMessageFormat.Withf("address must be EIP-55 checksummed")
+	}
+
+	i := 2
+	for i < len(lines) && lines[i] == "" {
+		i++
+	}
+	// 可选的statement, 不是 "Key: value" 形式
+	if i < len(lines) && !strings.HasPrefix(lines[i], "URI: ") {
+		m.Statement = lines[i]
+		i++
+	}
+
+	var err error
+	inResources := false
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if line == "" {
+			continue
+		}
+		if inResources {
+			if !strings.HasPrefix(line, "- ") {
+				return nil, ErrSiweMessageFormat.Withf("invalid resource %q", line)
+			}
+			m.Resources = append(m.Resources, strings.TrimPrefix(line, "- "))
+			continue
+		}
+		if line == "Resources:" {
+			inResources = true
+			continue
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, ErrSiweMessageFormat.Withf("invalid line %q", line)
+		}
+		switch key {
+		case "URI":
+			m.URI = value
+		case "Version":
+			m.Version = value
+		case "Chain ID":
+			m.ChainID, err = strconv.ParseInt(value, 10, 64)
+		case "Nonce":
+			m.Nonce = value
+		case "Issued At":
+			m.IssuedAt, err = time.Parse(time.RFC3339, value)
+		case "Expiration Time":
+			var t time.Time
+			t, err = time.Parse(time.RFC3339, value)
+			m.ExpirationTime = &t
+		case "Not Before":
+			var t time.Time
+			t, err = time.Parse(time.RFC3339, value)
+			m.NotBefore = &t
+		case "Request ID":
+			m.RequestID = value
+		default:
+			return nil, ErrSiweMessageFormat.Withf("unknown field %q", key)
+		}
+		if err != nil {
+			return nil, ErrSiweMessageFormat.Withf("%s: %v", key, err)
+		}
+	}
+
+	if m.URI == "" || m.Version != "1" || m.ChainID == 0 || len(m.Nonce) < 8 || m.IssuedAt.IsZero() {
+		return nil, ErrSiweMessageFormat.Withf("missing required field")
+	}
+	return m, nil
+}
+
+// EIP-4361要求地址为EIP-55校验和格式, 全小写或大小写错误的地址都拒绝
+func isChecksumAddress(s string) bool {
+	return strings.HasPrefix(s, "0x") && common.IsHexAddress(s) && common.HexToAddress(s).Hex() == s
+}
+
+// EIP-55校验和地址
+func ToChecksumAddress(addr string) string {
+	return common.HexToAddress(addr).Hex()
+}
+
+// 从personal_sign签名中恢复签名者地址(EIP-191)
+func RecoverPersonalSignAddress(msg, signature string) (string, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return "", errors.New("signature format is not correct")
+	}
+	// 钱包返回的v为27/28, SigToPub需要0/1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return "", errors.New("signature recovery id is not correct")
+	}
+
+	prefix := fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(msg))
+	hash := crypto.Keccak256([]byte(prefix), []byte(msg))
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(*pub).Hex(), nil
+}
+
+type SiweVerifyReq struct {
+	Message   string `form:"message" json:"message" binding:"required"`
+	Signature string `form:"signature" json:"signature" binding:"required"`
+}
+
+// 获取SIWE登录nonce
+func (h *Handler) SiweNonceHandler(c *gin.Context) {
+	nonce, err := h.siwe.NewNonce(c.Request.Context())
+	if err != nil {
+		respondError(c, "SiweNonce", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"nonce":   nonce,
+	})
+}
+
+// 校验SIWE签名并登录, 钱包地址未注册时自动创建用户
+func (h *Handler) SiweVerifyHandler(c *gin.Context) {
+	var req SiweVerifyReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "SiweVerify", bindError(err))
+		return
+	}
+
+	user, err := h.siwe.Login(c.Request.Context(), req.Message, req.Signature)
+	if err != nil {
+		respondError(c, "SiweVerify", err)
+		return
+	}
+	pair, err := h.auth.Issue(c.Request.Context(), user)
+	if err != nil {
+		respondError(c, "SiweVerify", err)
+		return
+	}
+
+	requestLogger(c).Info("SiweVerify successfully", zap.Uint("userID", user.ID), zap.String("address", *user.WalletAddress))
+	resp := tokenPairResponse(pair)
+	resp["user"] = gin.H{
+		"id":             user.ID,
+		"username":       user.Username,
+		"wallet_address": user.WalletAddress,
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// 已登录用户绑定钱包地址
+func (h *Handler) SiweLinkHandler(c *gin.Context) {
+	var req SiweVerifyReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "SiweLink", bindError(err))
+		return
+	}
+
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+	user, err := h.siwe.Link(c.Request.Context(), uid, req.Message, req.Signature)
+	if err != nil {
+		respondError(c, "SiweLink", err)
+		return
+	}
+
+	requestLogger(c).Info("SiweLink successfully", zap.String("address", *user.WalletAddress))
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"wallet_address": user.WalletAddress,
+	})
+}
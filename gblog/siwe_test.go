@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// 测试配置中的SIWE域名
+const testSiweDomain = "localhost:8080"
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func siweMessage(domain, addr, nonce string, issuedAt time.Time) string {
+	return fmt.Sprintf("%s wants you to sign in with your Ethereum account:\n%s\n\nSign in to gblog\n\nURI: https://%s\nVersion: 1\nChain ID: 1\nNonce: %s\nIssued At: %s",
+		domain, addr, domain, nonce, issuedAt.UTC().Format(time.RFC3339))
+}
+
+// 与钱包的personal_sign相同, v为27/28
+func personalSign(t *testing.T, key *ecdsa.PrivateKey, msg string) string {
+	t.Helper()
+	hash := crypto.Keccak256([]byte(fmt.Sprintf("\x19Ethereum Signed Message:\n%d", len(msg))), []byte(msg))
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig)
+}
+
+// 获取nonce并签名, 返回可提交的表单
+func siweForm(t *testing.T, srv *httptest.Server, key *ecdsa.PrivateKey) url.Values {
+	t.Helper()
+	r := doRequest(t, srv, "GET", "/siwe/nonce", "", nil)
+	expectStatus(t, r, http.StatusOK)
+	addr := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	msg := siweMessage(testSiweDomain, addr, r.get("nonce").(string), time.Now())
+	return url.Values{"message": {msg}, "signature": {personalSign(t, key, msg)}}
+}
+
+func TestDeleteWalletAccountRequiresSignature(t *testing.T) {
+	srv := newTestServer(t)
+	key := newTestKey(t)
+
+	r := doRequest(t, srv, "POST", "/siwe/verify", "", siweForm(t, srv, key))
+	expectStatus(t, r, http.StatusOK)
+	token := r.get("token").(string)
+
+	// 钱包用户没有密码, 只有token不能注销
+	r = doRequest(t, srv, "POST", "/auth/me/delete", token, url.Values{})
+	expectStatus(t, r, http.StatusBadRequest)
+	if code := r.get("code"); code != "siwe_signature_required" {
+		t.Fatalf("code = %v, want siwe_signature_required", code)
+	}
+	// 其他钱包的签名不能注销
+	r = doRequest(t, srv, "POST", "/auth/me/delete", token, siweForm(t, srv, newTestKey(t)))
+	expectStatus(t, r, http.StatusUnauthorized)
+	if code := r.get("code"); code != "siwe_signature_invalid" {
+		t.Fatalf("code = %v, want siwe_signature_invalid", code)
+	}
+
+	form := siweForm(t, srv, key)
+	expectStatus(t, doRequest(t, srv, "POST", "/auth/me/delete", token, form), http.StatusOK)
+	expectStatus(t, doRequest(t, srv, "GET", "/auth/me", token, nil), http.StatusUnauthorized)
+}
+
+// 注销后释放钱包地址, 同一钱包可以重新登录, 创建新账号
+func TestSiweLoginAfterAccountDeleted(t *testing.T) {
+	srv := newTestServer(t)
+	key := newTestKey(t)
+	addr := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	r := doRequest(t, srv, "POST", "/siwe/verify", "", siweForm(t, srv, key))
+	expectStatus(t, r, http.StatusOK)
+	oldID := r.id("user.id")
+	if name := r.get("user.username"); name != addr {
+		t.Fatalf("username = %v, want %s", name, addr)
+	}
+	expectStatus(t, doRequest(t, srv, "POST", "/auth/me/delete", r.get("token").(string), siweForm(t, srv, key)), http.StatusOK)
+
+	r = doRequest(t, srv, "POST", "/siwe/verify", "", siweForm(t, srv, key))
+	expectStatus(t, r, http.StatusOK)
+	name, _ := r.get("user.username").(string)
+	if r.id("user.id") == oldID || name == addr || !strings.HasPrefix(name, addr+"-") {
+		t.Fatalf("user after re-login = %d %q, want a new user named %s-*", r.id("user.id"), name, addr)
+	}
+	if wallet := r.get("user.wallet_address"); wallet != addr {
+		t.Fatalf("wallet_address = %v, want %s", wallet, addr)
+	}
+
+	// 再次登录返回同一个用户
+	r2 := doRequest(t, srv, "POST", "/siwe/verify", "", siweForm(t, srv, key))
+	expectStatus(t, r2, http.StatusOK)
+	if r2.id("user.id") != r.id("user.id") {
+		t.Fatalf("second login user = %d, want %d", r2.id("user.id"), r.id("user.id"))
+	}
+}
+
+func TestParseSiweMessageChecksumAddress(t *testing.T) {
+	addr := crypto.PubkeyToAddress(newTestKey(t).PublicKey).Hex()
+	wrongCase := []byte(addr)
+	for i := 2; i < len(wrongCase); i++ {
+		if c := wrongCase[i]; c >= 'a' && c <= 'f' {
+			wrongCase[i] = c - 'a' + 'A'
+			break
+		} else if c >= 'A' && c <= 'F' {
+			wrongCase[i] = c - 'A' + 'a'
+			break
+		}
+	}
+	tests := []struct {
+		name, addr string
+		ok         bool
+	}{
+		{"checksummed", addr, true},
+		{"lower case", strings.ToLower(addr), false},
+		{"wrong case", string(wrongCase), false},
+		{"no prefix", addr[2:], false},
+		{"short", addr[:40], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSiweMessage(siweMessage(testSiweDomain, tt.addr, "abcdefgh12345678", time.Now()))
+			if (err == nil) != tt.ok {
+				t.Fatalf("ParseSiweMessage(%s) err = %v, want ok = %v", tt.addr, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRecoverPersonalSignAddress(t *testing.T) {
+	key := newTestKey(t)
+	addr := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	msg := siweMessage(testSiweDomain, addr, "abcdefgh12345678", time.Now())
+	sig := personalSign(t, key, msg)
+	raw, _ := hexutil.Decode(sig)
+	raw[crypto.RecoveryIDOffset] -= 27
+	sigV01 := hexutil.Encode(raw)
+	raw[crypto.RecoveryIDOffset] += 29
+	sigBadV := hexutil.Encode(raw)
+
+	tests := []struct {
+		name, msg, sig string
+		want           string // 为空表示返回错误
+	}{
+		{"v 27/28", msg, sig, addr},
+		{"v 0/1", msg, sigV01, addr},
+		{"invalid v", msg, sigBadV, ""},
+		{"short signature", msg, sig[:len(sig)-2], ""},
+		{"not hex", msg, "0xzz" + sig[4:], ""},
+		{"no prefix", msg, sig[2:], ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RecoverPersonalSignAddress(tt.msg, tt.sig)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("err = nil, address %s", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("got %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+
+	// 修改消息后恢复出的是其他地址
+	got, err := RecoverPersonalSignAddress(msg+" ", sig)
+	if err == nil && got == addr {
+		t.Fatal("tampered message recovered the signer address")
+	}
+}
+
+// 使用内存存储, 当前时间由now控制
+func newTestSiweService(now *time.Time) *SiweService {
+	repos := NewMemoryRepositories()
+	return &SiweService{
+		cfg:         SiweConfig{Domain: testSiweDomain, NonceExpire: Duration(5 * time.Minute)},
+		nonces:      repos.Nonces,
+		users:       repos.Users,
+		defaultRole: RoleAuthor,
+		now:         func() time.Time { return *now },
+	}
+}
+
+func TestSiweServiceLogin(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestSiweService(&now)
+	key := newTestKey(t)
+	addr := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	newNonce := func() string {
+		nonce, err := s.NewNonce(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nonce
+	}
+
+	msg := siweMessage(testSiweDomain, addr, newNonce(), now)
+	sig := personalSign(t, key, msg)
+	user, err := s.Login(ctx, msg, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != addr || user.WalletAddress == nil || *user.WalletAddress != addr || user.Role != RoleAuthor {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+	// nonce只能使用一次
+	if _, err := s.Login(ctx, msg, sig); !errors.Is(err, ErrSiweNonceInvalid) {
+		t.Fatalf("replay err = %v, want ErrSiweNonceInvalid", err)
+	}
+	msg = siweMessage(testSiweDomain, addr, newNonce(), now)
+	again, err := s.Login(ctx, msg, personalSign(t, key, msg))
+	if err != nil || again.ID != user.ID {
+		t.Fatalf("second login = %+v, %v, want user %d", again, err, user.ID)
+	}
+
+	later := now.Add(time.Minute).Format(time.RFC3339)
+	earlier := now.Add(-time.Minute).Format(time.RFC3339)
+	tests := []struct {
+		name string
+		// 根据新的nonce生成消息和签名
+		sign func(nonce string) (string, string)
+		want error
+	}{
+		{"unknown nonce", func(string) (string, string) {
+			msg := siweMessage(testSiweDomain, addr, "unknown-nonce", now)
+			return msg, personalSign(t, key, msg)
+		}, ErrSiweNonceInvalid},
+		{"domain mismatch", func(nonce string) (string, string) {
+			msg := siweMessage("evil.example.com", addr, nonce, now)
+			return msg, personalSign(t, key, msg)
+		}, ErrSiweDomainMismatch},
+		{"expired", func(nonce string) (string, string) {
+			msg := siweMessage(testSiweDomain, addr, nonce, now) + "\nExpiration Time: " + earlier
+			return msg, personalSign(t, key, msg)
+		}, ErrSiweMessageExpired},
+		{"not yet valid", func(nonce string) (string, string) {
+			msg := siweMessage(testSiweDomain, addr, nonce, now) + "\nNot Before: " + later
+			return msg, personalSign(t, key, msg)
+		}, ErrSiweMessageExpired},
+		{"tampered message", func(nonce string) (string, string) {
+			msg := siweMessage(testSiweDomain, addr, nonce, now)
+			return msg + "\nRequest ID: 1", personalSign(t, key, msg)
+		}, ErrSiweSignatureInvalid},
+		{"signed by another key", func(nonce string) (string, string) {
+			msg := siweMessage(testSiweDomain, addr, nonce, now)
+			return msg, personalSign(t, newTestKey(t), msg)
+		}, ErrSiweSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := newNonce()
+			msg, sig := tt.sign(nonce)
+			if _, err := s.Login(ctx, msg, sig); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			// 校验失败不消耗nonce
+			msg = siweMessage(testSiweDomain, addr, nonce, now)
+			if _, err := s.Login(ctx, msg, personalSign(t, key, msg)); err != nil {
+				t.Fatalf("login with the same nonce after failure: %v", err)
+			}
+		})
+	}
+
+	// nonce过期后不能使用
+	msg = siweMessage(testSiweDomain, addr, newNonce(), now)
+	now = now.Add(time.Duration(s.cfg.NonceExpire))
+	if _, err := s.Login(ctx, msg, personalSign(t, key, msg)); !errors.Is(err, ErrSiweNonceInvalid) {
+		t.Fatalf("expired nonce err = %v, want ErrSiweNonceInvalid", err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// 存储驱动: 根据dsn生成gorm方言
+type StorageDriver func(dsn string) gorm.Dialector
+
+// 已注册的存储驱动, 通过 database.driver 选择
+var storageDrivers = map[string]StorageDriver{
+	"mysql":    mysql.Open,
+	"postgres": postgres.Open,
+	"sqlite":   sqlite.Open,
+}
+
+// 注册存储驱动, 同名驱动会被覆盖
+func RegisterStorageDriver(name string, driver StorageDriver) {
+	storageDrivers[name] = driver
+}
+
+// 内存存储驱动名, 数据只保存在进程内
+const memoryDriver = "memory"
+
+func storageDriverNames() []string {
+	names := []string{memoryDriver}
+	for name := range storageDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 打开数据库连接
+func openStorage(cfg DatabaseConfig) (*gorm.DB, error) {
+	driver, ok := storageDrivers[cfg.Driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown database driver %q, available: %s", cfg.Driver, strings.Join(storageDriverNames(), ", "))
+	}
+	db, err := gorm.Open(driver(cfg.DSN), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(gormTracing{}); err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Driver == "sqlite" && isSQLiteMemory(cfg.DSN) {
+		// 内存库每个连接都是独立的数据库, 只能使用单连接
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		if cfg.MaxOpenConns > 0 {
+			sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		}
+		if cfg.MaxIdleConns > 0 {
+			sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+		}
+	}
+	return db, nil
+}
+
+func isSQLiteMemory(dsn string) bool {
+	return strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 标签, 与文章多对多关联
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Name      string    `gorm:"size:32" json:"name"`
+	Slug      string    `gorm:"size:64;uniqueIndex" json:"slug"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// 分类, 通过ParentID组成层级
+type Category struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Name        string    `gorm:"size:64" json:"name"`
+	Slug        string    `gorm:"size:64;uniqueIndex" json:"slug"`
+	Description string    `json:"description"`
+	ParentID    *uint     `gorm:"index" json:"parent_id"`
+	CreatedAt   time.Time `json:"-"`
+	UpdatedAt   time.Time `json:"-"`
+}
+
+const (
+	maxTagRunes = 32
+	maxPostTags = 10
+)
+
+// 由名称生成slug: 小写, 空白和标点转为"-"
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// 解析逗号分隔的标签列表, 提交空字符串表示清空标签
+func parseTagsParam(v string) []string {
+	tags := splitList(v)
+	if tags == nil {
+		tags = []string{}
+	}
+	return tags
+}
+
+// 解析可选的id表单字段, 未提交返回nil, 提交空字符串或0表示清空
+func optionalFormID(c *gin.Context, field string) (*uint, bool) {
+	v, ok := c.GetPostForm(field)
+	if !ok {
+		return nil, true
+	}
+	var id uint
+	if v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeProblem(c, invalidParam(field, field+" format is not correct"))
+			return nil, false
+		}
+		id = uint(n)
+	}
+	return &id, true
+}
+
+// 标签云, limit为0时返回全部标签
+func (h *Handler) ListTagsHandler(c *gin.Context) {
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeProblem(c, invalidParam("limit", "limit must be a positive integer"))
+			return
+		}
+		limit = n
+	}
+
+	tags, err := h.taxonomy.TagCloud(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, "ListTags", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"tags":    tags,
+	})
+}
+
+type CreateTagReq struct {
+	Name string `form:"name" binding:"required"`
+}
+
+func (h *Handler) CreateTagHandler(c *gin.Context) {
+	var req CreateTagReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "CreateTag", bindError(err))
+		return
+	}
+
+	tag, err := h.taxonomy.CreateTag(c.Request.Context(), req.Name)
+	if err != nil {
+		respondError(c, "CreateTag", err)
+		return
+	}
+
+	requestLogger(c).Info("CreateTag successfully", zap.Uint("tag_id", tag.ID), zap.String("slug", tag.Slug))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"tag":     tag,
+	})
+}
+
+// 删除标签, 同时解除与文章的关联
+func (h *Handler) DeleteTagHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("tid"), 10, 64)
+	if err != nil {
+		writeProblem(c, invalidParam("tid", "tag_id format is not correct"))
+		return
+	}
+
+	if err := h.taxonomy.DeleteTag(c.Request.Context(), uint(id)); err != nil {
+		respondError(c, "DeleteTag", err)
+		return
+	}
+
+	requestLogger(c).Info("DeleteTag successfully", zap.Uint64("tag_id", id))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"tag_id":  id,
+	})
+}
+
+// 分类树
+func (h *Handler) ListCategoriesHandler(c *gin.Context) {
+	tree, err := h.taxonomy.CategoryTree(c.Request.Context())
+	if err != nil {
+		respondError(c, "ListCategories", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"categories": tree,
+	})
+}
+
+type CategoryReq struct {
+	Name        string `form:"name"`
+	Slug        string `form:"slug"`
+	Description string `form:"description"`
+}
+
+func (h *Handler) CreateCategoryHandler(c *gin.Context) {
+	var req CategoryReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "CreateCategory", bindError(err))
+		return
+	}
+	parentID, ok := optionalFormID(c, "parent_id")
+	if !ok {
+		return
+	}
+
+	category, err := h.taxonomy.CreateCategory(c.Request.Context(), CategoryInput{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		ParentID:    parentID,
+	})
+	if err != nil {
+		respondError(c, "CreateCategory", err)
+		return
+	}
+
+	requestLogger(c).Info("CreateCategory successfully", zap.Uint("category_id", category.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"category": category,
+	})
+}
+
+// 解析路径中的分类id
+func validateCategoryID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("cid"), 10, 64)
+	if err != nil {
+		writeProblem(c, invalidParam("cid", "category_id format is not correct"))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// 修改分类, 未提交的字段不修改
+func (h *Handler) UpdateCategoryHandler(c *gin.Context) {
+	id, ok := validateCategoryID(c)
+	if !ok {
+		return
+	}
+	var req CategoryReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "UpdateCategory", bindError(err))
+		return
+	}
+	parentID, ok := optionalFormID(c, "parent_id")
+	if !ok {
+		return
+	}
+
+	category, err := h.taxonomy.UpdateCategory(c.Request.Context(), id, CategoryInput{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		ParentID:    parentID,
+	})
+	if err != nil {
+		respondError(c, "UpdateCategory", err)
+		return
+	}
+
+	requestLogger(c).Info("UpdateCategory successfully", zap.Uint("category_id", category.ID))
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"category": category,
+	})
+}
+
+// 删除分类, 有子分类时不允许删除, 文章的分类被清空
+func (h *Handler) DeleteCategoryHandler(c *gin.Context) {
+	id, ok := validateCategoryID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.taxonomy.DeleteCategory(c.Request.Context(), id); err != nil {
+		respondError(c, "DeleteCategory", err)
+		return
+	}
+
+	requestLogger(c).Info("DeleteCategory successfully", zap.Uint("category_id", id))
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"category_id": id,
+	})
+}
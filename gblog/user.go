@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -13,7 +16,19 @@ type User struct {
 	gorm.Model
 	Username string `gorm:"unique" form:"username" binding:"required"`
 	Password string `form:"password" binding:"required"`
-	Email    string `form:"email"`
+	Email    string `gorm:"size:255;index" form:"email" binding:"omitempty,email"`
+	// 邮箱验证时间, 修改邮箱后需重新验证
+	EmailVerifiedAt *time.Time `form:"-" json:"email_verified_at"`
+	// 通过SIWE登录或绑定的钱包地址(EIP-55格式)
+	WalletAddress *string `gorm:"uniqueIndex;size:42" form:"-" json:"wallet_address"`
+	Role          Role    `gorm:"size:16;default:author" form:"-" json:"role"`
+
+	// 公开资料, 通过 PUT /auth/me 修改
+	DisplayName string `gorm:"size:64" form:"-" json:"display_name"`
+	AvatarURL   string `gorm:"size:512" form:"-" json:"avatar_url"`
+	Bio         string `gorm:"size:500" form:"-" json:"bio"`
+	// 被管理员封禁的时间, 封禁期间无法登录
+	SuspendedAt *time.Time `gorm:"index" form:"-" json:"suspended_at"`
 }
 
 type LoginUser struct {
@@ -22,15 +37,15 @@ type LoginUser struct {
 	Email    string `form:"email"`
 }
 
+// bcrypt加密强度
+const passwordCost = 10
+
 // 密码加密中间件
 func PasswordEncrypt() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 先调用 ParseMultipartForm 解析, 否则可能无法正确获取字段
 		if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
-			c.JSON(500, gin.H{
-				"error": "Parse form failed!",
-			})
-			c.Abort()
+			respondError(c, "parse form", ErrInvalidRequest.Wrap(err))
 			return
 		}
 		// 获取密码字段
@@ -40,12 +55,9 @@ func PasswordEncrypt() gin.HandlerFunc {
 			return
 		}
 		// 加密
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
 		if err != nil {
-			c.JSON(500, gin.H{
-				"error": "password encrypted failed!",
-			})
-			c.Abort()
+			respondError(c, "encrypt password", err)
 			return
 		}
 		// 重新设置password
@@ -59,82 +71,185 @@ func PasswordEncrypt() gin.HandlerFunc {
 }
 
 // 用户注册
-func registerHandler(c *gin.Context) {
+func (h *Handler) registerHandler(c *gin.Context) {
 	var user User
 	if err := c.ShouldBind(&user); err != nil {
-		zap.L().Error("register failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
-		c.Abort()
+		respondError(c, "register", bindError(err))
 		return
 	}
 	// 从上下文获取加密后的密码
 	hashedPassword, exists := c.Get("hashedPassword")
 	if !exists {
-		zap.L().Error("register failed", zap.String("error", "password not encrypted"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "password not encrypted"})
+		respondError(c, "register", errors.New("password not encrypted"))
 		return
 	}
 	user.Password = hashedPassword.(string)
 	// 创建
-	if err := db.Create(&user).Error; err != nil {
-		zap.L().Error("register failed", zap.String("error", err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
-		c.Abort()
+	if err := h.users.Register(c.Request.Context(), &user); err != nil {
+		respondError(c, "register", err)
 		return
 	}
+	// 填写了邮箱时发送验证邮件, 失败不影响注册, 可稍后重新发送
+	if user.Email != "" {
+		if err := h.account.SendVerification(c.Request.Context(), &user); err != nil {
+			requestLogger(c).Error("send verification failed", zap.Uint("user_id", user.ID), zap.Error(err))
+		}
+	}
 	// 生成token
-	token, err := GenerateToken(user.ID, user.Username)
+	pair, err := h.auth.Issue(c.Request.Context(), &user)
 	if err != nil {
-		zap.L().Error("register failed", zap.String("error", "Token generate failed: "+err.Error()), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token generate failed: " + err.Error()})
+		respondError(c, "register", err)
 		return
 	}
 
-	zap.L().Info("register successfully", zap.String("username", user.Username))
+	requestLogger(c).Info("register successfully", zap.String("username", user.Username))
 	// 返回
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"token":   token,
-	})
+	c.JSON(http.StatusOK, tokenPairResponse(pair))
 }
 
 // 登录
-func loginHandler(c *gin.Context) {
-	var user User
-	username := c.PostForm("username")
-	result := db.Where("username = ?", username).First(&user)
-	if result.Error != nil {
-		zap.L().Error("login failed", zap.String("error", username+" not exist"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "user not exist"})
-		return
-	}
-	// 比较密码
-	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(c.PostForm("password")))
+func (h *Handler) loginHandler(c *gin.Context) {
+	// 校验用户名和密码
+	user, err := h.users.Login(c.Request.Context(), c.PostForm("username"), c.PostForm("password"))
 	if err != nil {
-		zap.L().Error("login failed", zap.String("error", "Password is not correct"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is not correct"})
+		respondError(c, "login", err)
 		return
 	}
 	// 生成token
-	token, err := GenerateToken(user.ID, user.Username)
+	pair, err := h.auth.Issue(c.Request.Context(), user)
 	if err != nil {
-		zap.L().Error("login failed", zap.String("error", "Token generate failed"), zap.String("path", c.Request.URL.Path), zap.String("method", c.Request.Method))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token generate failed"})
+		respondError(c, "login", err)
 		return
 	}
 
-	zap.L().Info("login successfully", zap.Uint("userID", user.ID), zap.String("username", user.Username))
+	requestLogger(c).Info("login successfully", zap.Uint("userID", user.ID), zap.String("username", user.Username))
 	// 返回
+	resp := tokenPairResponse(pair)
+	resp["user"] = gin.H{
+		"id":       user.ID,
+		"username": user.Username,
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type SetRoleReq struct {
+	Role Role `form:"role" json:"role" binding:"required"`
+}
+
+// 管理员修改用户角色
+func (h *Handler) SetUserRoleHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		writeProblem(c, invalidParam("id", "user_id format is not correct"))
+		return
+	}
+	var req SetRoleReq
+	if err := c.ShouldBind(&req); err != nil {
+		respondError(c, "SetUserRole", bindError(err))
+		return
+	}
+
+	user, err := h.users.SetRole(c.Request.Context(), uint(id), req.Role)
+	if err != nil {
+		respondError(c, "SetUserRole", err)
+		return
+	}
+
+	requestLogger(c).Info("SetUserRole successfully", zap.Uint("target_user_id", user.ID), zap.String("role", string(user.Role)))
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"token":   token,
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
+			"role":     user.Role,
+		},
+	})
+}
+
+type ListUsersReq struct {
+	Keyword   string `form:"keyword"`
+	Role      Role   `form:"role"`
+	Suspended *bool  `form:"suspended"`
+}
+
+// 管理员查询用户, 支持按关键字、角色和封禁状态过滤
+func (h *Handler) ListUsersHandler(c *gin.Context) {
+	var req ListUsersReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeProblem(c, bindError(err))
+		return
+	}
+	page, pageNum, err := parsePage(c)
+	if err != nil {
+		writeProblem(c, err)
+		return
+	}
+	if page.After != nil {
+		writeProblem(c, invalidParam("cursor", "user list does not support cursor"))
+		return
+	}
+
+	users, total, err := h.users.List(c.Request.Context(), UserQuery{
+		Keyword:   req.Keyword,
+		Role:      req.Role,
+		Suspended: req.Suspended,
+		Page:      page,
+	})
+	if err != nil {
+		respondError(c, "ListUsers", err)
+		return
+	}
+
+	items := make([]gin.H, 0, len(users))
+	for i := range users {
+		items = append(items, accountJSON(&users[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"users":   items,
+		"meta": PageMeta{
+			Total:    total,
+			Page:     pageNum,
+			PageSize: page.Limit,
+			HasMore:  int64(page.Offset+len(users)) < total,
 		},
 	})
 }
+
+// 管理员封禁用户, 被封禁的用户立即下线且无法登录
+func (h *Handler) SuspendUserHandler(c *gin.Context) {
+	id, ok := validateUserID(c)
+	if !ok {
+		return
+	}
+	uid, ok := getCurrentUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.users.Suspend(c.Request.Context(), uid, id)
+	if err != nil {
+		respondError(c, "SuspendUser", err)
+		return
+	}
+
+	requestLogger(c).Info("SuspendUser successfully", zap.Uint("target_user_id", user.ID))
+	c.JSON(http.StatusOK, gin.H{"success": true, "user": accountJSON(user)})
+}
+
+// 管理员解除封禁
+func (h *Handler) UnsuspendUserHandler(c *gin.Context) {
+	id, ok := validateUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.users.Unsuspend(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, "UnsuspendUser", err)
+		return
+	}
+
+	requestLogger(c).Info("UnsuspendUser successfully", zap.Uint("target_user_id", user.ID))
+	c.JSON(http.StatusOK, gin.H{"success": true, "user": accountJSON(user)})
+}